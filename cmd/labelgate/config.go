@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/channinghe/labelgate/internal/config"
+)
+
+const configUsage = `Usage: labelgate config [flags]
+
+Prints the effective configuration, after applying defaults, environment
+variables and secret references, with secrets redacted. Exits with status 1
+if the configuration is invalid.
+
+Flags:
+`
+
+// runConfig runs the "config" command and returns the process exit code.
+func runConfig(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	configPath := fs.StringP("config", "c", "", "Path to configuration file")
+	format := fs.String("format", "yaml", "Output format: yaml or json")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, configUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 || *format != "yaml" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	settings := cfg.Redacted().Settings()
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(settings)
+	} else {
+		err = yaml.NewEncoder(os.Stdout).Encode(settings)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+const dbUsage = `Usage: labelgate db <command> [flags]
+
+Commands:
+  backup <file>           Write an online backup of the SQLite database
+  export [--format json]  Export the database as JSON (stdout or --output)
+  restore <file>          Restore the SQLite database from a backup (labelgate must be stopped)
+`
+
+// runDB runs the "db" subcommands and returns the process exit code.
+func runDB(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, dbUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("db "+args[0], flag.ContinueOnError)
+	configPath := fs.StringP("config", "c", "", "Path to configuration file")
+	format := fs.String("format", "json", "Export format (json)")
+	output := fs.StringP("output", "o", "", "Export output file (default: stdout)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "backup":
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, dbUsage)
+			return 2
+		}
+		err = dbBackup(ctx, cfg, fs.Arg(0))
+	case "export":
+		if *format != "json" {
+			fmt.Fprintf(os.Stderr, "unsupported export format: %s (supported: json)\n", *format)
+			return 2
+		}
+		err = dbExport(ctx, cfg, *output)
+	case "restore":
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, dbUsage)
+			return 2
+		}
+		err = dbRestore(ctx, cfg, fs.Arg(0))
+	default:
+		fmt.Fprint(os.Stderr, dbUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db %s failed: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// openStore opens and migrates the configured storage.
+func openStore(ctx context.Context, cfg *config.Config) (storage.Storage, error) {
+	store, err := storage.Open(cfg.Db.Driver, cfg.Db.Path, cfg.Db.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Initialize(ctx); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// openCurrentStore opens the configured storage without migrating it, so
+// reading a database does not upgrade it under a running older labelgate.
+// Its schema must be the one this build uses.
+func openCurrentStore(ctx context.Context, cfg *config.Config) (storage.Storage, error) {
+	if cfg.Db.Driver == storage.DriverSQLite {
+		if _, err := os.Stat(cfg.Db.Path); err != nil {
+			return nil, err
+		}
+	}
+	store, err := storage.Open(cfg.Db.Driver, cfg.Db.Path, cfg.Db.DSN)
+	if err != nil {
+		return nil, err
+	}
+	version, err := store.SchemaVersion(ctx)
+	if err != nil {
+		err = fmt.Errorf("not a labelgate database: %w", err)
+	} else {
+		err = storage.CheckSchemaVersion(version)
+	}
+	if err == nil && version < storage.LatestSchemaVersion() {
+		err = fmt.Errorf("database schema version %d is older than version %d of this build; start labelgate to migrate it first", version, storage.LatestSchemaVersion())
+	}
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// openSQLite opens the configured SQLite database as it is, without
+// migrating it; backups and restores of PostgreSQL are left to pg_dump and
+// pg_restore.
+func openSQLite(cfg *config.Config) (*storage.SQLiteStorage, error) {
+	if cfg.Db.Driver != storage.DriverSQLite {
+		return nil, fmt.Errorf("only supported by the sqlite driver; use pg_dump/pg_restore or 'labelgate db export' for %s", cfg.Db.Driver)
+	}
+	return storage.NewSQLiteStorage(cfg.Db.Path)
+}
+
+func dbBackup(ctx context.Context, cfg *config.Config, dst string) error {
+	if _, err := os.Stat(cfg.Db.Path); err != nil && cfg.Db.Driver == storage.DriverSQLite {
+		return err
+	}
+	store, err := openSQLite(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.Backup(ctx, dst); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "backup of %s written to %s\n", cfg.Db.Path, dst)
+	return nil
+}
+
+func dbExport(ctx context.Context, cfg *config.Config, output string) error {
+	store, err := openCurrentStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshot, err := storage.Export(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snapshot)
+}
+
+func dbRestore(ctx context.Context, cfg *config.Config, src string) error {
+	if cfg.Db.Driver != storage.DriverSQLite {
+		return fmt.Errorf("only supported by the sqlite driver; use pg_dump/pg_restore for %s", cfg.Db.Driver)
+	}
+	if err := storage.CheckNotInUse(ctx, cfg.Db.Path); err != nil {
+		return err
+	}
+	_, statErr := os.Stat(cfg.Db.Path)
+
+	store, err := openSQLite(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Keep the current database so a bad restore can be undone
+	var previous string
+	if statErr == nil {
+		previous = fmt.Sprintf("%s.%s.bak", cfg.Db.Path, time.Now().UTC().Format("20060102-150405"))
+		if err := store.Backup(ctx, previous); err != nil {
+			return fmt.Errorf("failed to save current database: %w", err)
+		}
+	}
+
+	if err := store.Restore(ctx, src); err != nil {
+		return err
+	}
+	if previous == "" {
+		fmt.Fprintf(os.Stderr, "restored %s from %s\n", cfg.Db.Path, src)
+	} else {
+		fmt.Fprintf(os.Stderr, "restored %s from %s (previous database saved to %s)\n", cfg.Db.Path, src, previous)
+	}
+	return nil
+}
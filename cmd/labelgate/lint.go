@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/pkg/labels"
+)
+
+const lintUsage = `Usage: labelgate lint [flags] <file|->
+
+Validates the labelgate labels of a docker-compose file or of
+"docker inspect" output and reports unknown labels, typos and invalid values.
+Exits with status 1 if any errors are found.
+
+Flags:
+`
+
+// runLint runs the "lint" command and returns the process exit code.
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	configPath := fs.StringP("config", "c", "", "Path to configuration file (for label_prefix)")
+	prefix := fs.String("prefix", "", "Label prefix (default: label_prefix from configuration, LABELGATE_LABEL_PREFIX or labelgate)")
+	format := fs.String("format", "text", "Output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, lintUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *format != "text" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	if *prefix == "" {
+		*prefix = lintPrefix(*configPath)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+		return 1
+	}
+
+	containers, err := readContainerLabels(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+		return 1
+	}
+
+	results := labels.NewParser(*prefix).LintContainers(containers, nil)
+	failed := false
+	for _, issues := range results {
+		failed = failed || labels.HasErrors(issues)
+	}
+
+	if *format == "json" {
+		if results == nil {
+			results = map[string][]labels.Issue{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"valid": !failed, "containers": results}); err != nil {
+			fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+			return 1
+		}
+	} else {
+		printLintResults(containers, results)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// lintPrefix resolves the label prefix when --prefix isn't given.
+func lintPrefix(configPath string) string {
+	if configPath != "" {
+		if cfg, err := config.Load(configPath); err == nil {
+			return cfg.LabelPrefix
+		}
+	}
+	if p := os.Getenv("LABELGATE_LABEL_PREFIX"); p != "" {
+		return p
+	}
+	return "labelgate"
+}
+
+func printLintResults(containers map[string]map[string]string, results map[string][]labels.Issue) {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs, warnings := 0, 0
+	for _, name := range names {
+		fmt.Printf("%s:\n", name)
+		for _, issue := range results[name] {
+			fmt.Printf("  %s\n", issue)
+			if issue.Severity == labels.SeverityError {
+				errs++
+			} else {
+				warnings++
+			}
+		}
+	}
+	fmt.Printf("%d container(s) checked, %d error(s), %d warning(s)\n", len(containers), errs, warnings)
+}
+
+// readContainerLabels extracts container labels (name -> labels) from
+// "docker inspect" JSON output or a docker-compose file.
+func readContainerLabels(data []byte) (map[string]map[string]string, error) {
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		var inspect []struct {
+			Name   string
+			Config struct {
+				Labels map[string]string
+			}
+		}
+		if err := json.Unmarshal(data, &inspect); err != nil {
+			return nil, fmt.Errorf("invalid docker inspect output: %w", err)
+		}
+		containers := make(map[string]map[string]string, len(inspect))
+		for _, c := range inspect {
+			containers[strings.TrimPrefix(c.Name, "/")] = c.Config.Labels
+		}
+		return containers, nil
+	}
+
+	// Compose labels are either a mapping or a list of "key=value" strings
+	var compose struct {
+		Services map[string]struct {
+			Labels yaml.Node `yaml:"labels"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	if len(compose.Services) == 0 {
+		return nil, fmt.Errorf("no services found (expected a compose file or docker inspect output)")
+	}
+
+	containers := make(map[string]map[string]string, len(compose.Services))
+	for name, svc := range compose.Services {
+		lbls := make(map[string]string)
+		switch svc.Labels.Kind {
+		case 0:
+		case yaml.MappingNode:
+			if err := svc.Labels.Decode(&lbls); err != nil {
+				return nil, fmt.Errorf("service %s: invalid labels: %w", name, err)
+			}
+		case yaml.SequenceNode:
+			var list []string
+			if err := svc.Labels.Decode(&list); err != nil {
+				return nil, fmt.Errorf("service %s: invalid labels: %w", name, err)
+			}
+			for _, item := range list {
+				key, value, _ := strings.Cut(item, "=")
+				lbls[key] = value
+			}
+		default:
+			return nil, fmt.Errorf("service %s: labels must be a mapping or a list", name)
+		}
+		containers[name] = lbls
+	}
+	return containers, nil
+}
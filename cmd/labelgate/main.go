@@ -3,11 +3,15 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,18 +21,40 @@ import (
 
 	"github.com/channinghe/labelgate/internal/agent"
 	"github.com/channinghe/labelgate/internal/api"
+	"github.com/channinghe/labelgate/internal/auth"
 	"github.com/channinghe/labelgate/internal/cloudflare"
 	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/leader"
+	"github.com/channinghe/labelgate/internal/maintenance"
+	"github.com/channinghe/labelgate/internal/metrics"
+	"github.com/channinghe/labelgate/internal/notify"
 	accessop "github.com/channinghe/labelgate/internal/operator/access"
 	dnsop "github.com/channinghe/labelgate/internal/operator/dns"
 	tunnelop "github.com/channinghe/labelgate/internal/operator/tunnel"
 	"github.com/channinghe/labelgate/internal/provider/docker"
 	"github.com/channinghe/labelgate/internal/reconciler"
+	"github.com/channinghe/labelgate/internal/reload"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
 	"github.com/channinghe/labelgate/internal/version"
 )
 
 func main() {
+	// Database maintenance, token, lint and config commands parse their own flags
+	if len(os.Args) > 1 && os.Args[1] == "db" {
+		os.Exit(runDB(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		os.Exit(runToken(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+
 	// Parse CLI flags
 	configPath := flag.StringP("config", "c", "", "Path to configuration file")
 	showVersion := flag.Bool("version", false, "Print version and exit")
@@ -41,7 +67,7 @@ func main() {
 
 	// Built-in healthcheck for distroless containers (no wget/curl available)
 	if flag.Arg(0) == "healthcheck" {
-		os.Exit(runHealthcheck(*configPath))
+		os.Exit(runHealthcheck(*configPath, flag.Arg(1)))
 	}
 
 	// Setup logger
@@ -77,7 +103,26 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Handle shutdown signals
+	// Set up tracing (no-op when disabled)
+	shutdownTracing, err := tracing.Setup(ctx, &cfg.Tracing, version.Version)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Warn().Err(err).Msg("Failed to flush traces")
+		}
+	}()
+	if cfg.Tracing.Enabled {
+		log.Info().Str("endpoint", cfg.Tracing.Endpoint).Float64("sample_ratio", cfg.Tracing.SampleRatio).Msg("Tracing enabled")
+	}
+
+	// Components register with the reloader once created
+	reloader := reload.New(*configPath, cfg)
+
+	// Handle shutdown and reload signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
@@ -86,7 +131,9 @@ func main() {
 			switch sig {
 			case syscall.SIGHUP:
 				log.Info().Msg("Received SIGHUP, reloading configuration...")
-				// TODO: Implement hot reload
+				if _, err := reloader.Reload(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to reload configuration, keeping the current configuration")
+				}
 			case syscall.SIGINT, syscall.SIGTERM:
 				log.Info().Msg("Received shutdown signal, gracefully shutting down...")
 				cancel()
@@ -99,7 +146,7 @@ func main() {
 	var runErr error
 	switch cfg.Mode {
 	case config.ModeMain:
-		runErr = runMain(ctx, cfg)
+		runErr = runMain(ctx, cfg, reloader)
 	case config.ModeAgent:
 		runErr = runAgent(ctx, cfg)
 	default:
@@ -114,9 +161,9 @@ func main() {
 }
 
 // runMain runs labelgate in main instance mode.
-func runMain(ctx context.Context, cfg *config.Config) error {
+func runMain(ctx context.Context, cfg *config.Config, reloader *reload.Reloader) error {
 	// Initialize storage
-	store, err := storage.NewSQLiteStorage(cfg.Db.Path)
+	store, err := storage.Open(cfg.Db.Driver, cfg.Db.Path, cfg.Db.DSN)
 	if err != nil {
 		return err
 	}
@@ -125,7 +172,37 @@ func runMain(ctx context.Context, cfg *config.Config) error {
 	if err := store.Initialize(ctx); err != nil {
 		return err
 	}
-	log.Info().Str("path", cfg.Db.Path).Msg("Storage initialized")
+	if cfg.Db.Driver == storage.DriverPostgres {
+		log.Info().Str("driver", cfg.Db.Driver).Msg("Storage initialized")
+	} else {
+		log.Info().Str("driver", cfg.Db.Driver).Str("path", cfg.Db.Path).Msg("Storage initialized")
+	}
+
+	// Resource counts are read from storage on each scrape
+	metrics.Registry.MustRegister(metrics.NewResourceCollector(store))
+
+	// Live event bus for the API event stream; resource changes come from storage
+	bus := events.NewBus()
+	if notifier, ok := store.(storage.EventNotifier); ok {
+		notifier.OnResourceEvent(func(e *storage.ResourceEvent) {
+			bus.Publish(events.ResourceEvent(e))
+		})
+	}
+
+	// Outbound notifications for selected events
+	var notifier *notify.Notifier
+	if cfg.Notifications.Enabled {
+		notifier, err = notify.New(&cfg.Notifications)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := notifier.Run(ctx, bus); err != nil && err != context.Canceled {
+				log.Error().Err(err).Msg("Notifier error")
+			}
+		}()
+		log.Info().Int("sinks", len(cfg.Notifications.Sinks)).Msg("Notifications enabled")
+	}
 
 	// Initialize credential manager
 	credManager, err := cloudflare.NewCredentialManager(cfg)
@@ -152,6 +229,10 @@ func runMain(ctx context.Context, cfg *config.Config) error {
 	dnsOperator := dnsop.NewDNSOperator(credManager, store)
 	tunnelOperator := tunnelop.NewTunnelOperator(credManager, store)
 	accessOperator := accessop.NewAccessOperator(credManager, store)
+	dnsOperator.SetWorkers(cfg.Sync.Workers)
+	tunnelOperator.SetWorkers(cfg.Sync.Workers)
+	tunnelOperator.SetEventBus(bus)
+	accessOperator.SetWorkers(cfg.Sync.Workers)
 
 	// Probe Access API permissions at startup (non-blocking)
 	if err := accessOperator.CheckPermissions(ctx); err != nil {
@@ -182,26 +263,86 @@ func runMain(ctx context.Context, cfg *config.Config) error {
 		PollInterval:   cfg.Docker.PollInterval,
 		OrphanTTL:      cfg.Sync.OrphanTTL,
 		RemoveDelay:    cfg.Sync.RemoveDelay,
+		Debounce:       cfg.Sync.Debounce,
 		ExpectedAgents: expectedAgents,
+		Events:         bus,
 	})
 
-	// Start agent server if enabled
+	// Create agent server if enabled (started only while leading)
 	var agentServer *agent.Server
 	if cfg.Agent.Enabled {
 		agentConfigs := buildAgentConfigs(cfg)
 		agentServer = agent.NewServer(&cfg.Agent, agentConfigs, rec, store, cfg.LabelPrefix)
+		agentServer.SetEventBus(bus)
+	}
+	reloader.Register(reload.Components{
+		Credentials: credManager,
+		AgentServer: agentServer,
+		Reconciler:  rec,
+	})
+
+	// Database maintenance (retention, vacuum, WAL checkpoint, integrity check)
+	maintenanceScheduler := maintenance.NewScheduler(&maintenance.Config{
+		Storage:            store,
+		Retention:          cfg.Db.Retention,
+		RetentionInterval:  cfg.Db.CleanupInterval,
+		VacuumInterval:     cfg.Db.VacuumInterval,
+		CheckpointInterval: cfg.Db.CheckpointInterval,
+		IntegrityInterval:  cfg.Db.IntegrityCheckInterval,
+	})
+
+	// runLeader runs everything that mutates Cloudflare or owns agent connections,
+	// plus database maintenance (the database may be shared with followers).
+	runLeader := func(ctx context.Context) error {
 		go func() {
-			if err := agentServer.Start(ctx); err != nil && err != context.Canceled {
-				log.Error().Err(err).Msg("Agent server error")
+			if err := maintenanceScheduler.Run(ctx); err != nil && err != context.Canceled {
+				log.Error().Err(err).Msg("Database maintenance error")
 			}
 		}()
 
-		// Start inbound connections to agents that have connect_to configured
-		agentServer.ConnectToInboundAgents(ctx)
+		if agentServer != nil {
+			go func() {
+				if err := agentServer.Start(ctx); err != nil && err != context.Canceled {
+					log.Error().Err(err).Msg("Agent server error")
+				}
+			}()
+
+			// Start inbound connections to agents that have connect_to configured
+			agentServer.ConnectToInboundAgents(ctx)
+		}
+
+		// Run reconciler
+		return rec.Run(ctx)
+	}
+
+	// Set up leader election if enabled
+	var elector *leader.Elector
+	if cfg.Leader.Enabled {
+		backend, err := leader.NewSQLiteBackend(cfg.Leader.Path)
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		elector = leader.NewElector(&leader.Config{
+			Backend:       backend,
+			Identity:      cfg.Leader.Identity,
+			LeaseDuration: cfg.Leader.LeaseDuration,
+			RenewInterval: cfg.Leader.RenewInterval,
+			OnStartedLeading: func(ctx context.Context) {
+				if err := runLeader(ctx); err != nil && err != context.Canceled {
+					log.Error().Err(err).Msg("Leader loop error")
+				}
+			},
+		})
 	}
 
 	// Start API server if enabled
 	if cfg.Api.Enabled {
+		access, oidc, err := newIdentityProviders(&cfg.Api.Auth)
+		if err != nil {
+			return fmt.Errorf("failed to set up API authentication: %w", err)
+		}
 		apiServer := api.NewServer(&api.Config{
 			Address:     cfg.Api.Address,
 			BasePath:    cfg.Api.BasePath,
@@ -210,6 +351,19 @@ func runMain(ctx context.Context, cfg *config.Config) error {
 			Reconciler:  rec,
 			AgentServer: agentServer,
 			CredManager: credManager,
+			Elector:     elector,
+			Events:      bus,
+			Access:      access,
+			OIDC:        oidc,
+			Roles:       auth.Roles(cfg.Api.Auth.Roles),
+			Notifier:    notifier,
+			LabelPrefix: cfg.LabelPrefix,
+			Docker:      dockerProvider,
+			TLS:         apiTLSConfig(&cfg.Api.TLS),
+			Socket:      cfg.Api.Socket,
+			SocketMode:  apiSocketMode(cfg.Api.SocketMode),
+			SocketGroup: cfg.Api.SocketGroup,
+			Reloader:    reloader,
 			Version:     version.Version,
 		})
 		go func() {
@@ -219,22 +373,16 @@ func runMain(ctx context.Context, cfg *config.Config) error {
 		}()
 	}
 
-	// Run reconciler
-	return rec.Run(ctx)
+	if elector != nil {
+		return elector.Run(ctx)
+	}
+	return runLeader(ctx)
 }
 
 // buildAgentConfigs builds agent config entries from configuration.
 func buildAgentConfigs(cfg *config.Config) map[string]*agent.AgentConfigEntry {
-	result := make(map[string]*agent.AgentConfigEntry)
-
 	// Load named agents from config
-	for id, entry := range cfg.Agent.Agents {
-		result[id] = &agent.AgentConfigEntry{
-			Token:         entry.Token,
-			DefaultTunnel: entry.DefaultTunnel,
-			ConnectTo:     entry.ConnectTo,
-		}
-	}
+	result := agent.ConfigEntries(&cfg.Agent)
 
 	count := len(result)
 	if cfg.Agent.AcceptToken != "" {
@@ -247,25 +395,110 @@ func buildAgentConfigs(cfg *config.Config) map[string]*agent.AgentConfigEntry {
 	return result
 }
 
+// newIdentityProviders creates the Cloudflare Access validator and OIDC
+// login flow enabled in cfg (nil when disabled).
+func newIdentityProviders(cfg *config.ApiAuthConfig) (*auth.AccessValidator, *auth.OIDC, error) {
+	var access *auth.AccessValidator
+	if cfg.Access.Enabled {
+		var err error
+		access, err = auth.NewAccessValidator(cfg.Access.TeamDomain, cfg.Access.Audience, cfg.Access.JWKSFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Info().Str("team_domain", cfg.Access.TeamDomain).Msg("Cloudflare Access authentication enabled")
+	}
+
+	var oidc *auth.OIDC
+	if cfg.OIDC.Enabled {
+		var err error
+		oidc, err = auth.NewOIDC(auth.OIDCConfig{
+			Issuer:        cfg.OIDC.Issuer,
+			ClientID:      cfg.OIDC.ClientID,
+			ClientSecret:  cfg.OIDC.ClientSecret,
+			RedirectURL:   cfg.OIDC.RedirectURL,
+			Scopes:        cfg.OIDC.Scopes,
+			GroupsClaim:   cfg.OIDC.GroupsClaim,
+			SessionSecret: cfg.OIDC.SessionSecret,
+			SessionTTL:    cfg.OIDC.SessionTTL,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Info().Str("issuer", cfg.OIDC.Issuer).Msg("OIDC login enabled")
+	}
+
+	return access, oidc, nil
+}
+
+// apiTLSConfig returns the API server TLS settings, nil for plain HTTP.
+func apiTLSConfig(cfg *config.ApiTLSConfig) *api.TLSConfig {
+	if cfg.Cert == "" {
+		return nil
+	}
+	return &api.TLSConfig{
+		CertFile:          cfg.Cert,
+		KeyFile:           cfg.Key,
+		ClientCAFile:      cfg.ClientCA,
+		RequireClientCert: cfg.RequireClientCert,
+	}
+}
+
+// apiSocketMode parses api.socket_mode, which config validation checked.
+func apiSocketMode(mode string) os.FileMode {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0o660
+	}
+	return os.FileMode(m)
+}
+
 // runHealthcheck performs an HTTP health check against the local API server.
 // It reuses the same config.Load path (env vars > config file > defaults)
-func runHealthcheck(configPath string) int {
-	addr := ":8080"
-	basePath := "/api"
-
+func runHealthcheck(configPath, probe string) int {
+	apiCfg := config.DefaultConfig().Api
 	if cfg, err := config.Load(configPath); err == nil {
-		addr = cfg.Api.Address
-		basePath = cfg.Api.BasePath
+		apiCfg = cfg.Api
 	}
 
-	_, port, err := net.SplitHostPort(addr)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "healthcheck: invalid address %q: %v\n", addr, err)
-		return 1
+	// "live" (default) checks that the process serves requests, "ready" also
+	// checks storage, Docker, reconciles, Cloudflare and the agent server
+	var endpoint string
+	switch probe {
+	case "", "live", "liveness":
+		endpoint = "/healthz"
+	case "ready", "readiness":
+		endpoint = "/readyz"
+	default:
+		fmt.Fprintf(os.Stderr, "healthcheck: unknown probe %q (use live or ready)\n", probe)
+		return 2
+	}
+
+	// Prefer the Unix socket, which works even when client certificates are required
+	client := &http.Client{Timeout: 10 * time.Second}
+	var url string
+	if apiCfg.Socket != "" {
+		url = "http://labelgate" + apiCfg.BasePath + endpoint
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", apiCfg.Socket)
+			},
+		}
+	} else {
+		_, port, err := net.SplitHostPort(apiCfg.Address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "healthcheck: invalid address %q: %v\n", apiCfg.Address, err)
+			return 1
+		}
+		scheme := "http"
+		if apiCfg.TLS.Cert != "" {
+			// The certificate is issued for the public name, not localhost
+			scheme = "https"
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		}
+		url = scheme + "://localhost:" + port + apiCfg.BasePath + endpoint
 	}
 
-	url := "http://localhost:" + port + basePath + "/health"
-	client := &http.Client{Timeout: 2 * time.Second}
 	resp, err := client.Get(url)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "healthcheck failed: %v\n", err)
@@ -274,7 +507,9 @@ func runHealthcheck(configPath string) int {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "healthcheck failed: status %d\n", resp.StatusCode)
+		// Readiness responses name the failing components
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		fmt.Fprintf(os.Stderr, "healthcheck failed: status %d: %s\n", resp.StatusCode, strings.TrimSpace(string(body)))
 		return 1
 	}
 	return 0
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/channinghe/labelgate/internal/auth"
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+const tokenUsage = `Usage: labelgate token <command> [flags]
+
+Commands:
+  create <name> --scope <scope>[,...] [--expires 720h]  Create an API token (printed once)
+  list                                                 List API tokens
+  revoke <name>                                        Revoke an API token
+
+Scopes: read, write, agents-admin, admin
+`
+
+// runToken runs the "token" subcommands and returns the process exit code.
+func runToken(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, tokenUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("token "+args[0], flag.ContinueOnError)
+	configPath := fs.StringP("config", "c", "", "Path to configuration file")
+	scopes := fs.StringSlice("scope", nil, "Scopes to grant (read, write, agents-admin, admin)")
+	expires := fs.Duration("expires", 0, "Token lifetime, e.g. 720h (default: never expires)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "create":
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, tokenUsage)
+			return 2
+		}
+		err = tokenCreate(ctx, cfg, fs.Arg(0), *scopes, *expires)
+	case "list":
+		err = tokenList(ctx, cfg)
+	case "revoke":
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, tokenUsage)
+			return 2
+		}
+		err = tokenRevoke(ctx, cfg, fs.Arg(0))
+	default:
+		fmt.Fprint(os.Stderr, tokenUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "token %s failed: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func tokenCreate(ctx context.Context, cfg *config.Config, name string, scopes []string, ttl time.Duration) error {
+	token, plaintext, err := auth.NewToken(name, scopes, ttl)
+	if err != nil {
+		return err
+	}
+
+	store, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.CreateAPIToken(ctx, token); err != nil {
+		if storage.IsConflict(err) {
+			return fmt.Errorf("token %q already exists", name)
+		}
+		return err
+	}
+
+	// The token goes to stdout alone so it can be captured by scripts
+	fmt.Fprintf(os.Stderr, "created token %q with scopes %s; it will not be shown again\n", name, strings.Join(token.Scopes, ","))
+	fmt.Println(plaintext)
+	return nil
+}
+
+func tokenList(ctx context.Context, cfg *config.Config) error {
+	store, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tokens, err := store.ListAPITokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPES\tCREATED\tEXPIRES\tLAST USED")
+	for _, t := range tokens {
+		expires := "never"
+		if t.ExpiresAt != nil {
+			expires = t.ExpiresAt.UTC().Format(time.RFC3339)
+			if t.Expired(now) {
+				expires += " (expired)"
+			}
+		}
+		lastUsed := "never"
+		if t.LastUsedAt != nil {
+			lastUsed = t.LastUsedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Name, strings.Join(t.Scopes, ","), t.CreatedAt.UTC().Format(time.RFC3339), expires, lastUsed)
+	}
+	return w.Flush()
+}
+
+func tokenRevoke(ctx context.Context, cfg *config.Config, name string) error {
+	store, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.DeleteAPIToken(ctx, name); err != nil {
+		if storage.IsNotFound(err) {
+			return fmt.Errorf("no token named %q", name)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "revoked token %q\n", name)
+	return nil
+}
@@ -27,6 +27,14 @@ async function fetchAPI<T>(path: string, params?: Record<string, string>): Promi
   }
 
   const res = await fetch(url.toString(), { headers });
+  if (res.status === 401 && !authToken) {
+    // Send the browser through the OIDC login when the server offers one
+    const body = await res.json().catch(() => ({}));
+    if (body.login_url) {
+      const returnTo = window.location.pathname + window.location.search;
+      window.location.href = `${body.login_url}?return_to=${encodeURIComponent(returnTo)}`;
+    }
+  }
   if (!res.ok) {
     throw new Error(`API error: ${res.status} ${res.statusText}`);
   }
@@ -42,6 +50,14 @@ export interface ResourceCounts {
   error: number;
 }
 
+export interface MaintenanceResult {
+  last_run: string;
+  duration: string;
+  status: 'success' | 'error';
+  detail?: string;
+  error?: string;
+}
+
 export interface OverviewData {
   resources: {
     dns: ResourceCounts;
@@ -65,6 +81,7 @@ export interface OverviewData {
   version: string;
   uptime: string;
   started_at: string;
+  maintenance?: Record<string, MaintenanceResult>;
 }
 
 export interface ManagedResource {
@@ -115,6 +132,27 @@ export interface AgentListResponse {
   total: number;
 }
 
+export interface HostnameConflict {
+  id: string;
+  hostname: string;
+  resource_type: 'dns' | 'tunnel_ingress';
+  container_id: string;
+  container_name: string;
+  service_name: string;
+  agent_id?: string;
+  winner_container_id: string;
+  winner_container_name: string;
+  winner_service_name: string;
+  reason: string;
+  first_seen: string;
+  last_seen: string;
+}
+
+export interface ConflictListResponse {
+  conflicts: HostnameConflict[];
+  total: number;
+}
+
 // --- API Functions ---
 
 export function fetchOverview() {
@@ -136,3 +174,7 @@ export function fetchAccess(params?: Record<string, string>) {
 export function fetchAgents() {
   return fetchAPI<AgentListResponse>('/agents');
 }
+
+export function fetchConflicts() {
+  return fetchAPI<ConflictListResponse>('/conflicts');
+}
@@ -5,9 +5,11 @@ import {
   fetchTunnels,
   fetchAccess,
   fetchAgents,
+  fetchConflicts,
   type OverviewData,
   type ResourceListResponse,
   type AgentListResponse,
+  type ConflictListResponse,
 } from '../api/client';
 
 // Default polling interval: 10 seconds
@@ -56,3 +58,10 @@ export function useAgents() {
     revalidateOnFocus: true,
   });
 }
+
+export function useConflicts() {
+  return useSWR<ConflictListResponse>('/api/conflicts', fetchConflicts, {
+    refreshInterval: POLL_INTERVAL,
+    revalidateOnFocus: true,
+  });
+}
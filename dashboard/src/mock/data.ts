@@ -63,6 +63,7 @@ export interface OverviewData {
   version: string;
   uptime: string;
   started_at: string;
+  maintenance?: Record<string, { last_run: string; duration: string; status: 'success' | 'error'; detail?: string; error?: string }>;
 }
 
 // --- Mock data ---
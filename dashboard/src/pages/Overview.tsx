@@ -10,6 +10,8 @@ import {
   Center,
   Box,
   Skeleton,
+  Table,
+  Badge,
 } from '@mantine/core';
 import {
   IconWorldWww,
@@ -24,9 +26,10 @@ import {
   IconPlugConnected,
   IconPlugConnectedX,
   IconCircleX,
+  IconArrowsSplit,
 } from '@tabler/icons-react';
 import { useNavigate } from 'react-router-dom';
-import { useOverview } from '../hooks/useAPI';
+import { useOverview, useConflicts } from '../hooks/useAPI';
 import { mockOverview, type OverviewData } from '../mock/data';
 import { formatTime } from '../utils/format';
 
@@ -125,6 +128,73 @@ function StatCard({ title, icon: Icon, color, total, breakdowns, to }: StatCardP
   );
 }
 
+function ConflictsPanel() {
+  const { data } = useConflicts();
+  const conflicts = data?.conflicts ?? [];
+
+  if (conflicts.length === 0) {
+    return null;
+  }
+
+  return (
+    <div>
+      <Text size="sm" fw={600} c="dimmed" tt="uppercase" mb="md">
+        Hostname Conflicts
+      </Text>
+      <Paper withBorder p="xl" radius="md">
+        <Group gap="sm" mb="lg">
+          <ThemeIcon variant="light" color="yellow" size="md" radius="md">
+            <IconArrowsSplit size={18} />
+          </ThemeIcon>
+          <Text fw={700} size="lg">
+            {conflicts.length} skipped {conflicts.length === 1 ? 'service' : 'services'}
+          </Text>
+        </Group>
+        <Table.ScrollContainer minWidth={700}>
+          <Table verticalSpacing="sm">
+            <Table.Thead>
+              <Table.Tr>
+                <Table.Th>Hostname</Table.Th>
+                <Table.Th>Type</Table.Th>
+                <Table.Th>Skipped</Table.Th>
+                <Table.Th>Winner</Table.Th>
+                <Table.Th>Reason</Table.Th>
+                <Table.Th>Since</Table.Th>
+              </Table.Tr>
+            </Table.Thead>
+            <Table.Tbody>
+              {conflicts.map((c) => (
+                <Table.Tr key={c.id}>
+                  <Table.Td>
+                    <Text size="sm" ff="monospace">{c.hostname}</Text>
+                  </Table.Td>
+                  <Table.Td>
+                    <Badge variant="light" color={c.resource_type === 'dns' ? 'blue' : 'violet'} size="sm">
+                      {c.resource_type === 'dns' ? 'DNS' : 'Tunnel'}
+                    </Badge>
+                  </Table.Td>
+                  <Table.Td>
+                    <Text size="sm">{c.container_name} / {c.service_name}</Text>
+                  </Table.Td>
+                  <Table.Td>
+                    <Text size="sm">{c.winner_container_name} / {c.winner_service_name}</Text>
+                  </Table.Td>
+                  <Table.Td>
+                    <Text size="sm" c="dimmed">{c.reason}</Text>
+                  </Table.Td>
+                  <Table.Td>
+                    <Text size="sm">{formatTime(c.first_seen)}</Text>
+                  </Table.Td>
+                </Table.Tr>
+              ))}
+            </Table.Tbody>
+          </Table>
+        </Table.ScrollContainer>
+      </Paper>
+    </div>
+  );
+}
+
 export function Overview() {
   const { data: apiData, error, isLoading } = useOverview();
 
@@ -144,6 +214,9 @@ export function Overview() {
     started_at: '',
   };
   const data: OverviewData = useMock ? mockOverview : (apiData ?? emptyOverview);
+  const maintenanceRuns = Object.values(data.maintenance ?? {});
+  const lastMaintenance = maintenanceRuns.reduce((latest, r) => (r.last_run > latest ? r.last_run : latest), '');
+  const maintenanceFailed = maintenanceRuns.some((r) => r.status === 'error');
 
   if (isLoading && !apiData) {
     return (
@@ -208,6 +281,8 @@ export function Overview() {
           </SimpleGrid>
         </div>
 
+        <ConflictsPanel />
+
         {/* Agents section */}
         <div>
           <Text size="sm" fw={600} c="dimmed" tt="uppercase" mb="md">
@@ -341,6 +416,14 @@ export function Overview() {
                     {formatTime(data.started_at)}
                   </Text>
                 </Group>
+                {maintenanceRuns.length > 0 && (
+                  <Group justify="space-between">
+                    <Text size="sm" c="dimmed">DB Maintenance</Text>
+                    <Text size="sm" c={maintenanceFailed ? 'red' : undefined}>
+                      {formatTime(lastMaintenance)}
+                    </Text>
+                  </Group>
+                )}
               </Stack>
             </Paper>
           </SimpleGrid>
@@ -11,26 +11,52 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 
+	"github.com/channinghe/labelgate/internal/events"
 	"github.com/channinghe/labelgate/internal/storage"
 )
 
+// inboundDialer is the connection loop to one inbound agent.
+type inboundDialer struct {
+	endpoint string
+	token    string
+	cancel   context.CancelFunc
+}
+
 // ConnectToInboundAgents starts goroutines to connect to all agents that have
 // ConnectTo configured (inbound mode). Each connection runs in its own goroutine
 // with automatic reconnection.
 func (s *Server) ConnectToInboundAgents(ctx context.Context) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Loops of a previous call ended with its context
+	s.inboundCtx = ctx
+	s.inbound = make(map[string]*inboundDialer)
+	s.updateInboundLocked()
+}
+
+// updateInboundLocked starts connection loops for inbound agents that have
+// none and stops the loops of agents whose connect_to or token changed or
+// that were removed. s.mu must be held.
+func (s *Server) updateInboundLocked() {
+	if s.inboundCtx == nil {
+		return
+	}
+
+	for agentID, dialer := range s.inbound {
+		if cfg := s.agents[agentID]; cfg == nil || cfg.ConnectTo != dialer.endpoint || cfg.Token != dialer.token {
+			dialer.cancel()
+			delete(s.inbound, agentID)
+		}
+	}
 
 	for agentID, cfg := range s.agents {
-		if cfg.ConnectTo == "" {
+		if cfg.ConnectTo == "" || s.inbound[agentID] != nil {
 			continue
 		}
-
-		id := agentID
-		endpoint := cfg.ConnectTo
-		token := cfg.Token
-
-		go s.connectToInboundAgent(ctx, id, endpoint, token)
+		ctx, cancel := context.WithCancel(s.inboundCtx)
+		s.inbound[agentID] = &inboundDialer{endpoint: cfg.ConnectTo, token: cfg.Token, cancel: cancel}
+		go s.connectToInboundAgent(ctx, agentID, cfg.ConnectTo, cfg.Token)
 	}
 }
 
@@ -178,6 +204,7 @@ func (s *Server) dialInboundAgent(ctx context.Context, expectedAgentID, endpoint
 		Str("agent_id", auth.AgentID).
 		Str("mode", "inbound").
 		Msg("Inbound agent authenticated and registered")
+	s.publishConnection(events.TypeAgentConnected, auth.AgentID, "inbound")
 
 	// Persist agent to storage
 	if s.storage != nil {
@@ -5,17 +5,23 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/metrics"
 	"github.com/channinghe/labelgate/internal/reconciler"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
 	"github.com/channinghe/labelgate/internal/types"
 	"github.com/channinghe/labelgate/pkg/labels"
 )
@@ -36,13 +42,18 @@ type AgentConnection struct {
 // Server is the WebSocket server for agent connections.
 type Server struct {
 	config      *config.AgentServerConfig
+	acceptToken string                       // guarded by mu, replaced by UpdateAgents
 	agents      map[string]*AgentConfigEntry // agentID -> config (from main config)
 	connections map[string]*AgentConnection  // agentID -> connection
+	inbound     map[string]*inboundDialer    // agentID -> outgoing connection to an inbound agent
+	inboundCtx  context.Context              // context of ConnectToInboundAgents (nil before)
 	reconciler  *reconciler.Reconciler
 	storage     storage.Storage
 	parser      *labels.Parser
+	bus         *events.Bus
 	mu          sync.RWMutex
 	upgrader    websocket.Upgrader
+	listening   atomic.Bool
 }
 
 // NewServer creates a new agent server.
@@ -54,8 +65,10 @@ func NewServer(cfg *config.AgentServerConfig, agentConfigs map[string]*AgentConf
 
 	return &Server{
 		config:      cfg,
+		acceptToken: cfg.AcceptToken,
 		agents:      agents,
 		connections: make(map[string]*AgentConnection),
+		inbound:     make(map[string]*inboundDialer),
 		reconciler:  rec,
 		storage:     store,
 		parser:      labels.NewParser(labelPrefix),
@@ -69,6 +82,29 @@ func NewServer(cfg *config.AgentServerConfig, agentConfigs map[string]*AgentConf
 	}
 }
 
+// SetEventBus publishes agent connect and disconnect events to bus.
+// It must be called before Start.
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.bus = bus
+}
+
+// publishConnection publishes an agent connection state change and updates
+// the connection metric. mode is "outbound" or "inbound" for connects and
+// empty for disconnects.
+func (s *Server) publishConnection(eventType events.Type, agentID, mode string) {
+	data := map[string]string{"agent_id": agentID}
+	if mode != "" {
+		data["mode"] = mode
+	}
+	s.bus.Publish(events.Event{Type: eventType, Data: data})
+
+	connected := 0.0
+	if eventType == events.TypeAgentConnected {
+		connected = 1
+	}
+	metrics.AgentConnected.WithLabelValues(agentID).Set(connected)
+}
+
 // AgentConfigEntry holds agent configuration entry.
 type AgentConfigEntry struct {
 	Token         string
@@ -76,6 +112,55 @@ type AgentConfigEntry struct {
 	ConnectTo     string
 }
 
+// ConfigEntries returns the pre-configured agent entries of cfg.
+func ConfigEntries(cfg *config.AgentServerConfig) map[string]*AgentConfigEntry {
+	entries := make(map[string]*AgentConfigEntry, len(cfg.Agents))
+	for id, entry := range cfg.Agents {
+		entries[id] = &AgentConfigEntry{
+			Token:         entry.Token,
+			DefaultTunnel: entry.DefaultTunnel,
+			ConnectTo:     entry.ConnectTo,
+		}
+	}
+	return entries
+}
+
+// UpdateAgents replaces the pre-configured agents and the accept token, e.g.
+// on a configuration reload. Agents registered through the accept token stay
+// registered while their token is still accepted. Connected agents whose
+// entry was removed or changed are disconnected, so they authenticate again
+// with the new settings, and connections to inbound agents follow the new
+// connect_to entries.
+func (s *Server) UpdateAgents(acceptToken string, agentConfigs map[string]*AgentConfigEntry) {
+	s.mu.Lock()
+	agents := make(map[string]*AgentConfigEntry, len(agentConfigs))
+	for id, ac := range agentConfigs {
+		agents[id] = ac
+	}
+	for id, ac := range s.agents {
+		if _, ok := agents[id]; !ok && acceptToken != "" && ac.Token == acceptToken {
+			agents[id] = &AgentConfigEntry{Token: ac.Token, DefaultTunnel: "default"}
+		}
+	}
+
+	var stale []*AgentConnection
+	for id, conn := range s.connections {
+		if prev, next := s.agents[id], agents[id]; next == nil || prev == nil || *prev != *next {
+			stale = append(stale, conn)
+		}
+	}
+	s.agents = agents
+	s.acceptToken = acceptToken
+	s.updateInboundLocked()
+	s.mu.Unlock()
+
+	// Closing the connection ends its read loop, which unregisters it
+	for _, conn := range stale {
+		log.Info().Str("agent_id", conn.ID).Msg("Disconnecting agent after configuration change")
+		conn.Conn.Close()
+	}
+}
+
 // Start starts the WebSocket server.
 func (s *Server) Start(ctx context.Context) error {
 	if !s.config.Enabled {
@@ -103,14 +188,21 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}
 
+	ln, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Listen, err)
+	}
+	s.listening.Store(true)
+
 	// Start server in goroutine
 	go func() {
+		defer s.listening.Store(false)
 		log.Info().Str("address", s.config.Listen).Msg("Starting agent server")
 		var err error
 		if server.TLSConfig != nil {
-			err = server.ListenAndServeTLS("", "")
+			err = server.ServeTLS(ln, "", "")
 		} else {
-			err = server.ListenAndServe()
+			err = server.Serve(ln)
 		}
 		if err != nil && err != http.ErrServerClosed {
 			log.Error().Err(err).Msg("Agent server error")
@@ -124,17 +216,27 @@ func (s *Server) Start(ctx context.Context) error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Close all connections
+	// Close all connections. The map is reset so that a later Start (after
+	// leadership is regained) doesn't close their done channels again when
+	// the agents reconnect.
 	s.mu.Lock()
-	for _, conn := range s.connections {
+	connections := s.connections
+	s.connections = make(map[string]*AgentConnection)
+	s.mu.Unlock()
+	for id, conn := range connections {
 		close(conn.done)
 		conn.Conn.Close()
+		s.publishConnection(events.TypeAgentDisconnected, id, "")
 	}
-	s.mu.Unlock()
 
 	return server.Shutdown(shutdownCtx)
 }
 
+// Listening reports whether the server is accepting agent connections.
+func (s *Server) Listening() bool {
+	return s.listening.Load()
+}
+
 // handleWebSocket handles WebSocket connection upgrade.
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := s.upgrader.Upgrade(w, r, nil)
@@ -187,7 +289,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			conn.Close()
 			return
 		}
-	} else if s.config.AcceptToken != "" && auth.Token == s.config.AcceptToken {
+	} else if s.acceptToken != "" && auth.Token == s.acceptToken {
 		// Dynamic agent: accept_token matches, register on the fly
 		agentConfig = &AgentConfigEntry{
 			Token:         auth.Token,
@@ -228,6 +330,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		Str("remote", conn.RemoteAddr().String()).
 		Str("version", auth.Version).
 		Msg("Agent connected")
+	s.publishConnection(events.TypeAgentConnected, auth.AgentID, "outbound")
 
 	// Persist agent to storage
 	if s.storage != nil {
@@ -344,14 +447,22 @@ func (s *Server) handleMessage(agent *AgentConnection, msg *Message) {
 
 // handleReport handles agent data report.
 func (s *Server) handleReport(agent *AgentConnection, msg *Message) {
+	ctx, span := tracing.Start(context.Background(), "agent.report", attribute.String("labelgate.agent_id", agent.ID))
+	defer span.End()
+
 	var report ReportPayload
 	if err := msg.ParsePayload(&report); err != nil {
 		log.Error().Err(err).Str("agent", agent.ID).Msg("Failed to parse report")
+		tracing.RecordError(span, err)
 		return
 	}
+	span.SetAttributes(attribute.Int("labelgate.containers", len(report.Containers)))
 
 	agent.PublicIP = report.PublicIP
 	agent.LastSeen = report.Timestamp
+	if !report.Timestamp.IsZero() {
+		metrics.AgentReportLatency.WithLabelValues(agent.ID).Observe(max(time.Since(report.Timestamp).Seconds(), 0))
+	}
 
 	// Parse containers and update reconciler
 	var parsedContainers []*types.ParsedContainer
@@ -371,7 +482,7 @@ func (s *Server) handleReport(agent *AgentConnection, msg *Message) {
 	// Update agent metadata in storage
 	if s.storage != nil {
 		now := time.Now()
-		if err := s.storage.SaveAgent(context.Background(), &storage.Agent{
+		if err := s.storage.SaveAgent(ctx, &storage.Agent{
 			ID:        agent.ID,
 			Name:      agent.Name,
 			Connected: true,
@@ -402,12 +513,14 @@ func (s *Server) handleReport(agent *AgentConnection, msg *Message) {
 func (s *Server) parseContainerLabels(container *types.ContainerInfo, agentID string) *types.ParsedContainer {
 	result := s.parser.Parse(container.Labels)
 
-	// Log any parse errors
+	// Log any parse errors; they are also kept for the containers API
+	var errs []string
 	for _, err := range result.Errors {
 		log.Warn().
 			Err(err).
 			Str("container", container.Name).
 			Msg("Label parsing error")
+		errs = append(errs, err.Error())
 	}
 
 	// Check hostname conflicts
@@ -416,9 +529,11 @@ func (s *Server) parseContainerLabels(container *types.ContainerInfo, agentID st
 			Err(err).
 			Str("container", container.Name).
 			Msg("Hostname conflict detected")
+		errs = append(errs, err.Error())
 	}
 
-	if len(result.DNSServices) == 0 && len(result.TunnelServices) == 0 && len(result.AccessPolicies) == 0 {
+	// Containers with only invalid labels are kept so their errors are visible
+	if len(result.DNSServices) == 0 && len(result.TunnelServices) == 0 && len(result.AccessPolicies) == 0 && len(errs) == 0 {
 		return nil
 	}
 
@@ -428,14 +543,20 @@ func (s *Server) parseContainerLabels(container *types.ContainerInfo, agentID st
 		TunnelServices: result.TunnelServices,
 		AccessPolicies: result.AccessPolicies,
 		AgentID:        agentID,
+		Priority:       result.Priority,
+		Rollout:        result.Rollout,
+		Errors:         errs,
 	}
 }
 
 // handleDisconnect handles agent disconnection.
 func (s *Server) handleDisconnect(agent *AgentConnection) {
 	s.mu.Lock()
+	// A replaced connection closes after its successor registered
+	current := false
 	if conn, ok := s.connections[agent.ID]; ok && conn == agent {
 		delete(s.connections, agent.ID)
+		current = true
 	}
 	s.mu.Unlock()
 
@@ -455,6 +576,9 @@ func (s *Server) handleDisconnect(agent *AgentConnection) {
 	}
 
 	log.Info().Str("agent", agent.ID).Msg("Agent disconnected")
+	if current {
+		s.publishConnection(events.TypeAgentDisconnected, agent.ID, "")
+	}
 }
 
 // handleHealth handles health check requests.
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/channinghe/labelgate/internal/config"
+)
+
+// freeAddress returns a local address that is free to listen on.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// startServer runs s.Start until the returned stop function is called.
+func startServer(t *testing.T, s *Server) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Start(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.Listening() {
+		if time.Now().After(deadline) {
+			t.Fatal("agent server did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return func() {
+		cancel()
+		if err := <-stopped; err != nil {
+			t.Errorf("agent server stopped with error: %v", err)
+		}
+	}
+}
+
+// connectAgent connects to s as agentID and waits for the ack.
+func connectAgent(t *testing.T, s *Server, agentID, token string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+s.config.Listen+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	msg, _ := NewMessageWithID(MessageTypeAuth, "auth-1", &AuthPayload{AgentID: agentID, Token: token})
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	var reply Message
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&reply); err != nil || reply.Type != MessageTypeAck {
+		t.Fatalf("expected ack, got %+v (%v)", reply, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	return conn
+}
+
+// waitConnected waits until agentID is connected to s.
+func waitConnected(t *testing.T, s *Server, agentID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.IsAgentConnected(agentID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("agent %s did not connect", agentID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// A leader that loses and regains leadership restarts the same server; agents
+// reconnecting in the new term must replace nothing from the previous one.
+func TestServerRestartAfterLeadershipChange(t *testing.T) {
+	cfg := &config.AgentServerConfig{Enabled: true, Listen: freeAddress(t)}
+	s := NewServer(cfg, map[string]*AgentConfigEntry{"edge": {Token: "secret"}}, nil, nil, "labelgate")
+
+	// Lead
+	stop := startServer(t, s)
+	connectAgent(t, s, "edge", "secret")
+	waitConnected(t, s, "edge")
+
+	// Follow
+	stop()
+	if s.IsAgentConnected("edge") {
+		t.Error("expected no connected agents after shutdown")
+	}
+
+	// Lead again: the agent reconnects, then replaces its own connection
+	stop = startServer(t, s)
+	defer stop()
+	connectAgent(t, s, "edge", "secret")
+	waitConnected(t, s, "edge")
+	connectAgent(t, s, "edge", "secret")
+	waitConnected(t, s, "edge")
+
+	if agents := s.GetConnectedAgents(); len(agents) != 1 {
+		t.Errorf("expected one connection for edge, got %v", agents)
+	}
+}
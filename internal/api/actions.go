@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/agent"
+	"github.com/channinghe/labelgate/internal/reconciler"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// Audit actions recorded for write endpoints.
+const (
+	auditReconcile           = "reconcile"
+	auditResourceRetry       = "resource.retry"
+	auditResourceForceDelete = "resource.force_delete"
+	auditResourceForget      = "resource.forget"
+	auditAgentRefresh        = "agent.refresh"
+	auditAgentReconnect      = "agent.reconnect"
+)
+
+// handleReconcile runs a full reconciliation and waits for it to finish.
+func (s *Server) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	rec := s.liveReconciler()
+	if rec == nil {
+		s.writeUnavailable(w, r, auditReconcile, "", "reconciler is not running on this instance")
+		return
+	}
+
+	// Don't abandon a half-applied reconcile when the client disconnects
+	ctx := context.WithoutCancel(r.Context())
+	err := rec.TriggerReconcile(ctx)
+	s.audit(r, auditReconcile, "", err, "")
+
+	resp := map[string]any{
+		"last_sync": rec.LastSyncTime().UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRetryResource re-reconciles the hostname of a resource in error status.
+func (s *Server) handleRetryResource(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec := s.liveReconciler()
+	if rec == nil {
+		s.writeUnavailable(w, r, auditResourceRetry, id, "reconciler is not running on this instance")
+		return
+	}
+
+	resource, err := rec.RetryResource(context.WithoutCancel(r.Context()), id)
+	s.audit(r, auditResourceRetry, id, err, "")
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"resource": resource})
+}
+
+// handleForceDeleteResource deletes an orphaned resource from Cloudflare now.
+func (s *Server) handleForceDeleteResource(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec := s.liveReconciler()
+	if rec == nil {
+		s.writeUnavailable(w, r, auditResourceForceDelete, id, "reconciler is not running on this instance")
+		return
+	}
+
+	err := rec.ForceDeleteResource(context.WithoutCancel(r.Context()), id)
+	s.audit(r, auditResourceForceDelete, id, err, "")
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// handleForgetResource drops a resource from storage without touching Cloudflare.
+func (s *Server) handleForgetResource(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec := s.liveReconciler()
+	if rec == nil {
+		s.writeUnavailable(w, r, auditResourceForget, id, "reconciler is not running on this instance")
+		return
+	}
+
+	err := rec.ForgetResource(r.Context(), id)
+	s.audit(r, auditResourceForget, id, err, "")
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "forgotten"})
+}
+
+// handleAgentRefresh asks an agent to re-report its containers.
+func (s *Server) handleAgentRefresh(w http.ResponseWriter, r *http.Request) {
+	s.sendAgentCommand(w, r, auditAgentRefresh, agent.CommandActionRefresh)
+}
+
+// handleAgentReconnect asks an agent to drop and re-establish its connection.
+func (s *Server) handleAgentReconnect(w http.ResponseWriter, r *http.Request) {
+	s.sendAgentCommand(w, r, auditAgentReconnect, agent.CommandActionReconnect)
+}
+
+func (s *Server) sendAgentCommand(w http.ResponseWriter, r *http.Request, auditAction, command string) {
+	id := r.PathValue("id")
+	agentServer := s.liveAgentServer()
+	if agentServer == nil {
+		s.writeUnavailable(w, r, auditAction, id, "agent server is not running on this instance")
+		return
+	}
+	if !agentServer.IsAgentConnected(id) {
+		err := fmt.Errorf("agent not connected: %s", id)
+		s.audit(r, auditAction, id, err, "")
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	err := agentServer.SendCommand(id, command)
+	s.audit(r, auditAction, id, err, "")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "sent"})
+}
+
+// liveReconciler returns the reconciler if this instance is running it.
+// Followers keep a reconciler but must not write to Cloudflare.
+func (s *Server) liveReconciler() *reconciler.Reconciler {
+	if s.config.Elector != nil && !s.config.Elector.IsLeader() {
+		return nil
+	}
+	return s.config.Reconciler
+}
+
+// writeUnavailable records and rejects an action this instance can't perform.
+func (s *Server) writeUnavailable(w http.ResponseWriter, r *http.Request, action, target, message string) {
+	s.audit(r, action, target, errors.New(message), "")
+	writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": message})
+}
+
+// writeActionError maps reconciler action errors to HTTP status codes.
+func writeActionError(w http.ResponseWriter, err error) {
+	var stateErr *reconciler.ResourceStateError
+	switch {
+	case storage.IsNotFound(err):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.As(err, &stateErr):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+// audit records the outcome of a write action. Failures to record are logged
+// but don't fail the request, since the action itself already happened.
+func (s *Server) audit(r *http.Request, action, target string, actionErr error, detail string) {
+	entry := &storage.AuditEntry{
+		Action: action,
+		Target: target,
+		Actor:  auditActor(r),
+		Status: storage.AuditSuccess,
+		Detail: detail,
+	}
+	if actionErr != nil {
+		entry.Status = storage.AuditError
+		entry.Error = actionErr.Error()
+	}
+
+	if err := s.config.Storage.RecordAudit(context.WithoutCancel(r.Context()), entry); err != nil {
+		log.Error().Err(err).Str("action", action).Str("target", target).Msg("Failed to record audit entry")
+	}
+}
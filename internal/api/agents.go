@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/rs/zerolog/log"
+
 	"github.com/channinghe/labelgate/internal/storage"
 )
 
@@ -29,17 +31,16 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resourceCounts := s.countAgentResources(ctx)
+
 	result := make([]agentResponse, 0, len(agents))
 	for _, a := range agents {
 		connected := a.Connected
 		// Override with live connection status if agent server is available
-		if s.config.AgentServer != nil {
-			connected = s.config.AgentServer.IsAgentConnected(a.ID)
+		if agentServer := s.liveAgentServer(); agentServer != nil {
+			connected = agentServer.IsAgentConnected(a.ID)
 		}
 
-		// Count resources managed by this agent
-		resourceCount := s.countAgentResources(ctx, a.ID)
-
 		result = append(result, agentResponse{
 			ID:            a.ID,
 			Name:          a.Name,
@@ -48,7 +49,7 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 			PublicIP:      a.PublicIP,
 			DefaultTunnel: a.DefaultTunnel,
 			Status:        string(a.Status),
-			ResourceCount: resourceCount,
+			ResourceCount: resourceCounts[a.ID],
 			CreatedAt:     a.CreatedAt,
 		})
 	}
@@ -59,13 +60,18 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// countAgentResources counts resources belonging to an agent.
-func (s *Server) countAgentResources(ctx context.Context, agentID string) int {
-	resources, err := s.config.Storage.ListResources(ctx, storage.ResourceFilter{
-		AgentID: agentID,
-	})
+// countAgentResources counts resources per agent in a single query.
+func (s *Server) countAgentResources(ctx context.Context) map[string]int {
+	counts := make(map[string]int)
+	groups, err := s.config.Storage.CountResourcesByGroup(ctx, storage.ResourceFilter{})
 	if err != nil {
-		return 0
+		log.Warn().Err(err).Msg("Failed to count agent resources")
+		return counts
+	}
+	for _, g := range groups {
+		if g.AgentID != "" {
+			counts[g.AgentID] += g.Count
+		}
 	}
-	return len(resources)
+	return counts
 }
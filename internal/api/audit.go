@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/auth"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// handleAudit returns the audit trail of write actions, newest first.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	var filter storage.AuditFilter
+	if err := applyAuditFilters(r, &filter); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	entries, err := s.config.Storage.ListAuditEntries(r.Context(), filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []*storage.AuditEntry{}
+	}
+	total, err := s.config.Storage.CountAuditEntries(r.Context(), filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"entries": entries,
+		"total":   total,
+	})
+}
+
+// applyAuditFilters populates an AuditFilter from query parameters.
+func applyAuditFilters(r *http.Request, filter *storage.AuditFilter) error {
+	q := r.URL.Query()
+	filter.Action = q.Get("action")
+	filter.Target = q.Get("target")
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return fmt.Errorf("invalid since %q (expected RFC 3339 time)", since)
+		}
+		filter.Since = t
+	}
+
+	filter.Limit = defaultEventLimit
+	if limit := q.Get("limit"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil && n > 0 {
+			filter.Limit = n
+		}
+	}
+	if offset := q.Get("offset"); offset != "" {
+		if n, err := strconv.Atoi(offset); err == nil {
+			filter.Offset = n
+		}
+	}
+	return nil
+}
+
+// auditActor identifies who made a request for the audit trail: the API
+// token name when authenticated, otherwise the client address.
+func auditActor(r *http.Request) string {
+	if p := auth.PrincipalFrom(r.Context()); p != nil && p.Name != "" {
+		return p.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
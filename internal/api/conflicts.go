@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+func (s *Server) handleConflicts(w http.ResponseWriter, r *http.Request) {
+	conflicts, err := s.config.Storage.ListConflicts(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if conflicts == nil {
+		conflicts = []*storage.Conflict{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"conflicts": conflicts,
+		"total":     len(conflicts),
+	})
+}
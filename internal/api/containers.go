@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/types"
+)
+
+// containerView is a container as seen by the reconciler: its raw labels,
+// what labelgate parsed from them, and what it manages on its behalf.
+type containerView struct {
+	*types.ParsedContainer
+
+	// Conflicts are the hostname conflicts the container lost or won
+	Conflicts []*storage.Conflict `json:"conflicts"`
+
+	// Resources are the managed resources owned by the container
+	Resources []*storage.ManagedResource `json:"resources"`
+}
+
+// liveStatuses are the resource statuses shown for containers (not deleted).
+var liveStatuses = []storage.ResourceStatus{
+	storage.StatusActive,
+	storage.StatusError,
+	storage.StatusOrphaned,
+	storage.StatusPendingCleanup,
+}
+
+// handleContainers lists the labeled containers known to the reconciler,
+// local and agent-reported. Filter with agent_id ("local" for the local
+// Docker host) and errors=true (only containers with label errors).
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	rec := s.liveReconciler()
+	if rec == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "reconciler is not running on this instance"})
+		return
+	}
+
+	agentID := r.URL.Query().Get("agent_id")
+	onlyErrors := r.URL.Query().Get("errors") == "true"
+
+	var containers []*types.ParsedContainer
+	for _, c := range rec.GetContainers() {
+		if agentID == "local" && c.AgentID != "" || agentID != "" && agentID != "local" && c.AgentID != agentID {
+			continue
+		}
+		if onlyErrors && len(c.Errors) == 0 {
+			continue
+		}
+		containers = append(containers, c)
+	}
+
+	views, err := s.containerViews(r, containers, storage.ResourceFilter{Statuses: liveStatuses})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"containers": views,
+		"total":      len(views),
+	})
+}
+
+// handleContainer returns one container by ID, unique ID prefix or name.
+func (s *Server) handleContainer(w http.ResponseWriter, r *http.Request) {
+	rec := s.liveReconciler()
+	if rec == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "reconciler is not running on this instance"})
+		return
+	}
+
+	id := r.PathValue("id")
+	var matches []*types.ParsedContainer
+	for _, c := range rec.GetContainers() {
+		if c.Info.ID == id || strings.TrimPrefix(c.Info.Name, "/") == strings.TrimPrefix(id, "/") {
+			matches = []*types.ParsedContainer{c}
+			break
+		}
+		if strings.HasPrefix(c.Info.ID, id) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "container not found: " + id})
+		return
+	case 1:
+	default:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "ambiguous container ID prefix: " + id})
+		return
+	}
+
+	views, err := s.containerViews(r, matches, storage.ResourceFilter{
+		ContainerID: matches[0].Info.ID,
+		Statuses:    liveStatuses,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, views[0])
+}
+
+// containerViews attaches conflicts and the resources matching filter to
+// each container, sorted by agent and name.
+func (s *Server) containerViews(r *http.Request, containers []*types.ParsedContainer, filter storage.ResourceFilter) ([]*containerView, error) {
+	resources, err := s.config.Storage.ListResources(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := s.config.Storage.ListConflicts(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*containerView, 0, len(containers))
+	byID := make(map[string]*containerView, len(containers))
+	for _, c := range containers {
+		v := &containerView{
+			ParsedContainer: c,
+			Conflicts:       []*storage.Conflict{},
+			Resources:       []*storage.ManagedResource{},
+		}
+		views = append(views, v)
+		byID[c.Info.ID] = v
+	}
+
+	for _, res := range resources {
+		if v, ok := byID[res.ContainerID]; ok {
+			v.Resources = append(v.Resources, res)
+		}
+	}
+	for _, c := range conflicts {
+		if v, ok := byID[c.ContainerID]; ok {
+			v.Conflicts = append(v.Conflicts, c)
+		}
+		if v, ok := byID[c.WinnerContainerID]; ok && c.WinnerContainerID != c.ContainerID {
+			v.Conflicts = append(v.Conflicts, c)
+		}
+	}
+
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].AgentID != views[j].AgentID {
+			return views[i].AgentID < views[j].AgentID
+		}
+		return views[i].Info.Name < views[j].Info.Name
+	})
+	return views, nil
+}
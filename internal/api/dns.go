@@ -13,15 +13,29 @@ func (s *Server) handleDNS(w http.ResponseWriter, r *http.Request) {
 	}
 	applyQueryFilters(r, &filter)
 
+	s.writeResources(w, r, filter)
+}
+
+// writeResources writes one page of resources matching filter; total is the
+// number of matches across all pages.
+func (s *Server) writeResources(w http.ResponseWriter, r *http.Request, filter storage.ResourceFilter) {
 	resources, err := s.config.Storage.ListResources(r.Context(), filter)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	if resources == nil {
+		resources = []*storage.ManagedResource{}
+	}
+	total, err := s.config.Storage.CountResources(r.Context(), filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"resources": resources,
-		"total":     len(resources),
+		"total":     total,
 	})
 }
 
@@ -1,11 +1,182 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 )
 
+// healthCheckTimeout bounds each readiness check.
+const healthCheckTimeout = 5 * time.Second
+
+// reconcileAgeFactor is how many poll intervals may pass since the last
+// reconcile before the instance is reported not ready, allowing a couple
+// of missed periodic reconciles.
+const reconcileAgeFactor = 3
+
+// healthProbeKey is the sync state key written to check that storage is writable.
+const healthProbeKey = "health_probe"
+
+// storageProbeInterval is how long a storage write probe result is reused, so
+// frequent unauthenticated readiness probes don't each write to the database.
+const storageProbeInterval = 30 * time.Second
+
+// Component check statuses.
+const (
+	checkOK      = "ok"
+	checkFail    = "fail"
+	checkSkipped = "skipped"
+)
+
+// componentCheck is the result of one readiness check.
+type componentCheck struct {
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// errSkipped marks a check that doesn't apply to this instance; its message
+// is reported as the detail.
+type errSkipped string
+
+func (e errSkipped) Error() string { return string(e) }
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{
 		"status": "ok",
 	})
 }
+
+// handleHealthz is the liveness check: it succeeds while the process serves
+// requests. Dependencies are deliberately not checked, so an outage of
+// Cloudflare or Docker doesn't get the container restarted.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+}
+
+// handleReadyz is the readiness check: it runs every component check and
+// returns 503 if any of them fails.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) (string, error){
+		"storage":      s.checkStorage,
+		"docker":       s.checkDocker,
+		"reconcile":    s.checkReconcile,
+		"cloudflare":   s.checkCloudflare,
+		"agent_server": s.checkAgentServer,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]componentCheck, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check(ctx)
+			result := componentCheck{Status: checkOK, DurationMS: time.Since(start).Milliseconds(), Detail: detail}
+			var skipped errSkipped
+			switch {
+			case errors.As(err, &skipped):
+				result.Status, result.Detail = checkSkipped, skipped.Error()
+			case err != nil:
+				result.Status, result.Error = checkFail, err.Error()
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	status, code := checkOK, http.StatusOK
+	for _, result := range results {
+		if result.Status == checkFail {
+			status, code = checkFail, http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, code, map[string]any{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// checkStorage writes a probe value, so a read-only or locked database fails.
+// The result is reused for storageProbeInterval.
+func (s *Server) checkStorage(ctx context.Context) (string, error) {
+	s.storageProbe.mu.Lock()
+	defer s.storageProbe.mu.Unlock()
+
+	if time.Since(s.storageProbe.at) >= storageProbeInterval {
+		s.storageProbe.err = s.config.Storage.SetSyncState(ctx, healthProbeKey, time.Now().UTC().Format(time.RFC3339))
+		s.storageProbe.at = time.Now()
+	}
+	return "", s.storageProbe.err
+}
+
+func (s *Server) checkDocker(ctx context.Context) (string, error) {
+	if s.config.Docker == nil {
+		return "", errSkipped("no Docker provider")
+	}
+	return "", s.config.Docker.Ping(ctx)
+}
+
+// checkReconcile fails when no reconcile finished within reconcileAgeFactor
+// poll intervals, e.g. because the reconcile loop is stuck. Reconcile errors are reported
+// as the detail only, since Cloudflare problems show up in their own check.
+func (s *Server) checkReconcile(ctx context.Context) (string, error) {
+	rec := s.liveReconciler()
+	if rec == nil {
+		return "", errSkipped("reconciler is not running on this instance")
+	}
+	last := rec.LastSyncTime()
+	if last.IsZero() {
+		return "", fmt.Errorf("no reconcile finished since start %s ago", formatDuration(time.Since(rec.StartedAt())))
+	}
+
+	age := time.Since(last).Round(time.Second)
+	maxAge := reconcileAgeFactor * rec.Timings().PollInterval
+	if maxAge > 0 && age > maxAge {
+		return "", fmt.Errorf("last reconcile finished %s ago (max %s)", age, maxAge)
+	}
+	detail := fmt.Sprintf("last reconcile finished %s ago", age)
+	if err := rec.LastSyncError(); err != nil {
+		detail += " with errors: " + err.Error()
+	}
+	return detail, nil
+}
+
+func (s *Server) checkCloudflare(ctx context.Context) (string, error) {
+	if s.config.CredManager == nil {
+		return "", errSkipped("no Cloudflare credentials")
+	}
+	result := s.config.CredManager.HealthCheck(ctx)
+	if !result.Reachable {
+		return "", fmt.Errorf("Cloudflare API unreachable: %s", result.Error)
+	}
+	return "", nil
+}
+
+func (s *Server) checkAgentServer(ctx context.Context) (string, error) {
+	if s.config.AgentServer == nil {
+		return "", errSkipped("agent server is disabled")
+	}
+	agentServer := s.liveAgentServer()
+	if agentServer == nil {
+		return "", errSkipped("agent server is not running on this instance")
+	}
+	if !agentServer.Listening() {
+		return "", errors.New("agent server is not listening")
+	}
+	return "", nil
+}
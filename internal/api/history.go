@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// defaultEventLimit caps history responses when no limit is given.
+const defaultEventLimit = 100
+
+// handleResourceHistory returns the history of one resource, newest first.
+// History outlives the resource, so deleted resources still return events.
+func (s *Server) handleResourceHistory(w http.ResponseWriter, r *http.Request) {
+	filter := storage.EventFilter{
+		ResourceID: r.PathValue("id"),
+	}
+	if err := applyEventFilters(r, &filter); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.writeEvents(w, r, filter)
+}
+
+// handleEvents returns resource history across all resources, newest first.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	var filter storage.EventFilter
+	if err := applyEventFilters(r, &filter); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.writeEvents(w, r, filter)
+}
+
+func (s *Server) writeEvents(w http.ResponseWriter, r *http.Request, filter storage.EventFilter) {
+	events, err := s.config.Storage.ListResourceEvents(r.Context(), filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if events == nil {
+		events = []*storage.ResourceEvent{}
+	}
+	total, err := s.config.Storage.CountResourceEvents(r.Context(), filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"events": events,
+		"total":  total,
+	})
+}
+
+// applyEventFilters populates an EventFilter from query parameters.
+func applyEventFilters(r *http.Request, filter *storage.EventFilter) error {
+	q := r.URL.Query()
+	if resourceType := q.Get("resource_type"); resourceType != "" {
+		filter.ResourceType = storage.ResourceType(resourceType)
+	}
+	if hostname := q.Get("hostname"); hostname != "" {
+		filter.Hostname = hostname
+	}
+	if action := q.Get("action"); action != "" {
+		filter.Action = storage.ResourceAction(action)
+	}
+	if reconcileID := q.Get("reconcile_id"); reconcileID != "" {
+		filter.ReconcileID = reconcileID
+	}
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return fmt.Errorf("invalid since %q (expected RFC 3339 time)", since)
+		}
+		filter.Since = t
+	}
+
+	filter.Limit = defaultEventLimit
+	if limit := q.Get("limit"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil && n > 0 {
+			filter.Limit = n
+		}
+	}
+	if offset := q.Get("offset"); offset != "" {
+		if n, err := strconv.Atoi(offset); err == nil {
+			filter.Offset = n
+		}
+	}
+	return nil
+}
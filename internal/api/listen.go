@@ -0,0 +1,220 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// certCheckInterval is how often the certificate files are checked for changes.
+const certCheckInterval = 10 * time.Second
+
+// TLSConfig configures HTTPS for the API server.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile verifies client certificates, which then authenticate
+	// requests as identities (empty disables client certificates)
+	ClientCAFile string
+
+	// RequireClientCert rejects connections without a valid client certificate
+	RequireClientCert bool
+}
+
+// newTLSConfig creates the server TLS configuration, serving the certificate
+// through a certReloader.
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, nil
+}
+
+// certReloader serves a certificate and key pair from disk, reloading it
+// when either file changes so renewed certificates need no restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: certCheckInterval}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. A certificate that
+// fails to load, e.g. while being rewritten, keeps the current one in use.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= r.interval {
+		r.checked = time.Now()
+		modTime, err := r.latestModTime()
+		if err == nil && !modTime.Equal(r.modTime) {
+			err = r.load(modTime)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("cert", r.certFile).Msg("Failed to reload API TLS certificate, keeping the current one")
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if r.cert != nil {
+		log.Info().Str("cert", r.certFile).Msg("Reloaded API TLS certificate")
+	}
+	r.cert, r.modTime, r.checked = &cert, modTime, time.Now()
+	return nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// listen opens the TCP listener on Address and the Unix socket, each nil
+// when not configured. TLS is applied when serving the TCP listener.
+func (s *Server) listen() (tcp, unix net.Listener, err error) {
+	if s.config.TLS != nil {
+		if s.server.TLSConfig, err = newTLSConfig(s.config.TLS); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if s.config.Address != "" {
+		if tcp, err = net.Listen("tcp", s.config.Address); err != nil {
+			return nil, nil, fmt.Errorf("failed to listen on %s: %w", s.config.Address, err)
+		}
+	}
+	if s.config.Socket != "" {
+		if unix, err = listenUnix(s.config.Socket, s.config.SocketMode, s.config.SocketGroup); err != nil {
+			if tcp != nil {
+				tcp.Close()
+			}
+			return nil, nil, err
+		}
+	}
+	return tcp, unix, nil
+}
+
+// serve serves ln until the server is shut down.
+func (s *Server) serve(ln net.Listener, useTLS bool) {
+	log.Info().Str("address", ln.Addr().String()).Bool("tls", useTLS).Msg("Starting API server")
+	var err error
+	if useTLS {
+		err = s.server.ServeTLS(ln, "", "")
+	} else {
+		err = s.server.Serve(ln)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Error().Err(err).Str("address", ln.Addr().String()).Msg("API server error")
+	}
+}
+
+// listenUnix listens on a Unix socket at path with the given mode and group,
+// replacing a stale socket left by a previous run.
+func listenUnix(path string, mode os.FileMode, group string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("API socket path %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale API socket: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set API socket mode: %w", err)
+	}
+	if group != "" {
+		gid, err := lookupGroup(group)
+		if err == nil {
+			err = os.Chown(path, -1, gid)
+		}
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set API socket group: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+// lookupGroup resolves a group name or numeric ID.
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
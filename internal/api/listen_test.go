@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/auth"
+)
+
+// testCert is a certificate and key, signed by parent or self-signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// write writes the certificate and key to dir and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, c.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestTLSListenerClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "test CA"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+	server := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "labelgate"}, IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca)
+	client := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ci"}, EmailAddresses: []string{"ops@example.com"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, ca)
+
+	certFile, keyFile := server.write(t, dir, "server")
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, ca.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(&Config{
+		Address:  "127.0.0.1:0",
+		BasePath: "/api",
+		Storage:  &mockStorage{},
+		TLS:      &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile},
+		Roles:    auth.Roles{auth.ScopeRead: {"ops@example.com"}},
+	})
+	tcp, unix, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unix != nil {
+		t.Fatal("expected no Unix socket")
+	}
+	go s.serve(tcp, true)
+	defer s.server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(path string, certs ...tls.Certificate) *http.Response {
+		t.Helper()
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		resp, err := c.Get("https://" + tcp.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := get("/api/healthz"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected health without a client certificate, got %d", resp.StatusCode)
+	}
+	if resp := get("/api/auth/me"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a client certificate, got %d", resp.StatusCode)
+	}
+
+	resp := get("/api/auth/me", client.tlsCertificate())
+	defer resp.Body.Close()
+	var me struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	json.NewDecoder(resp.Body).Decode(&me)
+	if resp.StatusCode != http.StatusOK || me.Name != "ops@example.com" || len(me.Scopes) != 1 || me.Scopes[0] != auth.ScopeRead {
+		t.Errorf("expected client certificate identity with read scope, got %d %+v", resp.StatusCode, me)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "first"}}, nil)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.interval = 0
+	commonName := func() string {
+		cert, err := r.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _ := x509.ParseCertificate(cert.Certificate[0])
+		return parsed.Subject.CommonName
+	}
+	touch := func() {
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(certFile, later, later)
+	}
+
+	second := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "second"}}, nil)
+	second.write(t, dir, "server")
+	touch()
+	if name := commonName(); name != "second" {
+		t.Errorf("expected the renewed certificate, got %s", name)
+	}
+
+	// A broken certificate keeps the current one
+	os.WriteFile(certFile, []byte("not a certificate"), 0o600)
+	touch()
+	if name := commonName(); name != "second" {
+		t.Errorf("expected the current certificate to be kept, got %s", name)
+	}
+
+	if _, err := newCertReloader(filepath.Join(dir, "missing.crt"), keyFile); err == nil {
+		t.Error("expected an error for a missing certificate")
+	}
+}
+
+func TestUnixSocketListener(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "api.sock")
+
+	// A stale socket from a previous run is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := NewServer(&Config{BasePath: "/api", Storage: &mockStorage{}, Socket: socket, SocketMode: 0o600})
+	tcp, unix, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tcp != nil {
+		t.Fatal("expected no TCP listener without an address")
+	}
+	go s.serve(unix, false)
+	defer s.server.Close()
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("expected mode 0600, got %o", mode)
+	}
+
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := c.Get("http://labelgate/api/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 over the socket, got %d", resp.StatusCode)
+	}
+
+	regular := filepath.Join(dir, "regular")
+	os.WriteFile(regular, nil, 0o600)
+	if _, err := listenUnix(regular, 0o600, ""); err == nil {
+		t.Error("expected an error for a path that is not a socket")
+	}
+}
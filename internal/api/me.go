@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/channinghe/labelgate/internal/auth"
+)
+
+// handleMe returns the authenticated caller and its scopes.
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	principal := auth.PrincipalFrom(r.Context())
+	scopes := principal.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"name":   principal.Name,
+		"scopes": scopes,
+	})
+}
@@ -2,28 +2,47 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/auth"
 )
 
-// tokenAuth returns middleware that validates Bearer token authentication.
-// If token is empty, no authentication is required.
-func tokenAuth(token string, next http.Handler) http.Handler {
+// tokenAuth returns middleware that authenticates requests by Bearer token
+// or identity and stores the caller's principal in the request context. If
+// neither is configured, no authentication is required.
+func tokenAuth(authn *auth.Authenticator, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if token == "" {
-			next.ServeHTTP(w, r)
+		principal, err := authn.AuthenticateRequest(r)
+		if err != nil {
+			if !errors.Is(err, auth.ErrUnauthorized) {
+				log.Error().Err(err).Msg("API authentication failed")
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "authentication failed"})
+				return
+			}
+			resp := map[string]string{"error": "unauthorized"}
+			if authn.LoginURL != "" {
+				resp["login_url"] = authn.LoginURL
+			}
+			writeJSON(w, http.StatusUnauthorized, resp)
 			return
 		}
 
-		auth := r.Header.Get("Authorization")
-		if auth != "Bearer "+token {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// requireScope returns a handler that rejects callers without scope.
+func requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.PrincipalFrom(r.Context()).Allows(scope) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "insufficient scope: requires " + scope})
 			return
 		}
-
-		next.ServeHTTP(w, r)
-	})
+		next(w, r)
+	}
 }
 
 // writeJSON writes a JSON response.
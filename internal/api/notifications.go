@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/channinghe/labelgate/internal/notify"
+)
+
+// auditNotificationTest is the audit action for test notifications.
+const auditNotificationTest = "notification.test"
+
+// handleNotifications lists the configured notification sinks.
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if s.config.Notifier == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false, "sinks": []notify.SinkInfo{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"enabled": true, "sinks": s.config.Notifier.Sinks()})
+}
+
+// handleNotificationTest sends a test notification to one sink ({"sink": "name"})
+// or to every sink, and reports the result of each delivery.
+func (s *Server) handleNotificationTest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Sink string `json:"sink"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	if s.config.Notifier == nil {
+		s.writeUnavailable(w, r, auditNotificationTest, req.Sink, "notifications are not enabled")
+		return
+	}
+
+	results, err := s.config.Notifier.Test(r.Context(), req.Sink)
+	if err == nil {
+		for _, result := range results {
+			if !result.OK {
+				err = errors.New("delivery failed: " + result.Sink + ": " + result.Error)
+				break
+			}
+		}
+	}
+	s.audit(r, auditNotificationTest, req.Sink, err, "")
+
+	var unknown notify.ErrUnknownSink
+	if errors.As(err, &unknown) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if results == nil {
+		results = []notify.TestResult{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
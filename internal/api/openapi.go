@@ -0,0 +1,40 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3 document describing the API. Paths are
+// relative to the server URL, which is set to the configured base path.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// routeMux is a ServeMux that remembers the patterns registered on it, so
+// the OpenAPI document can be checked against the actual routes.
+type routeMux struct {
+	*http.ServeMux
+	patterns *[]string
+}
+
+func (m routeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	*m.patterns = append(*m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
+}
+
+// handleOpenAPI serves the OpenAPI document with the server URL and version
+// of this instance.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	var doc map[string]any
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	doc["servers"] = []map[string]string{{"url": s.config.BasePath}}
+	if info, ok := doc["info"].(map[string]any); ok && s.config.Version != "" {
+		info["version"] = s.config.Version
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
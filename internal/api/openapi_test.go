@@ -0,0 +1,398 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/auth"
+	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/pkg/client"
+)
+
+// specOperation is an operation of the OpenAPI document.
+type specOperation struct {
+	method, path string
+	op           map[string]any
+}
+
+func loadSpec(t *testing.T) (map[string]any, []specOperation) {
+	t.Helper()
+	var doc map[string]any
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("invalid openapi.json: %v", err)
+	}
+	var ops []specOperation
+	for path, item := range doc["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			ops = append(ops, specOperation{strings.ToUpper(method), path, op.(map[string]any)})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].path+ops[i].method < ops[j].path+ops[j].method
+	})
+	return doc, ops
+}
+
+// resolve follows a local $ref.
+func resolve(doc, node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	target := any(doc)
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		target = target.(map[string]any)[part]
+	}
+	return resolve(doc, target.(map[string]any))
+}
+
+// apiRoutes returns the method-qualified API routes of s, relative to the base path.
+func apiRoutes(s *Server) map[string]bool {
+	routes := make(map[string]bool)
+	for _, pattern := range s.routes {
+		method, path, ok := strings.Cut(pattern, " ")
+		if ok && strings.HasPrefix(path, s.config.BasePath+"/") {
+			routes[method+" "+strings.TrimPrefix(path, s.config.BasePath)] = true
+		}
+	}
+	return routes
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	oidc, err := auth.NewOIDC(auth.OIDCConfig{
+		Issuer:        "https://idp.example.com",
+		ClientID:      "labelgate",
+		RedirectURL:   "https://labelgate.example.com/api/auth/callback",
+		SessionSecret: "secret",
+		SessionTTL:    time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create OIDC: %v", err)
+	}
+	s := NewServer(&Config{Address: ":0", BasePath: "/api", Storage: &mockStorage{}, OIDC: oidc})
+	routes := apiRoutes(s)
+
+	doc, ops := loadSpec(t)
+	documented := make(map[string]bool)
+	pathParam := regexp.MustCompile(`\{(\w+)\}`)
+	for _, o := range ops {
+		key := o.method + " " + o.path
+		documented[key] = true
+		if !routes[key] {
+			t.Errorf("%s is documented but not routed", key)
+		}
+
+		declared := make(map[string]bool)
+		params, _ := o.op["parameters"].([]any)
+		for _, p := range params {
+			if p := resolve(doc, p.(map[string]any)); p["in"] == "path" {
+				declared[p["name"].(string)] = true
+			}
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(o.path, -1) {
+			if !declared[m[1]] {
+				t.Errorf("%s: path parameter %s is not declared", key, m[1])
+			}
+		}
+	}
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("%s is routed but not documented", route)
+		}
+	}
+}
+
+func TestOpenAPIScopes(t *testing.T) {
+	// Tokens lacking each scope, and one without read that must still read
+	tokens := map[string][]string{
+		"writer":    {auth.ScopeWrite},
+		"no-write":  {auth.ScopeRead, auth.ScopeAgentsAdmin},
+		"no-agents": {auth.ScopeRead, auth.ScopeWrite},
+		"no-admin":  {auth.ScopeRead, auth.ScopeWrite, auth.ScopeAgentsAdmin},
+	}
+	denied := map[string]string{
+		auth.ScopeWrite:       "no-write",
+		auth.ScopeAgentsAdmin: "no-agents",
+		auth.ScopeAdmin:       "no-admin",
+	}
+	store := &mockStorage{}
+	secrets := make(map[string]string)
+	for name, scopes := range tokens {
+		token, secret, err := auth.NewToken(name, scopes, 0)
+		if err != nil {
+			t.Fatalf("NewToken: %v", err)
+		}
+		store.tokens = append(store.tokens, token)
+		secrets[name] = secret
+	}
+	s := newActionTestServer(store)
+
+	_, ops := loadSpec(t)
+	for _, o := range ops {
+		scope, _ := o.op["x-scope"].(string)
+		if scope == "" {
+			continue
+		}
+		token, wantDenied := denied[scope], true
+		if scope == auth.ScopeRead {
+			token, wantDenied = "writer", false
+		}
+
+		path := regexp.MustCompile(`\{\w+\}`).ReplaceAllString(o.path, "missing")
+		req := httptest.NewRequest(o.method, "/api"+path, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+secrets[token])
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		if denied := w.Code == http.StatusForbidden; denied != wantDenied || w.Code == http.StatusUnauthorized {
+			t.Errorf("%s %s (x-scope %s) with %s token: got %d", o.method, o.path, scope, token, w.Code)
+		}
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	token, secret, _ := auth.NewToken("ops", []string{auth.ScopeAdmin}, 0)
+	store := &mockStorage{tokens: []*storage.APIToken{token}}
+	s := newActionTestServer(store)
+	routes := apiRoutes(s)
+
+	doc, ops := loadSpec(t)
+	for _, o := range ops {
+		key := o.method + " " + o.path
+		if !routes[key] {
+			continue // OIDC login flow
+		}
+
+		var body string
+		if rb, ok := o.op["requestBody"].(map[string]any); ok {
+			example, _ := json.Marshal(rb["content"].(map[string]any)["application/json"].(map[string]any)["example"])
+			body = string(example)
+		}
+		path := regexp.MustCompile(`\{\w+\}`).ReplaceAllString(o.path, "missing")
+		req := httptest.NewRequest(o.method, "/api"+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+
+		resp, ok := o.op["responses"].(map[string]any)[strconv.Itoa(w.Code)].(map[string]any)
+		if !ok {
+			t.Errorf("%s: undocumented status %d: %s", key, w.Code, w.Body.String())
+			continue
+		}
+		content, _ := resolve(doc, resp)["content"].(map[string]any)
+		media, ok := content["application/json"].(map[string]any)
+		if !ok {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(w.Body.Bytes(), &value); err != nil {
+			t.Errorf("%s: status %d: invalid JSON: %v", key, w.Code, err)
+			continue
+		}
+		if err := validateSchema(doc, media["schema"].(map[string]any), value, "body"); err != nil {
+			t.Errorf("%s: status %d: %v", key, w.Code, err)
+		}
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	s := NewServer(&Config{Address: ":0", BasePath: "/labelgate/api", Storage: &mockStorage{}, Token: "secret", Version: "1.2.3"})
+
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/labelgate/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 without a token, got %d", w.Code)
+	}
+	var doc struct {
+		Info    struct{ Version string }
+		Servers []struct{ URL string }
+	}
+	json.NewDecoder(w.Body).Decode(&doc)
+	if doc.Info.Version != "1.2.3" || len(doc.Servers) != 1 || doc.Servers[0].URL != "/labelgate/api" {
+		t.Errorf("expected version and server URL of this instance, got %+v", doc)
+	}
+}
+
+// validateSchema checks value against the subset of JSON Schema used by
+// openapi.json. Objects with properties are closed unless additionalProperties
+// allows more, so undocumented response fields are reported.
+func validateSchema(doc, schema map[string]any, value any, at string) error {
+	schema = resolve(doc, schema)
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, value)
+		}
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := obj[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %s", at, name)
+				}
+			}
+		}
+		for name, v := range obj {
+			if prop, ok := props[name].(map[string]any); ok {
+				if err := validateSchema(doc, prop, v, at+"."+name); err != nil {
+					return err
+				}
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%s: undocumented property %s", at, name)
+				}
+			case map[string]any:
+				if err := validateSchema(doc, extra, v, at+"."+name); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%s: undocumented property %s", at, name)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, value)
+		}
+		for i, item := range items {
+			if err := validateSchema(doc, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, value)
+		}
+		if enum, ok := schema["enum"].([]any); ok {
+			for _, e := range enum {
+				if e == s {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s: %q is not one of %v", at, s, enum)
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected integer, got %v", at, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", at, value)
+		}
+	}
+	return nil
+}
+
+func TestClientMatchesSpec(t *testing.T) {
+	token, secret, _ := auth.NewToken("ops", []string{auth.ScopeAdmin}, 0)
+	s := newActionTestServer(&mockStorage{tokens: []*storage.APIToken{token}})
+
+	_, ops := loadSpec(t)
+	patterns := make(map[string]*regexp.Regexp)
+	for _, o := range ops {
+		patterns[o.method+" "+o.path] = regexp.MustCompile("^/api" + regexp.MustCompile(`\{\w+\}`).ReplaceAllString(o.path, "[^/]+") + "$")
+	}
+
+	called := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matched := false
+		for key, re := range patterns {
+			if strings.HasPrefix(key, r.Method+" ") && re.MatchString(r.URL.Path) {
+				called[key], matched = true, true
+			}
+		}
+		if !matched {
+			t.Errorf("client called undocumented %s %s", r.Method, r.URL.Path)
+		}
+		s.server.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := client.New(srv.URL+"/api", secret)
+	calls := map[string]func() error{
+		"Health":   func() error { return c.Health(ctx) },
+		"Live":     func() error { return c.Live(ctx) },
+		"Ready":    func() error { _, err := c.Ready(ctx); return err },
+		"OpenAPI":  func() error { _, err := c.OpenAPI(ctx); return err },
+		"Me":       func() error { _, err := c.Me(ctx); return err },
+		"Overview": func() error { _, err := c.Overview(ctx); return err },
+		"Version":  func() error { _, err := c.Version(ctx); return err },
+		"ListDNS": func() error {
+			_, err := c.ListDNS(ctx, client.ResourceFilter{Status: client.StatusError, Limit: 10})
+			return err
+		},
+		"ListTunnels": func() error { _, err := c.ListTunnels(ctx, client.ResourceFilter{}); return err },
+		"ListAccess":  func() error { _, err := c.ListAccess(ctx, client.ResourceFilter{}); return err },
+		"ResourceHistory": func() error {
+			_, err := c.ResourceHistory(ctx, "r1", client.EventFilter{Since: time.Now()})
+			return err
+		},
+		"ListEvents":            func() error { _, err := c.ListEvents(ctx, client.EventFilter{Action: "error"}); return err },
+		"ListConflicts":         func() error { _, err := c.ListConflicts(ctx); return err },
+		"ListContainers":        func() error { _, err := c.ListContainers(ctx, client.ContainerFilter{OnlyErrors: true}); return err },
+		"GetContainer":          func() error { _, err := c.GetContainer(ctx, "missing"); return err },
+		"ListAgents":            func() error { _, err := c.ListAgents(ctx); return err },
+		"Snapshot":              func() error { return c.Snapshot(ctx, io.Discard) },
+		"ListAudit":             func() error { _, err := c.ListAudit(ctx, client.AuditFilter{Limit: 5}); return err },
+		"ListNotificationSinks": func() error { _, err := c.ListNotificationSinks(ctx); return err },
+		"TestNotifications":     func() error { _, err := c.TestNotifications(ctx, ""); return err },
+		"Validate": func() error {
+			_, err := c.Validate(ctx, map[string]string{"labelgate.dns.web.hostname": "a.example.com"})
+			return err
+		},
+		"ValidateContainers":  func() error { _, err := c.ValidateContainers(ctx, map[string]map[string]string{"web": {}}); return err },
+		"Reconcile":           func() error { _, err := c.Reconcile(ctx); return err },
+		"RetryResource":       func() error { _, err := c.RetryResource(ctx, "missing"); return err },
+		"ForceDeleteResource": func() error { return c.ForceDeleteResource(ctx, "missing") },
+		"ForgetResource":      func() error { return c.ForgetResource(ctx, "missing") },
+		"RefreshAgent":        func() error { return c.RefreshAgent(ctx, "edge") },
+		"ReconnectAgent":      func() error { return c.ReconnectAgent(ctx, "edge") },
+		"ListTokens":          func() error { _, err := c.ListTokens(ctx); return err },
+		"CreateToken": func() error {
+			_, err := c.CreateToken(ctx, client.CreateTokenRequest{Name: "ci", Scopes: []string{client.ScopeRead}})
+			return err
+		},
+		"RevokeToken":  func() error { return c.RevokeToken(ctx, "ci") },
+		"ReloadConfig": func() error { _, err := c.ReloadConfig(ctx); return err },
+	}
+	for name, call := range calls {
+		var apiErr *client.Error
+		if err := call(); err != nil && !errors.As(err, &apiErr) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// The OIDC login flow is for browsers and the event stream for EventSource clients
+	skip := map[string]bool{"GET /auth/login": true, "GET /auth/callback": true, "POST /auth/logout": true, "GET /events/stream": true}
+	for key := range patterns {
+		if !called[key] && !skip[key] {
+			t.Errorf("%s has no client method", key)
+		}
+	}
+}
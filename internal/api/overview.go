@@ -6,17 +6,25 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/agent"
+	"github.com/channinghe/labelgate/internal/maintenance"
 	"github.com/channinghe/labelgate/internal/storage"
 )
 
 type overviewResponse struct {
-	Resources  resourceOverview  `json:"resources"`
-	Agents     agentOverview     `json:"agents"`
-	Sync       syncOverview      `json:"sync"`
-	Cloudflare cloudflareStatus  `json:"cloudflare"`
-	Version    string            `json:"version"`
-	Uptime     string            `json:"uptime"`
-	StartedAt  time.Time         `json:"started_at"`
+	Resources  resourceOverview `json:"resources"`
+	Agents     agentOverview    `json:"agents"`
+	Sync       syncOverview     `json:"sync"`
+	Cloudflare cloudflareStatus `json:"cloudflare"`
+	Version    string           `json:"version"`
+	Uptime     string           `json:"uptime"`
+	StartedAt  time.Time        `json:"started_at"`
+	Leader     *leaderStatus    `json:"leader,omitempty"`
+
+	// Maintenance holds the last run of each database maintenance task
+	Maintenance map[string]maintenance.Result `json:"maintenance,omitempty"`
 }
 
 type resourceCounts struct {
@@ -44,6 +52,12 @@ type syncOverview struct {
 	Error    string    `json:"error"`
 }
 
+type leaderStatus struct {
+	IsLeader bool   `json:"is_leader"`
+	Identity string `json:"identity"`
+	Leader   string `json:"leader"`
+}
+
 type cloudflareStatus struct {
 	Reachable bool      `json:"reachable"`
 	LastCheck time.Time `json:"last_check"`
@@ -53,9 +67,7 @@ func (s *Server) handleOverview(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Count resources by type and status
-	dnsCounts := s.countResources(ctx, storage.ResourceTypeDNS)
-	tunnelCounts := s.countResources(ctx, storage.ResourceTypeTunnelIngress)
-	accessCounts := s.countResources(ctx, storage.ResourceTypeAccessApp)
+	resources := s.countResources(ctx)
 
 	// Agent counts
 	agentCounts := s.countAgents(ctx)
@@ -86,40 +98,69 @@ func (s *Server) handleOverview(w http.ResponseWriter, r *http.Request) {
 		uptime = formatDuration(time.Since(startedAt))
 	}
 
+	// Leader election
+	var leaderInfo *leaderStatus
+	if s.config.Elector != nil {
+		leaderInfo = &leaderStatus{
+			IsLeader: s.config.Elector.IsLeader(),
+			Identity: s.config.Elector.Identity(),
+			Leader:   s.config.Elector.Leader(),
+		}
+	}
+
+	// Database maintenance, as recorded by whichever instance runs it
+	maintenanceStatus, err := maintenance.LoadStatus(ctx, s.config.Storage)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load maintenance status")
+	}
+
 	writeJSON(w, http.StatusOK, overviewResponse{
-		Resources: resourceOverview{
-			DNS:           dnsCounts,
-			TunnelIngress: tunnelCounts,
-			AccessApp:     accessCounts,
-		},
+		Resources:  resources,
 		Agents:     agentCounts,
 		Sync:       syncStatus,
 		Cloudflare: cfStatus,
 		Version:    s.config.Version,
 		Uptime:     uptime,
 		StartedAt:  startedAt,
+		Leader:     leaderInfo,
+
+		Maintenance: maintenanceStatus,
 	})
 }
 
 // countResources counts resources by type and status.
-func (s *Server) countResources(ctx context.Context, resourceType storage.ResourceType) resourceCounts {
-	all, err := s.config.Storage.ListResources(ctx, storage.ResourceFilter{ResourceType: resourceType})
+func (s *Server) countResources(ctx context.Context) resourceOverview {
+	var overview resourceOverview
+	groups, err := s.config.Storage.CountResourcesByGroup(ctx, storage.ResourceFilter{})
 	if err != nil {
-		return resourceCounts{}
+		log.Warn().Err(err).Msg("Failed to count resources")
+		return overview
 	}
 
-	counts := resourceCounts{Total: len(all)}
-	for _, r := range all {
-		switch r.Status {
+	for _, g := range groups {
+		var counts *resourceCounts
+		switch g.ResourceType {
+		case storage.ResourceTypeDNS:
+			counts = &overview.DNS
+		case storage.ResourceTypeTunnelIngress:
+			counts = &overview.TunnelIngress
+		case storage.ResourceTypeAccessApp:
+			counts = &overview.AccessApp
+		default:
+			continue
+		}
+
+		counts.Total += g.Count
+		switch g.Status {
 		case storage.StatusActive:
-			counts.Active++
+			counts.Active += g.Count
 		case storage.StatusOrphaned:
-			counts.Orphaned++
+			counts.Orphaned += g.Count
 		case storage.StatusError:
-			counts.Error++
+			counts.Error += g.Count
 		}
 	}
-	return counts
+	return overview
 }
 
 // countAgents returns agent counts.
@@ -133,8 +174,8 @@ func (s *Server) countAgents(ctx context.Context) agentOverview {
 	for _, a := range agents {
 		connected := a.Connected
 		// Override with live connection status if agent server is available
-		if s.config.AgentServer != nil {
-			connected = s.config.AgentServer.IsAgentConnected(a.ID)
+		if agentServer := s.liveAgentServer(); agentServer != nil {
+			connected = agentServer.IsAgentConnected(a.ID)
 		}
 		if connected {
 			overview.Connected++
@@ -145,6 +186,15 @@ func (s *Server) countAgents(ctx context.Context) agentOverview {
 	return overview
 }
 
+// liveAgentServer returns the agent server if this instance is running it.
+// Followers don't run the agent server, so stored connection state is used instead.
+func (s *Server) liveAgentServer() *agent.Server {
+	if s.config.Elector != nil && !s.config.Elector.IsLeader() {
+		return nil
+	}
+	return s.config.AgentServer
+}
+
 // formatDuration formats a duration to a human-readable string like "3d 5h 20m".
 func formatDuration(d time.Duration) string {
 	days := int(d.Hours()) / 24
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// auditConfigReload is the audit action for configuration reloads.
+const auditConfigReload = "config.reload"
+
+// handleConfigReload re-reads the configuration and applies the settings
+// that can change at runtime, like SIGHUP. An invalid configuration is
+// rejected and the current one stays in effect.
+func (s *Server) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if s.config.Reloader == nil {
+		s.writeUnavailable(w, r, auditConfigReload, "", "configuration reload is not available")
+		return
+	}
+
+	result, err := s.config.Reloader.Reload(r.Context())
+	if err != nil {
+		s.audit(r, auditConfigReload, "", err, "")
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.audit(r, auditConfigReload, "", nil, "applied="+strings.Join(result.Applied, ","))
+	writeJSON(w, http.StatusOK, result)
+}
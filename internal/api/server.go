@@ -4,13 +4,20 @@ package api
 import (
 	"context"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
-
 	"github.com/channinghe/labelgate/internal/agent"
+	"github.com/channinghe/labelgate/internal/auth"
 	"github.com/channinghe/labelgate/internal/cloudflare"
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/leader"
+	"github.com/channinghe/labelgate/internal/metrics"
+	"github.com/channinghe/labelgate/internal/notify"
+	"github.com/channinghe/labelgate/internal/provider"
 	"github.com/channinghe/labelgate/internal/reconciler"
+	"github.com/channinghe/labelgate/internal/reload"
 	"github.com/channinghe/labelgate/internal/storage"
 )
 
@@ -23,6 +30,19 @@ type Config struct {
 	Reconciler  *reconciler.Reconciler
 	AgentServer *agent.Server
 	CredManager *cloudflare.CredentialManager
+	Elector     *leader.Elector       // nil when leader election is disabled
+	Events      *events.Bus           // nil disables the live event stream
+	Access      *auth.AccessValidator // nil disables Cloudflare Access authentication
+	OIDC        *auth.OIDC            // nil disables the OIDC login flow
+	Roles       auth.Roles            // scopes granted to Access and OIDC identities
+	Notifier    *notify.Notifier      // nil when notifications are disabled
+	LabelPrefix string                // label prefix for /validate (default: labelgate)
+	Docker      provider.Provider     // nil skips the Docker readiness check
+	TLS         *TLSConfig            // nil serves plain HTTP on Address
+	Socket      string                // Unix socket served in addition to Address (empty disables)
+	SocketMode  os.FileMode           // file mode of Socket
+	SocketGroup string                // group name or ID owning Socket (empty keeps the default)
+	Reloader    *reload.Reloader      // nil disables configuration reload
 	Version     string
 }
 
@@ -30,19 +50,62 @@ type Config struct {
 type Server struct {
 	config *Config
 	server *http.Server
+
+	// routes are the method-qualified route patterns (see openapi.go)
+	routes []string
+
+	// storageProbe caches the last storage write probe (see checkStorage)
+	storageProbe struct {
+		mu  sync.Mutex
+		at  time.Time
+		err error
+	}
 }
 
 // NewServer creates a new API server.
 func NewServer(cfg *Config) *Server {
-	mux := http.NewServeMux()
 	s := &Server{config: cfg}
+	mux := routeMux{ServeMux: http.NewServeMux(), patterns: &s.routes}
 
-	// Health endpoint is registered outside auth middleware so Docker
-	// health checks work even when api.token is configured.
+	// Health endpoints and API description are registered outside auth
+	// middleware so Docker health checks work even when api.token is configured.
 	mux.HandleFunc("GET "+cfg.BasePath+"/health", s.handleHealth)
+	mux.HandleFunc("GET "+cfg.BasePath+"/healthz", s.handleHealthz)
+	mux.HandleFunc("GET "+cfg.BasePath+"/readyz", s.handleReadyz)
+	mux.HandleFunc("GET "+cfg.BasePath+"/openapi.json", s.handleOpenAPI)
+
+	// Apply auth middleware to all other API routes; routes check scopes
+	authn := auth.NewAuthenticator(cfg.Token, cfg.Storage)
+	var identities []auth.IdentitySource
+	if cfg.TLS != nil && cfg.TLS.ClientCAFile != "" {
+		identities = append(identities, auth.ClientCertificates{})
+	}
+	if cfg.Access != nil {
+		identities = append(identities, cfg.Access)
+	}
+	// Browser sessions and Access headers are sent automatically, so reject
+	// cross-origin writes; bearer-token clients send no Origin and pass
+	csrf := http.NewCrossOriginProtection()
+	csrf.SetDenyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-origin request rejected"})
+	}))
+
+	if cfg.OIDC != nil {
+		identities = append(identities, cfg.OIDC)
+		authn.LoginURL = cfg.BasePath + "/auth/login"
+
+		// The login flow itself must be reachable without a session
+		mux.HandleFunc("GET "+cfg.BasePath+"/auth/login", cfg.OIDC.HandleLogin)
+		mux.HandleFunc("GET "+cfg.BasePath+"/auth/callback", cfg.OIDC.HandleCallback)
+		mux.HandleFunc("POST "+cfg.BasePath+"/auth/logout", csrf.Handler(http.HandlerFunc(cfg.OIDC.HandleLogout)).ServeHTTP)
+	}
+	if len(identities) > 0 {
+		authn.SetIdentitySources(cfg.Roles, identities...)
+	}
+	apiHandler := csrf.Handler(tokenAuth(authn, s.apiMux(cfg.BasePath)))
 
-	// Apply auth middleware to all other API routes
-	apiHandler := tokenAuth(cfg.Token, s.apiMux(cfg.BasePath))
+	// Prometheus metrics, protected by API tokens when any are configured
+	mux.Handle("GET /metrics", tokenAuth(authn, requireScope(auth.ScopeRead, metrics.Handler().ServeHTTP)))
 
 	// Dashboard static files (no auth required for SPA assets)
 	dashboardHandler := newDashboardHandler()
@@ -68,14 +131,51 @@ func NewServer(cfg *Config) *Server {
 
 // apiMux creates the API route multiplexer.
 func (s *Server) apiMux(basePath string) http.Handler {
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("GET "+basePath+"/overview", s.handleOverview)
-	mux.HandleFunc("GET "+basePath+"/resources/dns", s.handleDNS)
-	mux.HandleFunc("GET "+basePath+"/resources/tunnels", s.handleTunnels)
-	mux.HandleFunc("GET "+basePath+"/resources/access", s.handleAccess)
-	mux.HandleFunc("GET "+basePath+"/agents", s.handleAgents)
-	mux.HandleFunc("GET "+basePath+"/version", s.handleVersion)
+	mux := routeMux{ServeMux: http.NewServeMux(), patterns: &s.routes}
+
+	read := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(auth.ScopeRead, h) }
+	write := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(auth.ScopeWrite, h) }
+	agentsAdmin := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(auth.ScopeAgentsAdmin, h) }
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(auth.ScopeAdmin, h) }
+
+	// Caller identity, available to any authenticated caller
+	mux.HandleFunc("GET "+basePath+"/auth/me", s.handleMe)
+
+	mux.HandleFunc("GET "+basePath+"/overview", read(s.handleOverview))
+	mux.HandleFunc("GET "+basePath+"/resources/dns", read(s.handleDNS))
+	mux.HandleFunc("GET "+basePath+"/resources/tunnels", read(s.handleTunnels))
+	mux.HandleFunc("GET "+basePath+"/resources/access", read(s.handleAccess))
+	mux.HandleFunc("GET "+basePath+"/resources/{id}/history", read(s.handleResourceHistory))
+	mux.HandleFunc("GET "+basePath+"/events", read(s.handleEvents))
+	mux.HandleFunc("GET "+basePath+"/events/stream", read(s.handleEventStream))
+	mux.HandleFunc("GET "+basePath+"/conflicts", read(s.handleConflicts))
+	mux.HandleFunc("GET "+basePath+"/containers", read(s.handleContainers))
+	mux.HandleFunc("GET "+basePath+"/containers/{id}", read(s.handleContainer))
+	mux.HandleFunc("GET "+basePath+"/agents", read(s.handleAgents))
+	mux.HandleFunc("GET "+basePath+"/version", read(s.handleVersion))
+	mux.HandleFunc("GET "+basePath+"/audit", read(s.handleAudit))
+	mux.HandleFunc("GET "+basePath+"/notifications", read(s.handleNotifications))
+	mux.HandleFunc("POST "+basePath+"/validate", read(s.handleValidate))
+
+	// Write actions (recorded in the audit trail)
+	mux.HandleFunc("POST "+basePath+"/reconcile", write(s.handleReconcile))
+	mux.HandleFunc("POST "+basePath+"/resources/{id}/retry", write(s.handleRetryResource))
+	mux.HandleFunc("POST "+basePath+"/resources/{id}/force-delete", write(s.handleForceDeleteResource))
+	mux.HandleFunc("POST "+basePath+"/resources/{id}/forget", write(s.handleForgetResource))
+	mux.HandleFunc("POST "+basePath+"/agents/{id}/refresh", agentsAdmin(s.handleAgentRefresh))
+	mux.HandleFunc("POST "+basePath+"/agents/{id}/reconnect", agentsAdmin(s.handleAgentReconnect))
+	mux.HandleFunc("POST "+basePath+"/notifications/test", write(s.handleNotificationTest))
+
+	// API token management
+	mux.HandleFunc("GET "+basePath+"/tokens", admin(s.handleListTokens))
+	mux.HandleFunc("POST "+basePath+"/tokens", admin(s.handleCreateToken))
+	mux.HandleFunc("DELETE "+basePath+"/tokens/{name}", admin(s.handleRevokeToken))
+
+	// Database snapshot, which includes token hashes and the audit trail
+	mux.HandleFunc("GET "+basePath+"/db/snapshot", admin(s.handleSnapshot))
+
+	// Configuration reload (like SIGHUP)
+	mux.HandleFunc("POST "+basePath+"/config/reload", admin(s.handleConfigReload))
 	// Note: /health is registered outside apiMux (no auth required)
 
 	return mux
@@ -83,12 +183,16 @@ func (s *Server) apiMux(basePath string) http.Handler {
 
 // Start starts the API server and blocks until the context is cancelled.
 func (s *Server) Start(ctx context.Context) error {
-	go func() {
-		log.Info().Str("address", s.config.Address).Msg("Starting API server")
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error().Err(err).Msg("API server error")
-		}
-	}()
+	tcp, unix, err := s.listen()
+	if err != nil {
+		return err
+	}
+	if tcp != nil {
+		go s.serve(tcp, s.server.TLSConfig != nil)
+	}
+	if unix != nil {
+		go s.serve(unix, false)
+	}
 
 	<-ctx.Done()
 
@@ -1,25 +1,54 @@
 package api
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/channinghe/labelgate/internal/auth"
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/notify"
+	"github.com/channinghe/labelgate/internal/reconciler"
+	"github.com/channinghe/labelgate/internal/reload"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
+	"github.com/channinghe/labelgate/internal/types"
+	"github.com/channinghe/labelgate/pkg/labels"
 )
 
 // mockStorage implements storage.Storage for testing.
 type mockStorage struct {
 	resources []*storage.ManagedResource
 	agents    []*storage.Agent
+	conflicts []*storage.Conflict
+	events    []*storage.ResourceEvent
+	audit     []*storage.AuditEntry
+	tokens    []*storage.APIToken
 }
 
 func (m *mockStorage) Initialize(ctx context.Context) error                    { return nil }
 func (m *mockStorage) Close() error                                            { return nil }
+func (m *mockStorage) SchemaVersion(ctx context.Context) (int, error) {
+	return storage.LatestSchemaVersion(), nil
+}
 func (m *mockStorage) GetResource(ctx context.Context, id string) (*storage.ManagedResource, error) {
+	for _, r := range m.resources {
+		if r.ID == id {
+			return r, nil
+		}
+	}
 	return nil, storage.ErrNotFound
 }
 func (m *mockStorage) GetResourceByHostname(ctx context.Context, hostname string, resourceType storage.ResourceType) (*storage.ManagedResource, error) {
@@ -42,8 +71,33 @@ func (m *mockStorage) ListResources(ctx context.Context, filter storage.Resource
 		}
 		result = append(result, r)
 	}
+	if filter.Offset > 0 {
+		result = result[min(filter.Offset, len(result)):]
+	}
+	if filter.Limit > 0 && filter.Limit < len(result) {
+		result = result[:filter.Limit]
+	}
 	return result, nil
 }
+func (m *mockStorage) CountResources(ctx context.Context, filter storage.ResourceFilter) (int, error) {
+	filter.Limit, filter.Offset = 0, 0
+	result, _ := m.ListResources(ctx, filter)
+	return len(result), nil
+}
+func (m *mockStorage) CountResourcesByGroup(ctx context.Context, filter storage.ResourceFilter) ([]storage.ResourceCount, error) {
+	filter.Limit, filter.Offset = 0, 0
+	result, _ := m.ListResources(ctx, filter)
+	counts := make(map[storage.ResourceCount]int)
+	for _, r := range result {
+		counts[storage.ResourceCount{ResourceType: r.ResourceType, Status: r.Status, AgentID: r.AgentID}]++
+	}
+	var groups []storage.ResourceCount
+	for g, n := range counts {
+		g.Count = n
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
 func (m *mockStorage) SaveResource(ctx context.Context, resource *storage.ManagedResource) error {
 	return nil
 }
@@ -53,7 +107,15 @@ func (m *mockStorage) UpdateResourceStatus(ctx context.Context, id string, statu
 func (m *mockStorage) UpdateResourceError(ctx context.Context, id string, status storage.ResourceStatus, lastError string) error {
 	return nil
 }
-func (m *mockStorage) DeleteResource(ctx context.Context, id string) error { return nil }
+func (m *mockStorage) DeleteResource(ctx context.Context, id string) error {
+	for i, r := range m.resources {
+		if r.ID == id {
+			m.resources = append(m.resources[:i], m.resources[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
 
 func (m *mockStorage) GetAgent(ctx context.Context, id string) (*storage.Agent, error) {
 	return nil, storage.ErrNotFound
@@ -67,6 +129,90 @@ func (m *mockStorage) UpdateAgentStatus(ctx context.Context, id string, connecte
 }
 func (m *mockStorage) DeleteAgent(ctx context.Context, id string) error { return nil }
 
+func (m *mockStorage) RebindResource(ctx context.Context, id, fromContainerID string, owner storage.ResourceOwner) error {
+	return nil
+}
+func (m *mockStorage) ListResourceEvents(ctx context.Context, filter storage.EventFilter) ([]*storage.ResourceEvent, error) {
+	var result []*storage.ResourceEvent
+	for _, e := range m.events {
+		if filter.ResourceID != "" && e.ResourceID != filter.ResourceID {
+			continue
+		}
+		if filter.Hostname != "" && e.Hostname != filter.Hostname {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+func (m *mockStorage) CountResourceEvents(ctx context.Context, filter storage.EventFilter) (int, error) {
+	result, _ := m.ListResourceEvents(ctx, filter)
+	return len(result), nil
+}
+func (m *mockStorage) CleanupResourceEvents(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+func (m *mockStorage) ReplaceConflicts(ctx context.Context, conflicts []*storage.Conflict) error {
+	m.conflicts = conflicts
+	return nil
+}
+func (m *mockStorage) ListConflicts(ctx context.Context) ([]*storage.Conflict, error) {
+	return m.conflicts, nil
+}
+
+func (m *mockStorage) RecordAudit(ctx context.Context, entry *storage.AuditEntry) error {
+	entry.ID = int64(len(m.audit) + 1)
+	m.audit = append(m.audit, entry)
+	return nil
+}
+func (m *mockStorage) ListAuditEntries(ctx context.Context, filter storage.AuditFilter) ([]*storage.AuditEntry, error) {
+	var result []*storage.AuditEntry
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		if filter.Action != "" && m.audit[i].Action != filter.Action {
+			continue
+		}
+		if filter.Target != "" && m.audit[i].Target != filter.Target {
+			continue
+		}
+		result = append(result, m.audit[i])
+	}
+	return result, nil
+}
+func (m *mockStorage) CountAuditEntries(ctx context.Context, filter storage.AuditFilter) (int, error) {
+	result, _ := m.ListAuditEntries(ctx, filter)
+	return len(result), nil
+}
+
+func (m *mockStorage) CreateAPIToken(ctx context.Context, token *storage.APIToken) error {
+	for _, t := range m.tokens {
+		if t.Name == token.Name {
+			return storage.ErrConflict
+		}
+	}
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+func (m *mockStorage) ListAPITokens(ctx context.Context) ([]*storage.APIToken, error) {
+	return m.tokens, nil
+}
+func (m *mockStorage) DeleteAPIToken(ctx context.Context, name string) error {
+	for i, t := range m.tokens {
+		if t.Name == name {
+			m.tokens = append(m.tokens[:i], m.tokens[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+func (m *mockStorage) TouchAPIToken(ctx context.Context, name string, at time.Time) error {
+	for _, t := range m.tokens {
+		if t.Name == name {
+			t.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
 func (m *mockStorage) GetSyncState(ctx context.Context, key string) (string, error) {
 	return "", nil
 }
@@ -81,7 +227,9 @@ func (m *mockStorage) ListExpiredOrphans(ctx context.Context, olderThan time.Tim
 func (m *mockStorage) ListOrphanedForCleanup(ctx context.Context, olderThan time.Time) ([]*storage.ManagedResource, error) {
 	return nil, nil
 }
-func (m *mockStorage) Vacuum(ctx context.Context) error { return nil }
+func (m *mockStorage) Vacuum(ctx context.Context) error         { return nil }
+func (m *mockStorage) Checkpoint(ctx context.Context) error     { return nil }
+func (m *mockStorage) IntegrityCheck(ctx context.Context) error { return nil }
 
 func newTestServer(store storage.Storage) *Server {
 	return NewServer(&Config{
@@ -111,6 +259,85 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestHealthzEndpoint(t *testing.T) {
+	s := NewServer(&Config{BasePath: "/api", Token: "secret", Storage: &mockStorage{}})
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 without a token, got %d", w.Code)
+	}
+}
+
+func TestReadyzEndpoint(t *testing.T) {
+	readyz := func(s *Server) (int, map[string]componentCheck) {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/readyz", nil))
+		var body struct {
+			Checks map[string]componentCheck `json:"checks"`
+		}
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Checks
+	}
+
+	code, checks := readyz(newTestServer(&mockStorage{}))
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %+v", code, checks)
+	}
+	if checks["storage"].Status != checkOK {
+		t.Errorf("expected storage ok, got %+v", checks["storage"])
+	}
+	for _, name := range []string{"docker", "reconcile", "cloudflare", "agent_server"} {
+		if checks[name].Status != checkSkipped {
+			t.Errorf("expected %s skipped, got %+v", name, checks[name])
+		}
+	}
+
+	s := newActionTestServer(&mockStorage{})
+	code, checks = readyz(s)
+	if code != http.StatusServiceUnavailable || checks["reconcile"].Status != checkFail {
+		t.Fatalf("expected 503 before the first reconcile, got %d: %+v", code, checks["reconcile"])
+	}
+
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/reconcile", nil))
+	code, checks = readyz(s)
+	if code != http.StatusOK || checks["reconcile"].Status != checkOK {
+		t.Fatalf("expected 200 after a reconcile, got %d: %+v", code, checks["reconcile"])
+	}
+}
+
+// probeCountingStorage counts sync state writes.
+type probeCountingStorage struct {
+	*mockStorage
+	writes int
+}
+
+func (m *probeCountingStorage) SetSyncState(ctx context.Context, key, value string) error {
+	m.writes++
+	return nil
+}
+
+func TestReadyzCachesStorageProbe(t *testing.T) {
+	store := &probeCountingStorage{mockStorage: &mockStorage{}}
+	s := newTestServer(store)
+	for range 5 {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/readyz", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+	}
+	if store.writes != 1 {
+		t.Errorf("expected one storage write for repeated probes, got %d", store.writes)
+	}
+
+	// The probe runs again once the cached result is stale
+	s.storageProbe.at = time.Now().Add(-storageProbeInterval)
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/readyz", nil))
+	if store.writes != 2 {
+		t.Errorf("expected a new storage write after %s, got %d writes", storageProbeInterval, store.writes)
+	}
+}
+
 func TestVersionEndpoint(t *testing.T) {
 	s := newTestServer(&mockStorage{})
 	req := httptest.NewRequest("GET", "/api/version", nil)
@@ -183,7 +410,7 @@ func TestTokenAuthMiddleware(t *testing.T) {
 
 	// Test with no token configured (should pass through)
 	t.Run("no token required", func(t *testing.T) {
-		h := tokenAuth("", handler)
+		h := tokenAuth(auth.NewAuthenticator("", nil), handler)
 		req := httptest.NewRequest("GET", "/", nil)
 		w := httptest.NewRecorder()
 		h.ServeHTTP(w, req)
@@ -194,7 +421,7 @@ func TestTokenAuthMiddleware(t *testing.T) {
 
 	// Test with valid token
 	t.Run("valid token", func(t *testing.T) {
-		h := tokenAuth("secret", handler)
+		h := tokenAuth(auth.NewAuthenticator("secret", nil), handler)
 		req := httptest.NewRequest("GET", "/", nil)
 		req.Header.Set("Authorization", "Bearer secret")
 		w := httptest.NewRecorder()
@@ -206,7 +433,7 @@ func TestTokenAuthMiddleware(t *testing.T) {
 
 	// Test with invalid token
 	t.Run("invalid token", func(t *testing.T) {
-		h := tokenAuth("secret", handler)
+		h := tokenAuth(auth.NewAuthenticator("secret", nil), handler)
 		req := httptest.NewRequest("GET", "/", nil)
 		req.Header.Set("Authorization", "Bearer wrong")
 		w := httptest.NewRecorder()
@@ -218,7 +445,7 @@ func TestTokenAuthMiddleware(t *testing.T) {
 
 	// Test with missing token
 	t.Run("missing token", func(t *testing.T) {
-		h := tokenAuth("secret", handler)
+		h := tokenAuth(auth.NewAuthenticator("secret", nil), handler)
 		req := httptest.NewRequest("GET", "/", nil)
 		w := httptest.NewRecorder()
 		h.ServeHTTP(w, req)
@@ -228,6 +455,33 @@ func TestTokenAuthMiddleware(t *testing.T) {
 	})
 }
 
+func TestDNSEndpointPaginatedTotal(t *testing.T) {
+	store := &mockStorage{
+		resources: []*storage.ManagedResource{
+			{ID: "1", ResourceType: storage.ResourceTypeDNS, Hostname: "a.example.com", Status: storage.StatusActive},
+			{ID: "2", ResourceType: storage.ResourceTypeDNS, Hostname: "b.example.com", Status: storage.StatusActive},
+			{ID: "3", ResourceType: storage.ResourceTypeDNS, Hostname: "c.example.com", Status: storage.StatusActive},
+		},
+	}
+	s := newTestServer(store)
+	req := httptest.NewRequest("GET", "/api/resources/dns?limit=2&offset=2", nil)
+	w := httptest.NewRecorder()
+
+	s.handleDNS(w, req)
+
+	var body struct {
+		Resources []*storage.ManagedResource `json:"resources"`
+		Total     int                        `json:"total"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if len(body.Resources) != 1 {
+		t.Fatalf("expected 1 resource on the last page, got %d", len(body.Resources))
+	}
+	if body.Total != 3 {
+		t.Fatalf("expected total 3 across pages, got %d", body.Total)
+	}
+}
+
 func TestAgentsEndpoint(t *testing.T) {
 	now := time.Now()
 	store := &mockStorage{
@@ -292,3 +546,654 @@ func TestOverviewEndpoint(t *testing.T) {
 		t.Fatalf("expected version 0.1.0-test, got %s", body.Version)
 	}
 }
+
+func TestConflictsEndpoint(t *testing.T) {
+	store := &mockStorage{
+		conflicts: []*storage.Conflict{
+			{ID: "1", Hostname: "app.example.com", ResourceType: storage.ResourceTypeDNS, ContainerName: "app-new", WinnerContainerName: "app-old"},
+		},
+	}
+	s := newTestServer(store)
+	req := httptest.NewRequest("GET", "/api/conflicts", nil)
+	w := httptest.NewRecorder()
+
+	s.handleConflicts(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var body map[string]any
+	json.NewDecoder(w.Body).Decode(&body)
+	if int(body["total"].(float64)) != 1 {
+		t.Fatalf("expected 1 conflict, got %v", body["total"])
+	}
+}
+
+func TestResourceHistoryEndpoint(t *testing.T) {
+	store := &mockStorage{
+		events: []*storage.ResourceEvent{
+			{ID: 2, ResourceID: "r1", Hostname: "a.example.com", Action: storage.ActionUpdate},
+			{ID: 1, ResourceID: "r1", Hostname: "a.example.com", Action: storage.ActionCreate},
+			{ID: 3, ResourceID: "r2", Hostname: "b.example.com", Action: storage.ActionCreate},
+		},
+	}
+	s := newTestServer(store)
+	req := httptest.NewRequest("GET", "/api/resources/r1/history", nil)
+	w := httptest.NewRecorder()
+
+	s.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var body map[string]any
+	json.NewDecoder(w.Body).Decode(&body)
+	if int(body["total"].(float64)) != 2 {
+		t.Fatalf("expected 2 events for r1, got %v", body["total"])
+	}
+}
+
+func TestEventsEndpointInvalidSince(t *testing.T) {
+	s := newTestServer(&mockStorage{})
+	req := httptest.NewRequest("GET", "/api/events?since=yesterday", nil)
+	w := httptest.NewRecorder()
+
+	s.handleEvents(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestSnapshotEndpoint(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(t.TempDir() + "/labelgate.db")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.Initialize(context.Background()); err != nil {
+		t.Fatalf("failed to initialize storage: %v", err)
+	}
+
+	s := newTestServer(store)
+	req := httptest.NewRequest("GET", "/api/db/snapshot", nil)
+	w := httptest.NewRecorder()
+
+	s.handleSnapshot(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Body.String(), "SQLite format 3") {
+		t.Errorf("expected a SQLite database in the response body")
+	}
+}
+
+func TestSnapshotEndpointUnsupported(t *testing.T) {
+	s := newTestServer(&mockStorage{})
+	req := httptest.NewRequest("GET", "/api/db/snapshot", nil)
+	w := httptest.NewRecorder()
+
+	s.handleSnapshot(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", w.Code)
+	}
+}
+
+func newActionTestServer(store *mockStorage) *Server {
+	s := newTestServer(store)
+	s.config.Reconciler = reconciler.NewReconciler(&reconciler.Config{Storage: store})
+	return s
+}
+
+func TestReconcileEndpointRecordsAudit(t *testing.T) {
+	store := &mockStorage{}
+	s := newActionTestServer(store)
+	req := httptest.NewRequest("POST", "/api/reconcile", nil)
+	w := httptest.NewRecorder()
+
+	s.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(store.audit) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(store.audit))
+	}
+	if e := store.audit[0]; e.Action != auditReconcile || e.Status != storage.AuditSuccess || e.Actor != "192.0.2.1" {
+		t.Errorf("unexpected audit entry: %+v", e)
+	}
+}
+
+func TestCrossOriginWriteRejected(t *testing.T) {
+	store := &mockStorage{}
+	s := newActionTestServer(store)
+
+	req := httptest.NewRequest("POST", "/api/reconcile", nil)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	req.Header.Set("Origin", "https://evil.example")
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a cross-origin write, got %d", w.Code)
+	}
+	if len(store.audit) != 0 {
+		t.Errorf("expected the rejected request not to run, got %d audit entries", len(store.audit))
+	}
+
+	// Same-origin browser requests and clients without an Origin still work
+	req = httptest.NewRequest("POST", "/api/reconcile", nil)
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a same-origin write, got %d", w.Code)
+	}
+
+	// Reads are not affected
+	req = httptest.NewRequest("GET", "/api/version", nil)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a cross-origin read, got %d", w.Code)
+	}
+}
+
+func TestForgetResourceEndpoint(t *testing.T) {
+	store := &mockStorage{
+		resources: []*storage.ManagedResource{
+			{ID: "active", Hostname: "a.example.com", Status: storage.StatusActive},
+			{ID: "orphan", Hostname: "b.example.com", Status: storage.StatusOrphaned},
+		},
+	}
+	s := newActionTestServer(store)
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"active", http.StatusConflict},
+		{"orphan", http.StatusOK},
+		{"missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/resources/"+tt.id+"/forget", nil)
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("forget %s: expected %d, got %d", tt.id, tt.want, w.Code)
+		}
+	}
+
+	if len(store.resources) != 1 || store.resources[0].ID != "active" {
+		t.Errorf("expected only the active resource to remain, got %d resources", len(store.resources))
+	}
+	if len(store.audit) != 3 {
+		t.Fatalf("expected every attempt to be audited, got %d entries", len(store.audit))
+	}
+	if store.audit[0].Status != storage.AuditError || store.audit[1].Status != storage.AuditSuccess {
+		t.Errorf("unexpected audit statuses: %s, %s", store.audit[0].Status, store.audit[1].Status)
+	}
+}
+
+func TestRetryResourceRequiresErrorStatus(t *testing.T) {
+	store := &mockStorage{
+		resources: []*storage.ManagedResource{
+			{ID: "r1", Hostname: "a.example.com", Status: storage.StatusActive},
+		},
+	}
+	s := newActionTestServer(store)
+	req := httptest.NewRequest("POST", "/api/resources/r1/retry", nil)
+	w := httptest.NewRecorder()
+
+	s.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+}
+
+func TestActionsUnavailableWithoutComponents(t *testing.T) {
+	store := &mockStorage{}
+	s := newTestServer(store)
+
+	for _, path := range []string{"/api/reconcile", "/api/agents/a1/refresh", "/api/agents/a1/reconnect", "/api/config/reload"} {
+		req := httptest.NewRequest("POST", path, nil)
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503, got %d", path, w.Code)
+		}
+	}
+	if len(store.audit) != 4 {
+		t.Errorf("expected rejected actions to be audited, got %d entries", len(store.audit))
+	}
+}
+
+func TestConfigReloadEndpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labelgate.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cloudflare:\n  api_token: token\nsync:\n  workers: 4\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &mockStorage{}
+	s := newTestServer(store)
+	s.config.Reloader = reload.New(path, cfg)
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/config/reload", nil))
+		return w
+	}
+
+	write("cloudflare:\n  api_token: token\nsync:\n  workers: 8\n")
+	w := post()
+	var result reload.Result
+	json.NewDecoder(w.Body).Decode(&result)
+	if w.Code != http.StatusOK || len(result.RestartRequired) != 1 || result.RestartRequired[0] != "sync.workers" {
+		t.Errorf("expected sync.workers to require a restart, got %d %+v", w.Code, result)
+	}
+
+	// An invalid configuration is rejected
+	write("cloudflare:\n  api_token: token\n  rate_limit: -1\n")
+	if w := post(); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid configuration, got %d", w.Code)
+	}
+
+	if len(store.audit) != 2 || store.audit[0].Status != storage.AuditSuccess || store.audit[1].Status != storage.AuditError {
+		t.Errorf("expected both reloads to be audited, got %+v", store.audit)
+	}
+}
+
+func TestAuditEndpoint(t *testing.T) {
+	store := &mockStorage{
+		audit: []*storage.AuditEntry{
+			{ID: 1, Action: auditReconcile, Status: storage.AuditSuccess},
+			{ID: 2, Action: auditResourceForget, Target: "r1", Status: storage.AuditSuccess},
+		},
+	}
+	s := newTestServer(store)
+	req := httptest.NewRequest("GET", "/api/audit?action=resource.forget", nil)
+	w := httptest.NewRecorder()
+
+	s.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var body map[string]any
+	json.NewDecoder(w.Body).Decode(&body)
+	if int(body["total"].(float64)) != 1 {
+		t.Fatalf("expected 1 audit entry, got %v", body["total"])
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	bus := events.NewBus()
+	s := newTestServer(&mockStorage{})
+	s.config.Events = bus
+	ts := httptest.NewServer(s.server.Handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/api/events/stream?type=resource&hostname=a.example.com", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	bus.Publish(events.Event{Type: events.TypeAgentConnected})
+	bus.Publish(events.Event{Type: "resource.create", Hostnames: []string{"b.example.com"}})
+	bus.Publish(events.Event{Type: "resource.create", Hostnames: []string{"a.example.com"}})
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 || lines[0] != "id: 3" || lines[1] != "event: resource.create" || !strings.HasPrefix(lines[2], "data: ") {
+		t.Fatalf("unexpected event: %q", lines)
+	}
+}
+
+func TestEventStreamUnavailable(t *testing.T) {
+	s := newTestServer(&mockStorage{})
+	req := httptest.NewRequest("GET", "/api/events/stream", nil)
+	w := httptest.NewRecorder()
+
+	s.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	s := NewServer(&Config{
+		Address:  ":0",
+		BasePath: "/api",
+		Token:    "secret",
+		Storage:  &mockStorage{},
+	})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "labelgate_last_successful_sync_timestamp_seconds") {
+		t.Errorf("expected labelgate metrics in response")
+	}
+}
+
+func TestReconcileEndpointTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(&config.TracingConfig{ServiceName: "labelgate", SampleRatio: 1}, sdktrace.NewSimpleSpanProcessor(exporter), "test")
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	s := newActionTestServer(&mockStorage{})
+	req := httptest.NewRequest("POST", "/api/reconcile", nil)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+
+	names := make(map[string]bool)
+	for _, span := range exporter.GetSpans() {
+		names[span.Name] = true
+	}
+	for _, want := range []string{"reconcile", "reconcile.orphan_cleanup"} {
+		if !names[want] {
+			t.Errorf("expected span %q, got %v", want, names)
+		}
+	}
+}
+
+func TestTokenScopes(t *testing.T) {
+	readToken, readSecret, _ := auth.NewToken("ci", []string{auth.ScopeRead}, 0)
+	adminToken, adminSecret, _ := auth.NewToken("ops", []string{auth.ScopeAdmin}, 0)
+	store := &mockStorage{tokens: []*storage.APIToken{readToken, adminToken}}
+	s := newTestServer(store)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "/api/overview", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token once tokens exist, got %d", w.Code)
+	}
+	if w := do("GET", "/api/overview", readSecret, ""); w.Code != http.StatusOK {
+		t.Errorf("expected read token to read, got %d", w.Code)
+	}
+	if w := do("POST", "/api/reconcile", readSecret, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected read token to be forbidden from writes, got %d", w.Code)
+	}
+	if w := do("GET", "/api/tokens", readSecret, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected read token to be forbidden from token management, got %d", w.Code)
+	}
+	if w := do("GET", "/api/db/snapshot", readSecret, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected read token to be forbidden from database snapshots, got %d", w.Code)
+	}
+
+	w := do("POST", "/api/tokens", adminSecret, `{"name":"deploy","scopes":["write"],"expires_in":"24h"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created map[string]any
+	json.NewDecoder(w.Body).Decode(&created)
+	secret, _ := created["token"].(string)
+	if secret == "" || created["expires_at"] == nil {
+		t.Fatalf("expected plaintext token and expiry in response, got %v", created)
+	}
+	if w := do("POST", "/api/tokens", adminSecret, `{"name":"deploy","scopes":["read"]}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate name, got %d", w.Code)
+	}
+
+	w = do("GET", "/api/tokens", secret, "")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected write token to be forbidden from token management, got %d", w.Code)
+	}
+	w = do("GET", "/api/tokens", adminSecret, "")
+	if strings.Contains(w.Body.String(), "sha256:") {
+		t.Errorf("token list must not expose hashes: %s", w.Body.String())
+	}
+
+	if w := do("DELETE", "/api/tokens/deploy", adminSecret, ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200 revoking token, got %d", w.Code)
+	}
+	if w := do("GET", "/api/overview", secret, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected revoked token to be rejected, got %d", w.Code)
+	}
+
+	if len(store.audit) != 3 || store.audit[0].Actor != "token:ops" {
+		t.Errorf("expected token changes audited under the token name, got %+v", store.audit)
+	}
+}
+
+func TestUnauthorizedIncludesLoginURL(t *testing.T) {
+	oidc, err := auth.NewOIDC(auth.OIDCConfig{
+		Issuer:        "https://idp.example.com",
+		ClientID:      "labelgate",
+		RedirectURL:   "https://labelgate.example.com/api/auth/callback",
+		SessionSecret: "secret",
+		SessionTTL:    time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create OIDC: %v", err)
+	}
+	s := NewServer(&Config{Address: ":0", BasePath: "/api", Storage: &mockStorage{}, OIDC: oidc})
+
+	req := httptest.NewRequest("GET", "/api/auth/me", nil)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", w.Code)
+	}
+	var body map[string]string
+	json.NewDecoder(w.Body).Decode(&body)
+	if body["login_url"] != "/api/auth/login" {
+		t.Errorf("expected login_url in response, got %v", body)
+	}
+}
+
+func TestMeEndpoint(t *testing.T) {
+	token, secret, _ := auth.NewToken("ci", []string{auth.ScopeRead}, 0)
+	s := newTestServer(&mockStorage{tokens: []*storage.APIToken{token}})
+
+	req := httptest.NewRequest("GET", "/api/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var body struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if body.Name != "token:ci" || len(body.Scopes) != 1 || body.Scopes[0] != "read" {
+		t.Errorf("unexpected response %+v", body)
+	}
+}
+
+func TestNotificationTestEndpoint(t *testing.T) {
+	var received int
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer hook.Close()
+
+	notifier, err := notify.New(&config.NotificationsConfig{
+		Enabled:       true,
+		Sinks:         map[string]config.NotificationSinkConfig{"hook": {Type: "webhook", URL: hook.URL}},
+		RetryAttempts: 1,
+		RetryDelay:    time.Second,
+		QueueSize:     1,
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("notify.New: %v", err)
+	}
+
+	store := &mockStorage{}
+	s := NewServer(&Config{BasePath: "/api", Storage: store, Notifier: notifier})
+
+	req := httptest.NewRequest("POST", "/api/notifications/test", strings.NewReader(`{"sink":"hook"}`))
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if received != 1 {
+		t.Fatalf("expected 1 delivery, got %d", received)
+	}
+	if len(store.audit) != 1 || store.audit[0].Action != auditNotificationTest || store.audit[0].Status != storage.AuditSuccess {
+		t.Fatalf("expected successful audit entry, got %+v", store.audit)
+	}
+
+	req = httptest.NewRequest("POST", "/api/notifications/test", strings.NewReader(`{"sink":"missing"}`))
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown sink, got %d", w.Code)
+	}
+
+	// Without a notifier the endpoint is unavailable
+	w = httptest.NewRecorder()
+	newTestServer(&mockStorage{}).server.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/notifications/test", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without notifier, got %d", w.Code)
+	}
+}
+
+func TestContainersEndpoint(t *testing.T) {
+	store := &mockStorage{
+		resources: []*storage.ManagedResource{
+			{ID: "r1", ResourceType: storage.ResourceTypeDNS, Hostname: "app.example.com", ContainerID: "abc123", Status: storage.StatusActive},
+			{ID: "r2", ResourceType: storage.ResourceTypeDNS, Hostname: "other.example.com", ContainerID: "def456", Status: storage.StatusActive},
+		},
+		conflicts: []*storage.Conflict{
+			{Hostname: "app.example.com", ContainerID: "def456", WinnerContainerID: "abc123", Reason: "higher priority"},
+		},
+	}
+	s := newActionTestServer(store)
+	s.config.Reconciler.UpdateAgentData("edge", []*types.ParsedContainer{
+		{
+			Info:        &types.ContainerInfo{ID: "abc123", Name: "app", Labels: map[string]string{"labelgate.dns.web.hostname": "app.example.com"}},
+			DNSServices: []*types.DNSService{{ServiceName: "web", Hostname: "app.example.com"}},
+			AgentID:     "edge",
+		},
+		{
+			Info:    &types.ContainerInfo{ID: "def456", Name: "broken", Labels: map[string]string{"labelgate.dns.web.proxyed": "true"}},
+			AgentID: "edge",
+			Errors:  []string{"dns service web: hostname is required"},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/containers?errors=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list struct {
+		Containers []map[string]any `json:"containers"`
+		Total      int              `json:"total"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if list.Total != 1 || list.Containers[0]["errors"] == nil {
+		t.Fatalf("expected only the container with errors, got %+v", list)
+	}
+
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/containers/abc", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var detail struct {
+		Info      types.ContainerInfo       `json:"info"`
+		Resources []storage.ManagedResource `json:"resources"`
+		Conflicts []storage.Conflict        `json:"conflicts"`
+		DNS       []types.DNSService        `json:"dns_services"`
+	}
+	json.NewDecoder(w.Body).Decode(&detail)
+	if detail.Info.Labels["labelgate.dns.web.hostname"] != "app.example.com" {
+		t.Errorf("expected raw labels, got %v", detail.Info.Labels)
+	}
+	if len(detail.Resources) != 1 || detail.Resources[0].ID != "r1" {
+		t.Errorf("expected owned resource r1, got %+v", detail.Resources)
+	}
+	if len(detail.Conflicts) != 1 || len(detail.DNS) != 1 {
+		t.Errorf("expected 1 conflict and 1 DNS service, got %d and %d", len(detail.Conflicts), len(detail.DNS))
+	}
+
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/containers/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	s := newActionTestServer(&mockStorage{})
+	s.config.Reconciler.UpdateAgentData("edge", []*types.ParsedContainer{
+		{
+			Info:           &types.ContainerInfo{ID: "abc123", Name: "auth"},
+			AccessPolicies: map[string]*types.AccessPolicyDef{"internal": {}},
+			AgentID:        "edge",
+		},
+	})
+
+	body := `{"labels": {"labelgate.dns.web.hostname": "app.example.com", "labelgate.dns.web.access": "internal", "labelgate.dns.web.proxyed": "false"}}`
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/validate", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Valid  bool           `json:"valid"`
+		Issues []labels.Issue `json:"issues"`
+	}
+	json.NewDecoder(w.Body).Decode(&result)
+	if result.Valid || len(result.Issues) != 1 || result.Issues[0].Suggestion != "labelgate.dns.web.proxied" {
+		t.Fatalf("expected only the proxyed typo, got %+v", result)
+	}
+
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/validate", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
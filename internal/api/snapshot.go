@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// handleSnapshot streams an online backup of the database. It is consistent
+// even while reconciles write to the database.
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	backuper, ok := s.config.Storage.(storage.Backuper)
+	if !ok {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "snapshots are only supported by the sqlite driver"})
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "labelgate-snapshot-*")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "labelgate.db")
+	if err := backuper.Backup(r.Context(), path); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	name := fmt.Sprintf("labelgate-%s.db", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/events"
+)
+
+// streamHeartbeat is how often an idle event stream sends a comment line, so
+// proxies and clients don't time the connection out.
+const streamHeartbeat = 15 * time.Second
+
+// handleEventStream streams live events as Server-Sent Events.
+// Query parameters: type (comma-separated types or categories, e.g.
+// "resource,agent.connected") and hostname. Clients that reconnect with a
+// Last-Event-ID header get the recent events they missed replayed first.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	bus := s.config.Events
+	if bus == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "event stream is not available"})
+		return
+	}
+
+	q := r.URL.Query()
+	filter := events.Filter{Hostname: q.Get("hostname")}
+	for _, t := range strings.Split(q.Get("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	var afterID uint64
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid Last-Event-ID %q", lastID)})
+			return
+		}
+		afterID = id
+	}
+
+	// Subscribe before responding so no event published after the client
+	// sees the response is missed
+	sub := bus.Subscribe(filter, afterID)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// The stream is long-lived; don't let a server write timeout cut it off
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/auth"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// Audit actions recorded for token management.
+const (
+	auditTokenCreate = "token.create"
+	auditTokenRevoke = "token.revoke"
+)
+
+// createTokenRequest is the body of POST /tokens.
+type createTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn is a Go duration such as "720h"; empty for no expiry
+	ExpiresIn string `json:"expires_in"`
+}
+
+// handleListTokens lists API tokens without their hashes.
+func (s *Server) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.config.Storage.ListAPITokens(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if tokens == nil {
+		tokens = []*storage.APIToken{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tokens": tokens})
+}
+
+// handleCreateToken creates an API token. The plaintext token is only
+// returned in this response.
+func (s *Server) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil || ttl <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid expires_in %q: must be a positive duration such as 720h", req.ExpiresIn)})
+			return
+		}
+	}
+
+	token, plaintext, err := auth.NewToken(req.Name, req.Scopes, ttl)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	err = s.config.Storage.CreateAPIToken(r.Context(), token)
+	s.audit(r, auditTokenCreate, req.Name, err, "scopes="+strings.Join(token.Scopes, ","))
+	if err != nil {
+		if storage.IsConflict(err) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": fmt.Sprintf("token %q already exists", req.Name)})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"token":      plaintext,
+		"name":       token.Name,
+		"scopes":     token.Scopes,
+		"expires_at": token.ExpiresAt,
+	})
+}
+
+// handleRevokeToken deletes an API token.
+func (s *Server) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := s.config.Storage.DeleteAPIToken(r.Context(), name)
+	s.audit(r, auditTokenRevoke, name, err, "")
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
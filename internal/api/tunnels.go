@@ -12,14 +12,5 @@ func (s *Server) handleTunnels(w http.ResponseWriter, r *http.Request) {
 	}
 	applyQueryFilters(r, &filter)
 
-	resources, err := s.config.Storage.ListResources(r.Context(), filter)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]any{
-		"resources": resources,
-		"total":     len(resources),
-	})
+	s.writeResources(w, r, filter)
 }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/channinghe/labelgate/pkg/labels"
+)
+
+// handleValidate lints container labels without applying them. The body is
+// either {"labels": {...}} for one container or {"containers": {"name": {...}}}.
+// Access references also resolve against the policies of running containers.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Labels     map[string]string            `json:"labels"`
+		Containers map[string]map[string]string `json:"containers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if (req.Labels == nil) == (req.Containers == nil) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "exactly one of labels or containers is required"})
+		return
+	}
+
+	prefix := s.config.LabelPrefix
+	if prefix == "" {
+		prefix = "labelgate"
+	}
+	parser := labels.NewParser(prefix)
+
+	policies := make(map[string]bool)
+	if rec := s.liveReconciler(); rec != nil {
+		for _, c := range rec.GetContainers() {
+			for name := range c.AccessPolicies {
+				policies[name] = true
+			}
+		}
+	}
+
+	if req.Labels != nil {
+		issues := parser.LintContainers(map[string]map[string]string{"": req.Labels}, policies)[""]
+		if issues == nil {
+			issues = []labels.Issue{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"valid":  !labels.HasErrors(issues),
+			"issues": issues,
+		})
+		return
+	}
+
+	results := parser.LintContainers(req.Containers, policies)
+	valid := true
+	for _, issues := range results {
+		valid = valid && !labels.HasErrors(issues)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"valid":      valid,
+		"containers": results,
+	})
+}
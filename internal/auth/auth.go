@@ -0,0 +1,280 @@
+// Package auth implements API authentication: named, scoped API tokens
+// stored as hashes, the legacy single api.token, and identities from
+// Cloudflare Access or an OIDC login mapped to scopes.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// API scopes.
+const (
+	// ScopeRead allows all read-only endpoints
+	ScopeRead = "read"
+	// ScopeWrite allows write actions such as reconcile, retry and forget
+	ScopeWrite = "write"
+	// ScopeAgentsAdmin allows sending commands to agents
+	ScopeAgentsAdmin = "agents-admin"
+	// ScopeAdmin allows everything, including managing API tokens
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists the valid scopes in canonical order.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAgentsAdmin, ScopeAdmin}
+
+// ErrUnauthorized is returned for a missing, unknown or expired token.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// tokenPrefix makes labelgate tokens recognizable, e.g. to secret scanners.
+const tokenPrefix = "lg_"
+
+// touchInterval limits how often a token's last-used time is written.
+const touchInterval = time.Minute
+
+var tokenNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Principal is an authenticated API caller.
+type Principal struct {
+	// Name identifies the caller in the audit trail (empty for anonymous access)
+	Name   string
+	Scopes []string
+}
+
+// Allows reports whether the principal has the given scope. The admin scope
+// implies every other scope, and any scope implies read.
+func (p *Principal) Allows(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin || scope == ScopeRead {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx, or nil.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// ParseScopes validates scope names, which may also be given comma-separated,
+// and returns them deduplicated in canonical order.
+func ParseScopes(values []string) ([]string, error) {
+	requested := make(map[string]bool)
+	for _, value := range values {
+		for _, scope := range strings.Split(value, ",") {
+			scope = strings.TrimSpace(scope)
+			if scope == "" {
+				continue
+			}
+			if !validScope(scope) {
+				return nil, fmt.Errorf("unknown scope %q (valid: %s)", scope, strings.Join(Scopes, ", "))
+			}
+			requested[scope] = true
+		}
+	}
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (valid: %s)", strings.Join(Scopes, ", "))
+	}
+
+	var scopes []string
+	for _, scope := range Scopes {
+		if requested[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HashToken returns the stored form of a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// NewToken generates a named token with the given scopes, expiring after ttl
+// (0 for never). It returns the record to store and the plaintext token,
+// which can't be recovered later.
+func NewToken(name string, scopes []string, ttl time.Duration) (*storage.APIToken, string, error) {
+	if !tokenNamePattern.MatchString(name) {
+		return nil, "", fmt.Errorf("invalid token name %q: use up to 64 letters, digits, '.', '_' or '-'", name)
+	}
+	scopes, err := ParseScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if ttl < 0 {
+		return nil, "", fmt.Errorf("token expiry must not be negative")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	plaintext := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	token := &storage.APIToken{
+		Name:      name,
+		Hash:      HashToken(plaintext),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if ttl > 0 {
+		expires := token.CreatedAt.Add(ttl)
+		token.ExpiresAt = &expires
+	}
+	return token, plaintext, nil
+}
+
+// TokenStore is the part of storage.Storage used to look up tokens.
+type TokenStore interface {
+	ListAPITokens(ctx context.Context) ([]*storage.APIToken, error)
+	TouchAPIToken(ctx context.Context, name string, at time.Time) error
+}
+
+// Authenticator resolves bearer tokens and identities to principals.
+type Authenticator struct {
+	legacyHash string
+	store      TokenStore
+	sources    []IdentitySource
+	roles      Roles
+
+	// LoginURL is where unauthenticated browsers can log in (empty if none)
+	LoginURL string
+
+	mu      sync.Mutex
+	touched map[string]time.Time
+}
+
+// NewAuthenticator creates an authenticator accepting legacyToken (the
+// api.token setting, granting every scope) and the tokens in store. Either
+// may be empty or nil.
+func NewAuthenticator(legacyToken string, store TokenStore) *Authenticator {
+	a := &Authenticator{store: store, touched: make(map[string]time.Time)}
+	if legacyToken != "" {
+		a.legacyHash = HashToken(legacyToken)
+	}
+	return a
+}
+
+// SetIdentitySources enables authentication by identity (Cloudflare Access,
+// OIDC sessions), with scopes granted according to roles.
+func (a *Authenticator) SetIdentitySources(roles Roles, sources ...IdentitySource) {
+	a.roles = roles
+	a.sources = sources
+}
+
+// AuthenticateRequest returns the principal for a request: by its bearer
+// token if it has one, otherwise by the first identity source that
+// recognizes it.
+func (a *Authenticator) AuthenticateRequest(r *http.Request) (*Principal, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.Authenticate(r.Context(), token)
+	}
+
+	for _, source := range a.sources {
+		id, err := source.Identify(r)
+		if err != nil {
+			log.Debug().Err(err).Msg("Rejected identity")
+			return nil, ErrUnauthorized
+		}
+		if id != nil {
+			return &Principal{Name: id.Name(), Scopes: a.roles.ScopesFor(id)}, nil
+		}
+	}
+	return a.Authenticate(r.Context(), "")
+}
+
+// Authenticate returns the principal for a bearer token. When no legacy
+// token, stored token or identity source is configured, authentication is
+// disabled and an anonymous principal with every scope is returned.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	var tokens []*storage.APIToken
+	if a.store != nil {
+		var err error
+		if tokens, err = a.store.ListAPITokens(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load API tokens: %w", err)
+		}
+	}
+	if a.legacyHash == "" && len(tokens) == 0 && len(a.sources) == 0 {
+		return &Principal{Scopes: []string{ScopeAdmin}}, nil
+	}
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	// Compare fixed-length hashes so timing reveals nothing about the token
+	hash := HashToken(token)
+	if a.legacyHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.legacyHash)) == 1 {
+		return &Principal{Name: "api.token", Scopes: []string{ScopeAdmin}}, nil
+	}
+
+	var match *storage.APIToken
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(t.Hash)) == 1 {
+			match = t
+		}
+	}
+	if match == nil {
+		return nil, ErrUnauthorized
+	}
+
+	now := time.Now()
+	if match.Expired(now) {
+		log.Debug().Str("token", match.Name).Msg("Rejected expired API token")
+		return nil, ErrUnauthorized
+	}
+	a.touch(ctx, match.Name, now)
+
+	return &Principal{Name: "token:" + match.Name, Scopes: match.Scopes}, nil
+}
+
+// touch records token use, at most once per touchInterval per token.
+func (a *Authenticator) touch(ctx context.Context, name string, now time.Time) {
+	a.mu.Lock()
+	if last, ok := a.touched[name]; ok && now.Sub(last) < touchInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.touched[name] = now
+	a.mu.Unlock()
+
+	if err := a.store.TouchAPIToken(context.WithoutCancel(ctx), name, now.UTC()); err != nil {
+		log.Warn().Err(err).Str("token", name).Msg("Failed to record API token use")
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+type memoryTokens struct {
+	tokens  []*storage.APIToken
+	touches int
+}
+
+func (m *memoryTokens) ListAPITokens(ctx context.Context) ([]*storage.APIToken, error) {
+	return m.tokens, nil
+}
+
+func (m *memoryTokens) TouchAPIToken(ctx context.Context, name string, at time.Time) error {
+	m.touches++
+	return nil
+}
+
+func TestAuthenticateDisabledWithoutTokens(t *testing.T) {
+	a := NewAuthenticator("", &memoryTokens{})
+	p, err := a.Authenticate(context.Background(), "")
+	if err != nil {
+		t.Fatalf("expected anonymous access, got %v", err)
+	}
+	if p.Name != "" || !p.Allows(ScopeAdmin) {
+		t.Errorf("expected anonymous principal with every scope, got %+v", p)
+	}
+}
+
+func TestAuthenticateLegacyToken(t *testing.T) {
+	a := NewAuthenticator("secret", nil)
+
+	p, err := a.Authenticate(context.Background(), "secret")
+	if err != nil {
+		t.Fatalf("expected legacy token to be accepted: %v", err)
+	}
+	if !p.Allows(ScopeAgentsAdmin) {
+		t.Errorf("expected legacy token to grant every scope")
+	}
+
+	for _, token := range []string{"", "wrong", "secret2"} {
+		if _, err := a.Authenticate(context.Background(), token); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("token %q: expected ErrUnauthorized, got %v", token, err)
+		}
+	}
+}
+
+func TestAuthenticateStoredTokens(t *testing.T) {
+	ci, ciSecret, err := NewToken("ci", []string{"read"}, 0)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	old, oldSecret, err := NewToken("old", []string{"write"}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	old.ExpiresAt = &past
+
+	store := &memoryTokens{tokens: []*storage.APIToken{ci, old}}
+	a := NewAuthenticator("", store)
+
+	p, err := a.Authenticate(context.Background(), ciSecret)
+	if err != nil {
+		t.Fatalf("expected stored token to be accepted: %v", err)
+	}
+	if p.Name != "token:ci" || !p.Allows(ScopeRead) || p.Allows(ScopeWrite) {
+		t.Errorf("unexpected principal %+v", p)
+	}
+
+	// Use is recorded at most once per interval
+	a.Authenticate(context.Background(), ciSecret)
+	if store.touches != 1 {
+		t.Errorf("expected 1 recorded use, got %d", store.touches)
+	}
+
+	if _, err := a.Authenticate(context.Background(), oldSecret); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected expired token to be rejected, got %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected missing token to be rejected once tokens exist, got %v", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"write,read", "read"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scopes) != 2 || scopes[0] != ScopeRead || scopes[1] != ScopeWrite {
+		t.Errorf("expected [read write], got %v", scopes)
+	}
+
+	if _, err := ParseScopes([]string{"superuser"}); err == nil {
+		t.Error("expected unknown scope to be rejected")
+	}
+	if _, err := ParseScopes(nil); err == nil {
+		t.Error("expected empty scopes to be rejected")
+	}
+}
+
+func TestNewTokenStoresHashOnly(t *testing.T) {
+	token, plaintext, err := NewToken("deploy", []string{"write"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.Hash == plaintext || token.Hash != HashToken(plaintext) {
+		t.Errorf("expected the hash of the token to be stored")
+	}
+	if token.ExpiresAt != nil {
+		t.Errorf("expected no expiry, got %v", token.ExpiresAt)
+	}
+
+	if _, _, err := NewToken("bad name", []string{"read"}, 0); err == nil {
+		t.Error("expected invalid name to be rejected")
+	}
+}
+
+type stubSource struct{ id *Identity }
+
+func (s stubSource) Identify(r *http.Request) (*Identity, error) {
+	if r.Header.Get("X-Stub") == "" {
+		return nil, nil
+	}
+	return s.id, nil
+}
+
+func TestAuthenticateRequestIdentity(t *testing.T) {
+	a := NewAuthenticator("", &memoryTokens{})
+	a.SetIdentitySources(Roles{ScopeWrite: {"alice@example.com"}}, stubSource{&Identity{Source: "access", Email: "alice@example.com"}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, err := a.AuthenticateRequest(r); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected identity sources to require authentication, got %v", err)
+	}
+
+	r.Header.Set("X-Stub", "1")
+	p, err := a.AuthenticateRequest(r)
+	if err != nil {
+		t.Fatalf("expected identity to authenticate: %v", err)
+	}
+	if p.Name != "alice@example.com" || !p.Allows(ScopeWrite) || p.Allows(ScopeAdmin) {
+		t.Errorf("unexpected principal %+v", p)
+	}
+
+	// A bearer token takes precedence over identities
+	r.Header.Set("Authorization", "Bearer unknown")
+	if _, err := a.AuthenticateRequest(r); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected unknown bearer token to be rejected, got %v", err)
+	}
+}
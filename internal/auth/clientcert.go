@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ClientCertificates authenticates requests by the TLS client certificate
+// verified during the handshake (see api.tls.client_ca).
+type ClientCertificates struct{}
+
+// Identify implements IdentitySource. The identity is the certificate's
+// first email address and common name; organizational units become groups.
+func (ClientCertificates) Identify(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	id := &Identity{Source: "cert", Subject: cert.Subject.CommonName, Groups: cert.Subject.OrganizationalUnit}
+	if len(cert.EmailAddresses) > 0 {
+		id.Email = cert.EmailAddresses[0]
+	}
+	if id.Subject == "" && id.Email == "" {
+		return nil, errors.New("client certificate has no common name or email address")
+	}
+	return id, nil
+}
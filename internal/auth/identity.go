@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Identity is a user or service authenticated by an identity provider
+// rather than an API token.
+type Identity struct {
+	// Source is the provider: "access", "oidc" or "cert"
+	Source  string   `json:"source"`
+	Subject string   `json:"sub"`
+	Email   string   `json:"email,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+}
+
+// Name identifies the identity in the audit trail.
+func (id *Identity) Name() string {
+	if id.Email != "" {
+		return id.Email
+	}
+	return id.Source + ":" + id.Subject
+}
+
+// IdentitySource authenticates requests by something other than a bearer
+// token, such as a signed header or a session cookie.
+type IdentitySource interface {
+	// Identify returns nil and no error when the request carries no
+	// credential for this source.
+	Identify(r *http.Request) (*Identity, error)
+}
+
+// Roles maps a scope to the identities granted it. Entries are an email
+// or subject, "@domain" for every email in a domain, "group:<name>" for a
+// group member, or "*" for any authenticated identity.
+type Roles map[string][]string
+
+// ScopesFor returns the scopes granted to id, in canonical order.
+func (r Roles) ScopesFor(id *Identity) []string {
+	var scopes []string
+	for _, scope := range Scopes {
+		for _, pattern := range r[scope] {
+			if matchIdentity(pattern, id) {
+				scopes = append(scopes, scope)
+				break
+			}
+		}
+	}
+	return scopes
+}
+
+func matchIdentity(pattern string, id *Identity) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "group:"):
+		group := strings.TrimPrefix(pattern, "group:")
+		for _, g := range id.Groups {
+			if g == group {
+				return true
+			}
+		}
+		return false
+	case strings.HasPrefix(pattern, "@"):
+		return id.Email != "" && strings.HasSuffix(strings.ToLower(id.Email), strings.ToLower(pattern))
+	default:
+		return strings.EqualFold(pattern, id.Email) || pattern == id.Subject
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+)
+
+// signingAlgs are the JWT signing algorithms accepted from identity
+// providers that don't advertise theirs. Only asymmetric algorithms are
+// listed, so a public key can never be used as an HMAC secret; go-jose
+// checks that each algorithm matches the type of the key.
+var signingAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+	oidc.PS256, oidc.PS384, oidc.PS512,
+	oidc.EdDSA,
+}
+
+// newHTTPClient returns the client used to talk to identity providers.
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// LoadKeySetFile reads a JWKS file into a static key set. Encryption keys
+// and private keys are ignored.
+func LoadKeySetFile(path string) (*oidc.StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%s: invalid JWKS: %w", path, err)
+	}
+
+	keys := &oidc.StaticKeySet{}
+	for _, key := range set.Keys {
+		if (key.Use != "" && key.Use != "sig") || !key.IsPublic() {
+			continue
+		}
+		switch key.Key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+			keys.PublicKeys = append(keys.PublicKeys, key.Key)
+		}
+	}
+	if len(keys.PublicKeys) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, errors.New("JWKS contains no usable signing keys"))
+	}
+	return keys, nil
+}
+
+// stringsClaim returns a claim holding a string or a list of strings.
+func stringsClaim(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
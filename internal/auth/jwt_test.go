@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signJWT signs claims with an RSA (RS256) or EC P-256 (ES256) key.
+func signJWT(t *testing.T, key crypto.Signer, kid string, claims map[string]any) string {
+	t.Helper()
+
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// jwksJSON returns a JWKS containing the public keys of keys by key ID.
+func jwksJSON(t *testing.T, keys map[string]crypto.Signer) []byte {
+	t.Helper()
+
+	enc := base64.RawURLEncoding.EncodeToString
+	var set []map[string]string
+	for kid, key := range keys {
+		switch k := key.Public().(type) {
+		case *rsa.PublicKey:
+			set = append(set, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig",
+				"n": enc(k.N.Bytes()), "e": enc(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			point, _ := k.Bytes()
+			set = append(set, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": enc(point[1:33]), "y": enc(point[33:]),
+			})
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"keys": set})
+	return data
+}
+
+func generateKeys(t *testing.T) (*rsa.PrivateKey, *ecdsa.PrivateKey) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return rsaKey, ecKey
+}
+
+func TestLoadKeySetFile(t *testing.T) {
+	rsaKey, ecKey := generateKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+
+	if err := os.WriteFile(path, jwksJSON(t, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeySetFile(path)
+	if err != nil || len(keys.PublicKeys) != 2 {
+		t.Fatalf("expected 2 keys, got %v, %v", keys, err)
+	}
+
+	// Encryption keys are not signing keys
+	enc := base64.RawURLEncoding.EncodeToString
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "enc", "use": "enc",
+		"n": enc(rsaKey.N.Bytes()), "e": enc(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}}})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeySetFile(path); err == nil {
+		t.Error("expected a JWKS without signing keys to be rejected")
+	}
+}
+
+func TestAccessValidator(t *testing.T) {
+	rsaKey, ecKey := generateKeys(t)
+	jwks := jwksJSON(t, map[string]crypto.Signer{"k1": rsaKey, "k2": ecKey})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewAccessValidator("myteam.cloudflareaccess.com", "aud-tag", jwksFile)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]any{
+		"iss": "https://myteam.cloudflareaccess.com", "aud": []string{"aud-tag"},
+		"sub": "u1", "email": "alice@example.com", "exp": exp,
+	}
+
+	identify := func(token string) (*Identity, error) {
+		r := httptest.NewRequest("GET", "/api/overview", nil)
+		if token != "" {
+			r.Header.Set(AccessHeader, token)
+		}
+		return v.Identify(r)
+	}
+
+	if id, err := identify(""); id != nil || err != nil {
+		t.Errorf("expected no identity without header, got %v, %v", id, err)
+	}
+
+	for kid, key := range map[string]crypto.Signer{"k1": rsaKey, "k2": ecKey} {
+		id, err := identify(signJWT(t, key, kid, valid))
+		if err != nil || id.Email != "alice@example.com" || id.Source != "access" {
+			t.Fatalf("%s: expected alice, got %+v, %v", kid, id, err)
+		}
+	}
+
+	id, err := identify(signJWT(t, rsaKey, "k1", map[string]any{
+		"iss": "https://myteam.cloudflareaccess.com", "aud": "aud-tag",
+		"sub": "", "common_name": "client-id.access", "exp": exp,
+	}))
+	if err != nil || id.Name() != "access:client-id.access" {
+		t.Fatalf("expected service token identity, got %+v, %v", id, err)
+	}
+
+	token := signJWT(t, rsaKey, "k1", valid)
+	parts := strings.Split(token, ".")
+	tampered, _ := json.Marshal(map[string]any{
+		"iss": "https://myteam.cloudflareaccess.com", "aud": "aud-tag", "email": "root@example.com", "exp": exp,
+	})
+	encode := base64.RawURLEncoding.EncodeToString
+	hmacSigned := func(header string) string {
+		signed := encode([]byte(header)) + "." + parts[1]
+		mac := hmac.New(sha256.New, jwks)
+		mac.Write([]byte(signed))
+		return signed + "." + encode(mac.Sum(nil))
+	}
+
+	rejected := map[string]string{
+		"another application": signJWT(t, rsaKey, "k1", map[string]any{
+			"iss": "https://myteam.cloudflareaccess.com", "aud": "other-app", "email": "alice@example.com", "exp": exp,
+		}),
+		"another team": signJWT(t, rsaKey, "k1", map[string]any{
+			"iss": "https://evil.cloudflareaccess.com", "aud": "aud-tag", "email": "alice@example.com", "exp": exp,
+		}),
+		"expired": signJWT(t, rsaKey, "k1", map[string]any{
+			"iss": "https://myteam.cloudflareaccess.com", "aud": "aud-tag", "email": "alice@example.com",
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		}),
+		"tampered payload": parts[0] + "." + encode(tampered) + "." + parts[2],
+		"alg none":         encode([]byte(`{"alg":"none","kid":"k1"}`)) + "." + parts[1] + ".",
+		// The public key set used as an HMAC secret
+		"HMAC with public keys": hmacSigned(`{"alg":"HS256","kid":"k1"}`),
+		// An RSA signature presented as ECDSA
+		"alg not matching key": encode([]byte(`{"alg":"ES256","kid":"k1"}`)) + "." + parts[1] + "." + parts[2],
+	}
+	for name, token := range rejected {
+		if id, err := identify(token); err == nil {
+			t.Errorf("%s: expected token to be rejected, got %+v", name, id)
+		}
+	}
+}
+
+func TestRolesScopesFor(t *testing.T) {
+	roles := Roles{
+		ScopeRead:        {"*"},
+		ScopeWrite:       {"@example.com"},
+		ScopeAgentsAdmin: {"group:ops"},
+		ScopeAdmin:       {"Root@Example.com"},
+	}
+
+	tests := []struct {
+		id   Identity
+		want string
+	}{
+		{Identity{Email: "guest@other.org"}, "read"},
+		{Identity{Email: "bob@example.com"}, "read,write"},
+		{Identity{Email: "carol@other.org", Groups: []string{"ops"}}, "read,agents-admin"},
+		{Identity{Email: "root@example.com"}, "read,write,admin"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(roles.ScopesFor(&tt.id), ","); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.id.Email, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+// Cookies set by the OIDC login flow.
+const (
+	SessionCookie    = "labelgate_session"
+	loginStateCookie = "labelgate_oidc"
+)
+
+// loginStateTTL is how long a user has to complete a login at the provider.
+const loginStateTTL = 10 * time.Minute
+
+// OIDCConfig configures an OIDC login flow.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+
+	// SessionSecret signs cookies; a random one is used if empty
+	SessionSecret string
+	SessionTTL    time.Duration
+
+	// HTTPClient talks to the provider (default: 10s timeout)
+	HTTPClient *http.Client
+}
+
+// OIDC implements an OpenID Connect authorization code login (with PKCE)
+// and authenticates requests by the resulting session cookie.
+type OIDC struct {
+	cfg    OIDCConfig
+	secret []byte
+	client *http.Client
+	secure bool
+
+	mu       sync.Mutex
+	provider *oidcProvider
+}
+
+// oidcProvider is a discovered provider: its endpoints and an ID token
+// verifier using its key set.
+type oidcProvider struct {
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// loginState is carried through the provider redirect in a signed cookie.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+	Expires  int64  `json:"exp"`
+}
+
+// session is the payload of the signed session cookie.
+type session struct {
+	Identity
+	Expires int64 `json:"exp"`
+}
+
+// NewOIDC creates an OIDC login flow. The provider is discovered on first use.
+func NewOIDC(cfg OIDCConfig) (*OIDC, error) {
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+
+	o := &OIDC{cfg: cfg, client: cfg.HTTPClient, secure: redirect.Scheme == "https"}
+	if o.client == nil {
+		o.client = newHTTPClient()
+	}
+	if cfg.SessionSecret != "" {
+		o.secret = []byte(cfg.SessionSecret)
+	} else {
+		o.secret = make([]byte, 32)
+		if _, err := rand.Read(o.secret); err != nil {
+			return nil, err
+		}
+		log.Warn().Msg("api.auth.oidc.session_secret is not set; sessions end on restart and are not shared between instances")
+	}
+	return o, nil
+}
+
+// HandleLogin redirects to the provider. The optional return_to query
+// parameter is a local path to return to after login.
+func (o *OIDC) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	provider, err := o.discover(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("OIDC discovery failed")
+		http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	returnTo := r.URL.Query().Get("return_to")
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		returnTo = "/dashboard/"
+	}
+	state := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		ReturnTo: returnTo,
+		Expires:  time.Now().Add(loginStateTTL).Unix(),
+	}
+	o.setCookie(w, loginStateCookie, o.sign(state), loginStateTTL)
+
+	authURL := provider.oauth.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleCallback completes the login and sets the session cookie.
+func (o *OIDC) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	var state loginState
+	cookie, err := r.Cookie(loginStateCookie)
+	if err != nil || !o.verify(cookie.Value, &state) || time.Now().Unix() > state.Expires {
+		http.Error(w, "login expired, please try again", http.StatusBadRequest)
+		return
+	}
+	o.setCookie(w, loginStateCookie, "", -1)
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "login failed: "+e+" "+q.Get("error_description"), http.StatusUnauthorized)
+		return
+	}
+	if q.Get("state") != state.State {
+		http.Error(w, "login state mismatch, please try again", http.StatusBadRequest)
+		return
+	}
+
+	id, err := o.exchange(r.Context(), q.Get("code"), &state)
+	if err != nil {
+		log.Warn().Err(err).Msg("OIDC login failed")
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+
+	s := session{Identity: *id, Expires: time.Now().Add(o.cfg.SessionTTL).Unix()}
+	o.setCookie(w, SessionCookie, o.sign(s), o.cfg.SessionTTL)
+	log.Info().Str("identity", id.Name()).Msg("OIDC login")
+	http.Redirect(w, r, state.ReturnTo, http.StatusFound)
+}
+
+// HandleLogout clears the session cookie.
+func (o *OIDC) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	o.setCookie(w, SessionCookie, "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Identify implements IdentitySource using the session cookie.
+func (o *OIDC) Identify(r *http.Request) (*Identity, error) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	var s session
+	if !o.verify(cookie.Value, &s) {
+		return nil, errors.New("invalid session cookie")
+	}
+	if time.Now().Unix() > s.Expires {
+		return nil, errors.New("session has expired")
+	}
+	return &s.Identity, nil
+}
+
+// exchange redeems an authorization code and validates the ID token.
+func (o *OIDC) exchange(ctx context.Context, code string, state *loginState) (*Identity, error) {
+	provider, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, o.client)
+	token, err := provider.oauth.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := provider.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	// An unverified email could be set to anyone's address, and roles match on it
+	email, _ := claims["email"].(string)
+	if verified, _ := claims["email_verified"].(bool); !verified {
+		email = ""
+	}
+
+	return &Identity{
+		Source:  "oidc",
+		Subject: idToken.Subject,
+		Email:   email,
+		Groups:  stringsClaim(claims, o.cfg.GroupsClaim),
+	}, nil
+}
+
+// discover fetches the provider's discovery document once.
+func (o *OIDC) discover(ctx context.Context) (*oidcProvider, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return o.provider, nil
+	}
+
+	// The key set keeps using the client, but not ctx, for later fetches
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, o.client), o.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	endpoint := provider.Endpoint()
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	// Confidential clients authenticate with HTTP basic auth, public ones
+	// only send their client_id
+	endpoint.AuthStyle = oauth2.AuthStyleInParams
+	if o.cfg.ClientSecret != "" {
+		endpoint.AuthStyle = oauth2.AuthStyleInHeader
+	}
+
+	o.provider = &oidcProvider{
+		oauth: &oauth2.Config{
+			ClientID:     o.cfg.ClientID,
+			ClientSecret: o.cfg.ClientSecret,
+			Endpoint:     endpoint,
+			RedirectURL:  o.cfg.RedirectURL,
+			Scopes:       o.cfg.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: o.cfg.ClientID}),
+	}
+	return o.provider, nil
+}
+
+// sign encodes v as a signed cookie value.
+func (o *OIDC) sign(v any) string {
+	payload, _ := json.Marshal(v)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(o.mac(encoded))
+}
+
+// verify checks a signed cookie value and decodes it into v.
+func (o *OIDC) verify(value string, v any) bool {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, o.mac(encoded)) {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(payload, v) == nil
+}
+
+func (o *OIDC) mac(data string) []byte {
+	h := hmac.New(sha256.New, o.secret)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// setCookie sets an HttpOnly cookie; a negative ttl deletes it.
+func (o *OIDC) setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   o.secure,
+		// Lax keeps the cookie off cross-site POSTs (CSRF) but allows the
+		// top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
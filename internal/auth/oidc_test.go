@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a minimal OIDC provider issuing ID tokens for one user.
+type fakeProvider struct {
+	t         *testing.T
+	srv       *httptest.Server
+	challenge string
+	nonce     string
+
+	emailVerified bool
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	rsaKey, _ := generateKeys(t)
+	p := &fakeProvider{t: t, emailVerified: true}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.srv.URL,
+			"authorization_endpoint": p.srv.URL + "/authorize",
+			"token_endpoint":         p.srv.URL + "/token",
+			"jwks_uri":               p.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwksJSON(t, map[string]crypto.Signer{"k1": rsaKey}))
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		if id, secret, _ := r.BasicAuth(); id != "labelgate" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token": signJWT(t, rsaKey, "k1", map[string]any{
+				"iss": p.srv.URL, "aud": "labelgate", "sub": "user-1", "email": "alice@example.com",
+				"email_verified": p.emailVerified, "groups": []string{"ops"}, "nonce": p.nonce, "exp": time.Now().Add(time.Hour).Unix(),
+			}),
+		})
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func TestOIDCUnverifiedEmail(t *testing.T) {
+	provider := newFakeProvider(t)
+	provider.emailVerified = false
+	o, err := NewOIDC(OIDCConfig{
+		Issuer:        provider.srv.URL,
+		ClientID:      "labelgate",
+		ClientSecret:  "s3cret",
+		RedirectURL:   "http://labelgate.test/api/auth/callback",
+		GroupsClaim:   "groups",
+		SessionSecret: "session-secret",
+		HTTPClient:    provider.srv.Client(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create OIDC: %v", err)
+	}
+
+	state := &loginState{Nonce: "nonce", Verifier: "verifier"}
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	provider.challenge, provider.nonce = base64.RawURLEncoding.EncodeToString(challenge[:]), state.Nonce
+
+	id, err := o.exchange(t.Context(), "good-code", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Email != "" || id.Subject != "user-1" || len(id.Groups) != 1 {
+		t.Errorf("expected an identity without the unverified email, got %+v", id)
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	provider := newFakeProvider(t)
+	o, err := NewOIDC(OIDCConfig{
+		Issuer:        provider.srv.URL,
+		ClientID:      "labelgate",
+		ClientSecret:  "s3cret",
+		RedirectURL:   "http://labelgate.test/api/auth/callback",
+		Scopes:        []string{"openid", "email"},
+		GroupsClaim:   "groups",
+		SessionSecret: "session-secret",
+		SessionTTL:    time.Hour,
+		HTTPClient:    provider.srv.Client(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create OIDC: %v", err)
+	}
+
+	// Login redirects to the provider with PKCE and a state cookie
+	w := httptest.NewRecorder()
+	o.HandleLogin(w, httptest.NewRequest("GET", "/api/auth/login?return_to=/dashboard/dns", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected redirect, got %d: %s", w.Code, w.Body.String())
+	}
+	authorize, _ := url.Parse(w.Header().Get("Location"))
+	if !strings.HasPrefix(authorize.String(), provider.srv.URL+"/authorize?") {
+		t.Fatalf("unexpected redirect %s", authorize)
+	}
+	q := authorize.Query()
+	provider.challenge, provider.nonce = q.Get("code_challenge"), q.Get("nonce")
+	stateCookie := w.Result().Cookies()[0]
+
+	callback := func(query string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/auth/callback?"+query, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		o.HandleCallback(w, r)
+		return w
+	}
+
+	if w := callback("code=good-code&state=" + q.Get("state")); w.Code != http.StatusBadRequest {
+		t.Errorf("expected callback without state cookie to fail, got %d", w.Code)
+	}
+	if w := callback("code=good-code&state=forged", stateCookie); w.Code != http.StatusBadRequest {
+		t.Errorf("expected forged state to fail, got %d", w.Code)
+	}
+
+	w = callback("code=good-code&state="+q.Get("state"), stateCookie)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/dashboard/dns" {
+		t.Fatalf("expected redirect to /dashboard/dns, got %d %s: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == SessionCookie {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie")
+	}
+
+	r := httptest.NewRequest("GET", "/api/overview", nil)
+	r.AddCookie(session)
+	id, err := o.Identify(r)
+	if err != nil || id == nil {
+		t.Fatalf("expected session to identify the user, got %v", err)
+	}
+	if id.Email != "alice@example.com" || len(id.Groups) != 1 || id.Groups[0] != "ops" {
+		t.Errorf("unexpected identity %+v", id)
+	}
+
+	// A session cookie with a modified payload is rejected
+	payload, _ := json.Marshal(map[string]any{"source": "oidc", "email": "root@example.com", "exp": time.Now().Add(time.Hour).Unix()})
+	_, sig, _ := strings.Cut(session.Value, ".")
+	r = httptest.NewRequest("GET", "/api/overview", nil)
+	r.AddCookie(&http.Cookie{Name: SessionCookie, Value: base64.RawURLEncoding.EncodeToString(payload) + "." + sig})
+	if _, err := o.Identify(r); err == nil {
+		t.Error("expected forged session to be rejected")
+	}
+}
+
+func TestOIDCLoginRejectsOpenRedirect(t *testing.T) {
+	provider := newFakeProvider(t)
+	o, _ := NewOIDC(OIDCConfig{
+		Issuer:      provider.srv.URL,
+		ClientID:    "labelgate",
+		RedirectURL: "https://labelgate.test/api/auth/callback",
+		SessionTTL:  time.Hour,
+		HTTPClient:  provider.srv.Client(),
+	})
+
+	w := httptest.NewRecorder()
+	o.HandleLogin(w, httptest.NewRequest("GET", "/api/auth/login?return_to=//evil.example", nil))
+
+	var state loginState
+	cookie := w.Result().Cookies()[0]
+	if !o.verify(cookie.Value, &state) || state.ReturnTo != "/dashboard/" {
+		t.Errorf("expected return_to to fall back to /dashboard/, got %q", state.ReturnTo)
+	}
+	if !cookie.Secure {
+		t.Error("expected a Secure cookie for an https redirect URL")
+	}
+}
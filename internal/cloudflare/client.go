@@ -4,19 +4,28 @@ package cloudflare
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	cf "github.com/cloudflare/cloudflare-go/v6"
 	"github.com/cloudflare/cloudflare-go/v6/option"
 	"github.com/cloudflare/cloudflare-go/v6/zones"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+
+	"github.com/channinghe/labelgate/internal/metrics"
+	"github.com/channinghe/labelgate/internal/tracing"
 )
 
 // Client wraps the Cloudflare API client with caching and credential management.
 type Client struct {
 	api         *cf.Client
 	accountID   string
+	limiter     *rate.Limiter // shared API rate limiter (nil = unlimited)
 	zoneCache   map[string]string // zone name -> zone ID (e.g. "example.com" -> "abc123")
 	zonesLoaded bool              // whether zone cache has been populated
 	zoneCacheMu sync.RWMutex
@@ -24,10 +33,56 @@ type Client struct {
 
 // NewClient creates a new Cloudflare client with API token authentication.
 func NewClient(apiToken string) *Client {
-	return &Client{
-		api:       cf.NewClient(option.WithAPIToken(apiToken)),
+	c := &Client{
 		zoneCache: make(map[string]string),
 	}
+	c.api = cf.NewClient(
+		option.WithAPIToken(apiToken),
+		option.WithMiddleware(c.rateLimit, instrument),
+	)
+	return c
+}
+
+// SetRateLimiter sets the rate limiter applied to every API request.
+// The same limiter may be shared by several clients. Must be called before use.
+func (c *Client) SetRateLimiter(limiter *rate.Limiter) {
+	c.limiter = limiter
+}
+
+// rateLimit is request middleware that waits for the shared rate limiter.
+func (c *Client) rateLimit(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return next(req)
+}
+
+// instrument is request middleware that records request counts and latency
+// and traces each request, excluding time spent waiting for the rate limiter.
+func instrument(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	endpoint := metrics.CloudflareEndpoint(req.URL.Path)
+	ctx, span := tracing.Start(req.Context(), "cloudflare "+req.Method+" "+endpoint,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("http.route", endpoint),
+	)
+	start := time.Now()
+	resp, err := next(req.WithContext(ctx))
+	metrics.CloudflareRequestDuration.WithLabelValues(endpoint, req.Method).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	spanErr := err
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			spanErr = fmt.Errorf("cloudflare API returned %s", resp.Status)
+		}
+	}
+	metrics.CloudflareRequests.WithLabelValues(endpoint, req.Method, status).Inc()
+	tracing.End(span, spanErr)
+	return resp, err
 }
 
 // SetAccountID sets the account ID for tunnel operations.
@@ -3,11 +3,14 @@ package cloudflare
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
 
 	"github.com/channinghe/labelgate/internal/config"
 )
@@ -35,22 +38,56 @@ type HealthResult struct {
 	Error     string
 }
 
-// CredentialManager manages multiple Cloudflare credentials.
+// CredentialManager manages multiple Cloudflare credentials. The credentials
+// can be replaced at runtime with Update.
 type CredentialManager struct {
+	set atomic.Pointer[credentialSet]
+
+	// Cached health check
+	healthResult *HealthResult
+	healthMu     sync.RWMutex
+}
+
+// credentialSet is the credentials and tunnels of one configuration.
+type credentialSet struct {
 	credentials       []Credential
 	tunnelCredentials []TunnelCredential
 	defaultCredential *Credential
 	clients           map[string]*Client // credential name -> client
 	clientsMu         sync.RWMutex
-
-	// Cached health check
-	healthResult *HealthResult
-	healthMu     sync.RWMutex
+	limiter           *rate.Limiter // shared by all clients (nil = unlimited)
 }
 
 // NewCredentialManager creates a new credential manager from config.
 func NewCredentialManager(cfg *config.Config) (*CredentialManager, error) {
-	cm := &CredentialManager{
+	set, err := newCredentialSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cm := &CredentialManager{}
+	cm.set.Store(set)
+	return cm, nil
+}
+
+// Update replaces the credentials and tunnels with those in cfg, e.g. on a
+// configuration reload. Operations in flight finish with the previous
+// credentials. On error the current credentials are kept.
+func (cm *CredentialManager) Update(cfg *config.Config) error {
+	set, err := newCredentialSet(cfg)
+	if err != nil {
+		return err
+	}
+	cm.set.Store(set)
+
+	// The cached health check was made with the previous credentials
+	cm.healthMu.Lock()
+	cm.healthResult = nil
+	cm.healthMu.Unlock()
+	return nil
+}
+
+func newCredentialSet(cfg *config.Config) (*credentialSet, error) {
+	cs := &credentialSet{
 		credentials:       make([]Credential, 0),
 		tunnelCredentials: make([]TunnelCredential, 0),
 		clients:           make(map[string]*Client),
@@ -63,8 +100,8 @@ func NewCredentialManager(cfg *config.Config) (*CredentialManager, error) {
 			APIToken: cfg.Cloudflare.APIToken,
 			Default:  true,
 		}
-		cm.credentials = append(cm.credentials, defaultCred)
-		cm.defaultCredential = &cm.credentials[0]
+		cs.credentials = append(cs.credentials, defaultCred)
+		cs.defaultCredential = &cs.credentials[0]
 	}
 
 	// Load additional named credentials from config
@@ -75,13 +112,13 @@ func NewCredentialManager(cfg *config.Config) (*CredentialManager, error) {
 			Zones:    cfgCred.Zones,
 			Default:  false,
 		}
-		cm.credentials = append(cm.credentials, cred)
+		cs.credentials = append(cs.credentials, cred)
 	}
 
 	// If no default set, use the first one
-	if cm.defaultCredential == nil && len(cm.credentials) > 0 {
-		cm.credentials[0].Default = true
-		cm.defaultCredential = &cm.credentials[0]
+	if cs.defaultCredential == nil && len(cs.credentials) > 0 {
+		cs.credentials[0].Default = true
+		cs.defaultCredential = &cs.credentials[0]
 	}
 
 	// Load default tunnel from root-level cloudflare config
@@ -91,7 +128,7 @@ func NewCredentialManager(cfg *config.Config) (*CredentialManager, error) {
 			TunnelName: "default",
 			AccountID:  cfg.Cloudflare.AccountID,
 		}
-		cm.tunnelCredentials = append(cm.tunnelCredentials, tunnelCred)
+		cs.tunnelCredentials = append(cs.tunnelCredentials, tunnelCred)
 	}
 
 	// Load additional named tunnels from config
@@ -102,24 +139,30 @@ func NewCredentialManager(cfg *config.Config) (*CredentialManager, error) {
 			AccountID:  tunnel.AccountID,
 			Credential: tunnel.Credential,
 		}
-		cm.tunnelCredentials = append(cm.tunnelCredentials, tunnelCred)
+		cs.tunnelCredentials = append(cs.tunnelCredentials, tunnelCred)
 	}
 
-	if len(cm.credentials) == 0 {
+	if len(cs.credentials) == 0 {
 		return nil, fmt.Errorf("no Cloudflare credentials configured")
 	}
 
-	return cm, nil
+	// Shared rate limiter: concurrent reconcile workers all draw from one budget
+	if cfg.Cloudflare.RateLimit > 0 {
+		burst := int(math.Ceil(cfg.Cloudflare.RateLimit))
+		cs.limiter = rate.NewLimiter(rate.Limit(cfg.Cloudflare.RateLimit), burst)
+	}
+
+	return cs, nil
 }
 
-// GetCredentialForZone returns the appropriate credential for a given hostname.
+// credentialForZone returns the appropriate credential for a given hostname.
 // Priority: explicit label > zone matching > default
-func (cm *CredentialManager) GetCredentialForZone(hostname string, explicitCredName string) (*Credential, error) {
+func (cs *credentialSet) credentialForZone(hostname string, explicitCredName string) (*Credential, error) {
 	// 1. Check explicit credential name
 	if explicitCredName != "" {
-		for i := range cm.credentials {
-			if cm.credentials[i].Name == explicitCredName {
-				return &cm.credentials[i], nil
+		for i := range cs.credentials {
+			if cs.credentials[i].Name == explicitCredName {
+				return &cs.credentials[i], nil
 			}
 		}
 		return nil, fmt.Errorf("credential not found: %s", explicitCredName)
@@ -127,45 +170,45 @@ func (cm *CredentialManager) GetCredentialForZone(hostname string, explicitCredN
 
 	// 2. Match by zone — check if hostname belongs to any credential's zones
 	// using suffix matching against user-configured zone names.
-	for i := range cm.credentials {
-		if matchZone(&cm.credentials[i], hostname) {
-			return &cm.credentials[i], nil
+	for i := range cs.credentials {
+		if matchZone(&cs.credentials[i], hostname) {
+			return &cs.credentials[i], nil
 		}
 	}
 
 	// 3. Use default
-	if cm.defaultCredential != nil {
-		return cm.defaultCredential, nil
+	if cs.defaultCredential != nil {
+		return cs.defaultCredential, nil
 	}
 
 	return nil, fmt.Errorf("no matching credential found for hostname: %s", hostname)
 }
 
-// GetTunnelCredential returns the tunnel credential for a given tunnel ID or name.
-func (cm *CredentialManager) GetTunnelCredential(tunnelIDOrName string) (*TunnelCredential, error) {
-	for i := range cm.tunnelCredentials {
-		if cm.tunnelCredentials[i].TunnelID == tunnelIDOrName ||
-			cm.tunnelCredentials[i].TunnelName == tunnelIDOrName {
-			return &cm.tunnelCredentials[i], nil
+// tunnelCredential returns the tunnel credential for a given tunnel ID or name.
+func (cs *credentialSet) tunnelCredential(tunnelIDOrName string) (*TunnelCredential, error) {
+	for i := range cs.tunnelCredentials {
+		if cs.tunnelCredentials[i].TunnelID == tunnelIDOrName ||
+			cs.tunnelCredentials[i].TunnelName == tunnelIDOrName {
+			return &cs.tunnelCredentials[i], nil
 		}
 	}
 	return nil, fmt.Errorf("tunnel credential not found: %s", tunnelIDOrName)
 }
 
-// GetClient returns a cached Cloudflare client for the given credential.
-func (cm *CredentialManager) GetClient(cred *Credential) (*Client, error) {
-	cm.clientsMu.RLock()
-	if client, ok := cm.clients[cred.Name]; ok {
-		cm.clientsMu.RUnlock()
+// client returns a cached Cloudflare client for the given credential.
+func (cs *credentialSet) client(cred *Credential) (*Client, error) {
+	cs.clientsMu.RLock()
+	if client, ok := cs.clients[cred.Name]; ok {
+		cs.clientsMu.RUnlock()
 		return client, nil
 	}
-	cm.clientsMu.RUnlock()
+	cs.clientsMu.RUnlock()
 
-	cm.clientsMu.Lock()
-	defer cm.clientsMu.Unlock()
+	cs.clientsMu.Lock()
+	defer cs.clientsMu.Unlock()
 
 	// Double-check after acquiring write lock
-	if client, ok := cm.clients[cred.Name]; ok {
+	if client, ok := cs.clients[cred.Name]; ok {
 		return client, nil
 	}
 
@@ -174,7 +217,8 @@ func (cm *CredentialManager) GetClient(cred *Credential) (*Client, error) {
 	}
 
 	client := NewClient(cred.APIToken)
-	cm.clients[cred.Name] = client
+	client.SetRateLimiter(cs.limiter)
+	cs.clients[cred.Name] = client
 	log.Debug().
 		Str("credential", cred.Name).
 		Msg("Created new Cloudflare client")
@@ -182,29 +226,20 @@ func (cm *CredentialManager) GetClient(cred *Credential) (*Client, error) {
 	return client, nil
 }
 
-// GetClientForHostname returns a client suitable for operations on the given hostname.
-func (cm *CredentialManager) GetClientForHostname(hostname string, explicitCredName string) (*Client, error) {
-	cred, err := cm.GetCredentialForZone(hostname, explicitCredName)
-	if err != nil {
-		return nil, err
-	}
-	return cm.GetClient(cred)
-}
-
-// GetTunnelClient returns a client configured for tunnel operations.
-func (cm *CredentialManager) GetTunnelClient(tunnelIDOrName string) (*Client, *TunnelCredential, error) {
-	tunnelCred, err := cm.GetTunnelCredential(tunnelIDOrName)
+// tunnelClient returns a client configured for tunnel operations.
+func (cs *credentialSet) tunnelClient(tunnelIDOrName string) (*Client, *TunnelCredential, error) {
+	tunnelCred, err := cs.tunnelCredential(tunnelIDOrName)
 	if err != nil {
 		// Fall back to default credential
-		if cm.defaultCredential != nil {
-			client, clientErr := cm.GetClient(cm.defaultCredential)
+		if cs.defaultCredential != nil {
+			client, clientErr := cs.client(cs.defaultCredential)
 			if clientErr != nil {
 				return nil, nil, clientErr
 			}
 			// Try to set account ID from first tunnel config
-			if len(cm.tunnelCredentials) > 0 {
-				client.SetAccountID(cm.tunnelCredentials[0].AccountID)
-				return client, &cm.tunnelCredentials[0], nil
+			if len(cs.tunnelCredentials) > 0 {
+				client.SetAccountID(cs.tunnelCredentials[0].AccountID)
+				return client, &cs.tunnelCredentials[0], nil
 			}
 			return client, nil, nil
 		}
@@ -214,19 +249,19 @@ func (cm *CredentialManager) GetTunnelClient(tunnelIDOrName string) (*Client, *T
 	// Use the tunnel's specified credential, or fall back to default
 	var cred *Credential
 	if tunnelCred.Credential != "" {
-		cred, err = cm.GetCredentialForZone("", tunnelCred.Credential)
+		cred, err = cs.credentialForZone("", tunnelCred.Credential)
 		if err != nil {
-			cred = cm.defaultCredential
+			cred = cs.defaultCredential
 		}
 	} else {
-		cred = cm.defaultCredential
+		cred = cs.defaultCredential
 	}
 
 	if cred == nil {
 		return nil, nil, fmt.Errorf("no credential available for tunnel %s", tunnelIDOrName)
 	}
 
-	client, err := cm.GetClient(cred)
+	client, err := cs.client(cred)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -235,20 +270,20 @@ func (cm *CredentialManager) GetTunnelClient(tunnelIDOrName string) (*Client, *T
 	return client, tunnelCred, nil
 }
 
-// ListTunnels returns all tunnel names.
-func (cm *CredentialManager) ListTunnels() []string {
-	names := make([]string, 0, len(cm.tunnelCredentials))
-	for _, cred := range cm.tunnelCredentials {
+// tunnelNames returns all tunnel names.
+func (cs *credentialSet) tunnelNames() []string {
+	names := make([]string, 0, len(cs.tunnelCredentials))
+	for _, cred := range cs.tunnelCredentials {
 		names = append(names, cred.TunnelName)
 	}
 	return names
 }
 
-// Validate validates all configured credentials.
-func (cm *CredentialManager) Validate(ctx context.Context) error {
-	for i := range cm.credentials {
-		cred := &cm.credentials[i]
-		client, err := cm.GetClient(cred)
+// validate validates all configured credentials.
+func (cs *credentialSet) validate(ctx context.Context) error {
+	for i := range cs.credentials {
+		cred := &cs.credentials[i]
+		client, err := cs.client(cred)
 		if err != nil {
 			return fmt.Errorf("failed to create client for %s: %w", cred.Name, err)
 		}
@@ -265,12 +300,58 @@ func (cm *CredentialManager) Validate(ctx context.Context) error {
 	return nil
 }
 
-// GetDefaultClient returns the default Cloudflare client.
-func (cm *CredentialManager) GetDefaultClient() (*Client, error) {
-	if cm.defaultCredential == nil {
+// defaultClient returns the default Cloudflare client.
+func (cs *credentialSet) defaultClient() (*Client, error) {
+	if cs.defaultCredential == nil {
 		return nil, fmt.Errorf("no default credential configured")
 	}
-	return cm.GetClient(cm.defaultCredential)
+	return cs.client(cs.defaultCredential)
+}
+
+// GetCredentialForZone returns the appropriate credential for a given hostname.
+// Priority: explicit label > zone matching > default
+func (cm *CredentialManager) GetCredentialForZone(hostname string, explicitCredName string) (*Credential, error) {
+	return cm.set.Load().credentialForZone(hostname, explicitCredName)
+}
+
+// GetTunnelCredential returns the tunnel credential for a given tunnel ID or name.
+func (cm *CredentialManager) GetTunnelCredential(tunnelIDOrName string) (*TunnelCredential, error) {
+	return cm.set.Load().tunnelCredential(tunnelIDOrName)
+}
+
+// GetClient returns a cached Cloudflare client for the given credential.
+func (cm *CredentialManager) GetClient(cred *Credential) (*Client, error) {
+	return cm.set.Load().client(cred)
+}
+
+// GetClientForHostname returns a client suitable for operations on the given hostname.
+func (cm *CredentialManager) GetClientForHostname(hostname string, explicitCredName string) (*Client, error) {
+	set := cm.set.Load()
+	cred, err := set.credentialForZone(hostname, explicitCredName)
+	if err != nil {
+		return nil, err
+	}
+	return set.client(cred)
+}
+
+// GetTunnelClient returns a client configured for tunnel operations.
+func (cm *CredentialManager) GetTunnelClient(tunnelIDOrName string) (*Client, *TunnelCredential, error) {
+	return cm.set.Load().tunnelClient(tunnelIDOrName)
+}
+
+// ListTunnels returns all tunnel names.
+func (cm *CredentialManager) ListTunnels() []string {
+	return cm.set.Load().tunnelNames()
+}
+
+// Validate validates all configured credentials.
+func (cm *CredentialManager) Validate(ctx context.Context) error {
+	return cm.set.Load().validate(ctx)
+}
+
+// GetDefaultClient returns the default Cloudflare client.
+func (cm *CredentialManager) GetDefaultClient() (*Client, error) {
+	return cm.set.Load().defaultClient()
 }
 
 // HealthCheck returns a cached health check result for the Cloudflare API.
@@ -49,7 +49,7 @@ type Config struct {
 	// Sync configuration (reconciliation + lifecycle)
 	Sync SyncConfig `mapstructure:"sync"`
 
-	// Db configuration (SQLite database)
+	// Db configuration (SQLite or PostgreSQL database)
 	Db DbConfig `mapstructure:"db"`
 
 	// Api configuration (HTTP API server)
@@ -61,9 +61,18 @@ type Config struct {
 	// Connect configuration (for agent mode)
 	Connect ConnectConfig `mapstructure:"connect"`
 
+	// Leader election configuration (for running multiple main instances)
+	Leader LeaderConfig `mapstructure:"leader"`
+
 	// Retry configuration (general retry policy for API calls and reconnection)
 	Retry RetryConfig `mapstructure:"retry"`
 
+	// Tracing configuration (OpenTelemetry)
+	Tracing TracingConfig `mapstructure:"tracing"`
+
+	// Notifications configuration (outbound webhooks and chat messages)
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+
 	// SkipCredentialValidation skips credential validation on startup
 	SkipCredentialValidation bool `mapstructure:"skip_credential_validation"`
 }
@@ -124,11 +133,15 @@ type CloudflareConfig struct {
 	// TunnelID is the default Cloudflare Tunnel ID
 	TunnelID string `mapstructure:"tunnel_id"`
 
-	// Credentials is additional named credentials (config file only)
+	// Credentials is additional named credentials
 	Credentials map[string]CredentialConfig `mapstructure:"credentials"`
 
-	// Tunnels is additional named tunnels (config file only)
+	// Tunnels is additional named tunnels
 	Tunnels map[string]TunnelConfig `mapstructure:"tunnels"`
+
+	// RateLimit is the maximum Cloudflare API requests per second,
+	// shared by all credentials (0 = unlimited)
+	RateLimit float64 `mapstructure:"rate_limit"`
 }
 
 // CredentialConfig holds a single Cloudflare credential.
@@ -163,20 +176,41 @@ type SyncConfig struct {
 
 	// OrphanTTL is the TTL for orphaned resources (0 = never auto cleanup)
 	OrphanTTL time.Duration `mapstructure:"orphan_ttl"`
+
+	// Workers is the number of hostnames each operator reconciles concurrently
+	Workers int `mapstructure:"workers"`
+
+	// Debounce is the window for coalescing container events into one
+	// incremental reconcile (0 = reconcile on every event)
+	Debounce time.Duration `mapstructure:"debounce"`
 }
 
 // DbConfig holds database configuration.
 type DbConfig struct {
-	// Path is the path to SQLite database
+	// Driver is the storage backend: sqlite (default) or postgres
+	Driver string `mapstructure:"driver"`
+
+	// Path is the path to SQLite database (driver sqlite)
 	Path string `mapstructure:"path"`
 
-	// Retention is how long to keep deleted records for audit.
-	// TODO: not yet implemented — CleanupDeletedResources() exists but is not scheduled.
+	// DSN is the PostgreSQL connection string (driver postgres)
+	DSN string `mapstructure:"dsn"`
+
+	// Retention is how long to keep deleted resources and resource history
+	// (audit events), 0 = forever
 	Retention time.Duration `mapstructure:"retention"`
 
-	// VacuumInterval is the interval for database vacuum.
-	// TODO: not yet implemented — Vacuum() exists but is not scheduled.
+	// CleanupInterval is how often retention cleanup runs (0 = disabled)
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+
+	// VacuumInterval is the interval for database vacuum (0 = disabled)
 	VacuumInterval time.Duration `mapstructure:"vacuum_interval"`
+
+	// CheckpointInterval is the interval for WAL checkpoints (0 = disabled)
+	CheckpointInterval time.Duration `mapstructure:"checkpoint_interval"`
+
+	// IntegrityCheckInterval is the interval for database integrity checks (0 = disabled)
+	IntegrityCheckInterval time.Duration `mapstructure:"integrity_check_interval"`
 }
 
 // ApiConfig holds HTTP API server configuration.
@@ -190,9 +224,106 @@ type ApiConfig struct {
 	// BasePath is the API base path
 	BasePath string `mapstructure:"base_path"`
 
-	// Token is the optional Bearer token for API authentication.
-	// If empty, no authentication is required.
+	// Token is the optional shared Bearer token for API authentication,
+	// granting every scope. Named, scoped tokens are managed with
+	// "labelgate token". If empty and no named token exists, no
+	// authentication is required.
 	Token string `mapstructure:"token"`
+
+	// Auth configures identity-based authentication (Cloudflare Access, OIDC)
+	Auth ApiAuthConfig `mapstructure:"auth"`
+
+	// TLS serves the API over HTTPS on Address
+	TLS ApiTLSConfig `mapstructure:"tls"`
+
+	// Socket is the path of a Unix socket to serve the API on, in addition
+	// to Address, for local tooling (empty disables it). Set Address to an
+	// empty string to only serve the socket.
+	Socket string `mapstructure:"socket"`
+
+	// SocketMode is the octal file mode of Socket
+	SocketMode string `mapstructure:"socket_mode"`
+
+	// SocketGroup is the group name or ID owning Socket (optional)
+	SocketGroup string `mapstructure:"socket_group"`
+}
+
+// ApiTLSConfig holds HTTPS configuration for the API server.
+type ApiTLSConfig struct {
+	// Cert is the path to the server certificate (PEM). It is reloaded when
+	// the file changes, so renewed certificates need no restart.
+	Cert string `mapstructure:"cert"`
+
+	// Key is the path to the server private key (PEM)
+	Key string `mapstructure:"key"`
+
+	// ClientCA is the path to CA certificates (PEM) for client certificate
+	// authentication. Verified clients are identified by the certificate's
+	// email address or common name and granted scopes by api.auth.roles.
+	ClientCA string `mapstructure:"client_ca"`
+
+	// RequireClientCert rejects connections without a valid client certificate
+	RequireClientCert bool `mapstructure:"require_client_cert"`
+}
+
+// ApiAuthConfig holds identity-based API authentication configuration.
+type ApiAuthConfig struct {
+	// Access validates the Cf-Access-Jwt-Assertion header set by Cloudflare Access
+	Access AccessAuthConfig `mapstructure:"access"`
+
+	// OIDC enables a browser login flow with session cookies
+	OIDC OIDCAuthConfig `mapstructure:"oidc"`
+
+	// Roles maps a scope (read, write, agents-admin, admin) to the identities
+	// granted it: an email, "@domain" for a whole domain, "group:<name>" for
+	// an OIDC group, or "*" for any authenticated identity
+	Roles map[string][]string `mapstructure:"roles"`
+}
+
+// AccessAuthConfig holds Cloudflare Access JWT validation configuration.
+type AccessAuthConfig struct {
+	// Enabled controls whether Access JWTs are accepted
+	Enabled bool `mapstructure:"enabled"`
+
+	// TeamDomain is the Zero Trust team domain, e.g. myteam.cloudflareaccess.com
+	TeamDomain string `mapstructure:"team_domain"`
+
+	// Audience is the Application Audience (AUD) tag of the Access application
+	Audience string `mapstructure:"audience"`
+
+	// JWKSFile is a local JSON Web Key Set used instead of the team's
+	// certs endpoint (for testing)
+	JWKSFile string `mapstructure:"jwks_file"`
+}
+
+// OIDCAuthConfig holds OpenID Connect login configuration.
+type OIDCAuthConfig struct {
+	// Enabled controls whether the OIDC login flow is served
+	Enabled bool `mapstructure:"enabled"`
+
+	// Issuer is the provider's issuer URL (used for discovery)
+	Issuer string `mapstructure:"issuer"`
+
+	// ClientID and ClientSecret identify labelgate at the provider
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+
+	// RedirectURL is the callback URL registered at the provider,
+	// e.g. https://labelgate.example.com/api/auth/callback
+	RedirectURL string `mapstructure:"redirect_url"`
+
+	// Scopes are the OAuth scopes requested
+	Scopes []string `mapstructure:"scopes"`
+
+	// GroupsClaim is the ID token claim holding the user's groups
+	GroupsClaim string `mapstructure:"groups_claim"`
+
+	// SessionSecret signs session cookies. If empty, a random secret is
+	// generated, so sessions end on restart and aren't shared by replicas
+	SessionSecret string `mapstructure:"session_secret"`
+
+	// SessionTTL is how long a login lasts
+	SessionTTL time.Duration `mapstructure:"session_ttl"`
 }
 
 // AgentServerConfig holds agent server configuration (main instance).
@@ -251,6 +382,95 @@ type ConnectConfig struct {
 	TLS TLSConfig `mapstructure:"tls"`
 }
 
+// LeaderConfig holds leader election configuration for high availability.
+// Only the leader reconciles and runs the agent server; followers serve the
+// read-only API and take over when the leader's lease expires.
+type LeaderConfig struct {
+	// Enabled controls whether leader election is enabled
+	Enabled bool `mapstructure:"enabled"`
+
+	// Backend is the lease backend (sqlite)
+	Backend string `mapstructure:"backend"`
+
+	// Path is the lease database path on a local disk of the host running
+	// all instances (default: db.path)
+	Path string `mapstructure:"path"`
+
+	// Identity is this instance's unique identity (default: hostname-pid)
+	Identity string `mapstructure:"identity"`
+
+	// LeaseDuration is how long a lease stays valid without renewal
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+
+	// RenewInterval is how often the lease is acquired or renewed
+	RenewInterval time.Duration `mapstructure:"renew_interval"`
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration.
+type TracingConfig struct {
+	// Enabled controls whether spans are exported
+	Enabled bool `mapstructure:"enabled"`
+
+	// Endpoint is the OTLP/HTTP traces endpoint URL
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Headers are extra HTTP headers sent to the endpoint (e.g. authentication)
+	Headers map[string]string `mapstructure:"headers"`
+
+	// ServiceName is the service.name resource attribute
+	ServiceName string `mapstructure:"service_name"`
+
+	// SampleRatio is the fraction of traces sampled (0-1)
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// NotificationsConfig holds outbound notification configuration.
+type NotificationsConfig struct {
+	// Enabled controls whether notifications are sent
+	Enabled bool `mapstructure:"enabled"`
+
+	// Sinks are the named notification targets (config file only)
+	Sinks map[string]NotificationSinkConfig `mapstructure:"sinks"`
+
+	// RetryAttempts is the maximum number of delivery attempts per notification
+	RetryAttempts int `mapstructure:"retry_attempts"`
+
+	// RetryDelay is the delay before the first retry, doubled on each attempt
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+
+	// QueueSize is the number of pending deliveries kept before dropping
+	QueueSize int `mapstructure:"queue_size"`
+
+	// Timeout is the HTTP timeout for a single delivery
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// NotificationSinkConfig holds a single notification target.
+type NotificationSinkConfig struct {
+	// Type is the payload format: webhook, slack, discord, ntfy or gotify
+	Type string `mapstructure:"type"`
+
+	// URL is the target URL (webhook URL, ntfy topic URL or Gotify server URL)
+	URL string `mapstructure:"url"`
+
+	// Secret signs webhook payloads with HMAC-SHA256 (webhook only)
+	Secret string `mapstructure:"secret"`
+
+	// Token is the ntfy access token or Gotify application token
+	Token string `mapstructure:"token"`
+
+	// Events are the event types ("resource.error") or categories ("agent")
+	// sent to this sink (default: resource.error, agent.disconnected,
+	// orphan.cleanup, orphan.cleanup_failed, drift.detected)
+	Events []string `mapstructure:"events"`
+
+	// Priority is the ntfy (1-5) or Gotify (0-10) message priority (0 = server default)
+	Priority int `mapstructure:"priority"`
+
+	// Headers are extra HTTP headers sent with every delivery
+	Headers map[string]string `mapstructure:"headers"`
+}
+
 // RetryConfig holds retry configuration for API calls and reconnection.
 type RetryConfig struct {
 	// Attempts is the maximum number of retry attempts
@@ -281,21 +501,36 @@ func DefaultConfig() *Config {
 		Cloudflare: CloudflareConfig{
 			Credentials: make(map[string]CredentialConfig),
 			Tunnels:     make(map[string]TunnelConfig),
+			RateLimit:   4, // Cloudflare allows 1200 requests per 5 minutes
 		},
 		Sync: SyncConfig{
 			Interval:    time.Hour,
 			RemoveDelay: 30 * time.Minute,
 			OrphanTTL:   0,
+			Workers:     8,
+			Debounce:    2 * time.Second,
 		},
 		Db: DbConfig{
-			Path:           "/app/config/labelgate.db",
-			Retention:      7 * 24 * time.Hour,
-			VacuumInterval: 24 * time.Hour,
+			Driver:                 "sqlite",
+			Path:                   "/app/config/labelgate.db",
+			Retention:              7 * 24 * time.Hour,
+			CleanupInterval:        time.Hour,
+			VacuumInterval:         24 * time.Hour,
+			CheckpointInterval:     time.Hour,
+			IntegrityCheckInterval: 24 * time.Hour,
 		},
 		Api: ApiConfig{
 			Enabled:  true,
-			Address:  ":8080",
-			BasePath: "/api",
+			Address:    ":8080",
+			BasePath:   "/api",
+			SocketMode: "0660",
+			Auth: ApiAuthConfig{
+				OIDC: OIDCAuthConfig{
+					Scopes:      []string{"openid", "email", "profile"},
+					GroupsClaim: "groups",
+					SessionTTL:  12 * time.Hour,
+				},
+			},
 		},
 		Agent: AgentServerConfig{
 			Enabled: false,
@@ -305,11 +540,31 @@ func DefaultConfig() *Config {
 			Mode:              ConnectOutbound,
 			HeartbeatInterval: 30 * time.Second,
 		},
+		Leader: LeaderConfig{
+			Enabled:       false,
+			Backend:       "sqlite",
+			LeaseDuration: 15 * time.Second,
+			RenewInterval: 5 * time.Second,
+		},
 		Retry: RetryConfig{
 			Attempts: 3,
 			Delay:    time.Second,
 			MaxDelay: 30 * time.Second,
 			Backoff:  2,
 		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Endpoint:    "http://localhost:4318/v1/traces",
+			ServiceName: "labelgate",
+			SampleRatio: 1,
+		},
+		Notifications: NotificationsConfig{
+			Enabled:       false,
+			Sinks:         make(map[string]NotificationSinkConfig),
+			RetryAttempts: 5,
+			RetryDelay:    10 * time.Second,
+			QueueSize:     100,
+			Timeout:       10 * time.Second,
+		},
 	}
 }
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"cmp"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Named entry environment variables, e.g.
+// LABELGATE_CLOUDFLARE_CREDENTIALS_<NAME>_API_TOKEN.
+var (
+	credentialEnvPrefix = EnvPrefix + "_CLOUDFLARE_CREDENTIALS_"
+	tunnelEnvPrefix     = EnvPrefix + "_CLOUDFLARE_TUNNELS_"
+	agentEnvPrefix      = EnvPrefix + "_AGENT_AGENTS_"
+
+	credentialEnvFields = []string{"API_TOKEN", "API_TOKEN_FILE", "ZONES"}
+	tunnelEnvFields     = []string{"ACCOUNT_ID", "TUNNEL_ID", "CREDENTIAL"}
+	agentEnvFields      = []string{"TOKEN", "TOKEN_FILE", "DEFAULT_TUNNEL", "CONNECT_TO"}
+)
+
+// entryEnv is an environment variable setting one field of a named entry.
+type entryEnv struct {
+	field string // e.g. API_TOKEN
+	value string
+}
+
+// namedEnvs returns the non-empty environment variables starting with
+// prefix, by entry name as written in the variable (e.g. PROD). The entry
+// name is what precedes the field suffix, so it may contain underscores.
+func namedEnvs(prefix string, fields []string) (map[string][]entryEnv, error) {
+	// Longest first, so a field never matches the end of a longer one
+	fields = slices.SortedFunc(slices.Values(fields), func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	entries := make(map[string][]entryEnv)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix) || value == "" {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+
+		i := slices.IndexFunc(fields, func(field string) bool { return rest == field || strings.HasSuffix(rest, "_"+field) })
+		if i < 0 {
+			return nil, &ValidationError{Field: name, Message: "unknown setting (valid suffixes: _" + strings.Join(fields, ", _") + ")"}
+		}
+		entry := strings.TrimSuffix(strings.TrimSuffix(rest, fields[i]), "_")
+		if entry == "" {
+			return nil, &ValidationError{Field: name, Message: "missing the entry name, e.g. " + prefix + "<NAME>_" + fields[i]}
+		}
+		entries[entry] = append(entries[entry], entryEnv{field: fields[i], value: value})
+	}
+	return entries, nil
+}
+
+// entryName returns the key of the entry in m that an environment variable
+// refers to as entry, or its lowercase form for a new entry. Config file keys
+// are lowercase and "-" is written as "_" in variables.
+func entryName[V any](m map[string]V, entry string) string {
+	for name := range m {
+		if strings.ToUpper(strings.ReplaceAll(name, "-", "_")) == entry {
+			return name
+		}
+	}
+	return strings.ToLower(entry)
+}
+
+// hasField reports whether envs set field.
+func hasField(envs []entryEnv, field string) bool {
+	return slices.ContainsFunc(envs, func(e entryEnv) bool { return e.field == field })
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// applyNamedEnvOverrides adds or updates named credentials, tunnels and
+// agents from their environment variables. Secrets given as _FILE variables
+// are read later by resolveSecrets.
+func applyNamedEnvOverrides(cfg *Config) error {
+	credentials, err := namedEnvs(credentialEnvPrefix, credentialEnvFields)
+	if err != nil {
+		return err
+	}
+	for _, entry := range slices.Sorted(maps.Keys(credentials)) {
+		envs := credentials[entry]
+		name := entryName(cfg.Cloudflare.Credentials, entry)
+		cred := cfg.Cloudflare.Credentials[name]
+		for _, e := range envs {
+			switch e.field {
+			case "API_TOKEN":
+				cred.APIToken = e.value
+			case "ZONES":
+				cred.Zones = splitList(e.value)
+			}
+		}
+		if cred.APIToken == "" && !hasField(envs, "API_TOKEN_FILE") {
+			return &ValidationError{Field: credentialEnvPrefix + entry + "_API_TOKEN", Message: "api_token is required for credential " + name}
+		}
+		if cfg.Cloudflare.Credentials == nil {
+			cfg.Cloudflare.Credentials = make(map[string]CredentialConfig)
+		}
+		cfg.Cloudflare.Credentials[name] = cred
+	}
+
+	tunnels, err := namedEnvs(tunnelEnvPrefix, tunnelEnvFields)
+	if err != nil {
+		return err
+	}
+	for _, entry := range slices.Sorted(maps.Keys(tunnels)) {
+		envs := tunnels[entry]
+		name := entryName(cfg.Cloudflare.Tunnels, entry)
+		tunnel := cfg.Cloudflare.Tunnels[name]
+		for _, e := range envs {
+			switch e.field {
+			case "ACCOUNT_ID":
+				tunnel.AccountID = e.value
+			case "TUNNEL_ID":
+				tunnel.TunnelID = e.value
+			case "CREDENTIAL":
+				tunnel.Credential = e.value
+			}
+		}
+		if tunnel.TunnelID == "" {
+			return &ValidationError{Field: tunnelEnvPrefix + entry + "_TUNNEL_ID", Message: "tunnel_id is required for tunnel " + name}
+		}
+		if tunnel.AccountID == "" {
+			return &ValidationError{Field: tunnelEnvPrefix + entry + "_ACCOUNT_ID", Message: "account_id is required for tunnel " + name}
+		}
+		if cfg.Cloudflare.Tunnels == nil {
+			cfg.Cloudflare.Tunnels = make(map[string]TunnelConfig)
+		}
+		cfg.Cloudflare.Tunnels[name] = tunnel
+	}
+
+	agents, err := namedEnvs(agentEnvPrefix, agentEnvFields)
+	if err != nil {
+		return err
+	}
+	for _, entry := range slices.Sorted(maps.Keys(agents)) {
+		envs := agents[entry]
+		id := entryName(cfg.Agent.Agents, entry)
+		agent := cfg.Agent.Agents[id]
+		for _, e := range envs {
+			switch e.field {
+			case "TOKEN":
+				agent.Token = e.value
+			case "DEFAULT_TUNNEL":
+				agent.DefaultTunnel = e.value
+			case "CONNECT_TO":
+				agent.ConnectTo = e.value
+			}
+		}
+		if agent.Token == "" && !hasField(envs, "TOKEN_FILE") {
+			return &ValidationError{Field: agentEnvPrefix + entry + "_TOKEN", Message: "token is required for agent " + id}
+		}
+		if cfg.Agent.Agents == nil {
+			cfg.Agent.Agents = make(map[string]AgentEntryConfig)
+		}
+		cfg.Agent.Agents[id] = agent
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestApplyNamedEnvOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		check     func(t *testing.T, cfg *Config)
+		wantField string // variable named by the expected ValidationError
+	}{
+		{
+			name: "name with underscores",
+			env: map[string]string{
+				"LABELGATE_CLOUDFLARE_CREDENTIALS_EU_PROD_API_TOKEN": "token",
+				"LABELGATE_CLOUDFLARE_TUNNELS_HOME_LAB_TUNNEL_ID":    "tunnel",
+				"LABELGATE_CLOUDFLARE_TUNNELS_HOME_LAB_ACCOUNT_ID":   "account",
+				"LABELGATE_CLOUDFLARE_TUNNELS_HOME_LAB_CREDENTIAL":   "eu_prod",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Cloudflare.Credentials["eu_prod"].APIToken != "token" {
+					t.Errorf("expected credential eu_prod, got %+v", cfg.Cloudflare.Credentials)
+				}
+				if tunnel := cfg.Cloudflare.Tunnels["home_lab"]; tunnel != (TunnelConfig{AccountID: "account", TunnelID: "tunnel", Credential: "eu_prod"}) {
+					t.Errorf("expected tunnel home_lab, got %+v", cfg.Cloudflare.Tunnels)
+				}
+			},
+		},
+		{
+			name: "name ending in a field name",
+			env: map[string]string{
+				"LABELGATE_CLOUDFLARE_CREDENTIALS_MY_API_API_TOKEN": "token",
+				"LABELGATE_AGENT_AGENTS_EDGE_TOKEN_TOKEN":           "agent-token",
+				"LABELGATE_AGENT_AGENTS_EDGE_TOKEN_FILE":            "/run/secrets/edge",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Cloudflare.Credentials["my_api"].APIToken != "token" {
+					t.Errorf("expected credential my_api, got %+v", cfg.Cloudflare.Credentials)
+				}
+				if cfg.Agent.Agents["edge_token"].Token != "agent-token" {
+					t.Errorf("expected agent edge_token, got %+v", cfg.Agent.Agents)
+				}
+				if _, ok := cfg.Agent.Agents["edge"]; !ok {
+					t.Errorf("expected agent edge with a token file, got %+v", cfg.Agent.Agents)
+				}
+			},
+		},
+		{
+			name: "comma-separated zones",
+			env: map[string]string{
+				"LABELGATE_CLOUDFLARE_CREDENTIALS_PROD_API_TOKEN": "token",
+				"LABELGATE_CLOUDFLARE_CREDENTIALS_PROD_ZONES":     " example.com,example.org ,, example.net,",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				want := []string{"example.com", "example.org", "example.net"}
+				if got := cfg.Cloudflare.Credentials["prod"].Zones; !slices.Equal(got, want) {
+					t.Errorf("got zones %q, want %q", got, want)
+				}
+			},
+		},
+		{
+			name: "secret from a _FILE variable",
+			env:  map[string]string{"LABELGATE_CLOUDFLARE_CREDENTIALS_PROD_API_TOKEN_FILE": "/run/secrets/cf"},
+			check: func(t *testing.T, cfg *Config) {
+				if _, ok := cfg.Cloudflare.Credentials["prod"]; !ok {
+					t.Errorf("expected credential prod, got %+v", cfg.Cloudflare.Credentials)
+				}
+			},
+		},
+		{
+			name:      "credential without a token",
+			env:       map[string]string{"LABELGATE_CLOUDFLARE_CREDENTIALS_EU_PROD_ZONES": "example.eu"},
+			wantField: "LABELGATE_CLOUDFLARE_CREDENTIALS_EU_PROD_API_TOKEN",
+		},
+		{
+			name:      "tunnel without a tunnel ID",
+			env:       map[string]string{"LABELGATE_CLOUDFLARE_TUNNELS_HOME_ACCOUNT_ID": "account"},
+			wantField: "LABELGATE_CLOUDFLARE_TUNNELS_HOME_TUNNEL_ID",
+		},
+		{
+			name:      "tunnel without an account ID",
+			env:       map[string]string{"LABELGATE_CLOUDFLARE_TUNNELS_HOME_TUNNEL_ID": "tunnel"},
+			wantField: "LABELGATE_CLOUDFLARE_TUNNELS_HOME_ACCOUNT_ID",
+		},
+		{
+			name:      "agent without a token",
+			env:       map[string]string{"LABELGATE_AGENT_AGENTS_EDGE_1_DEFAULT_TUNNEL": "home"},
+			wantField: "LABELGATE_AGENT_AGENTS_EDGE_1_TOKEN",
+		},
+		{
+			name:      "unknown setting",
+			env:       map[string]string{"LABELGATE_CLOUDFLARE_TUNNELS_HOME_SECRET": "x"},
+			wantField: "LABELGATE_CLOUDFLARE_TUNNELS_HOME_SECRET",
+		},
+		{
+			name:      "missing entry name",
+			env:       map[string]string{"LABELGATE_CLOUDFLARE_CREDENTIALS_API_TOKEN": "token"},
+			wantField: "LABELGATE_CLOUDFLARE_CREDENTIALS_API_TOKEN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg := DefaultConfig()
+
+			err := applyNamedEnvOverrides(cfg)
+			if tt.wantField != "" {
+				var verr *ValidationError
+				if !errors.As(err, &verr) || verr.Field != tt.wantField {
+					t.Fatalf("expected a validation error for %s, got %v", tt.wantField, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoad_EnvOverridesMergeWithConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labelgate.yaml")
+	if err := os.WriteFile(path, []byte(`
+cloudflare:
+  api_token: file-token
+  credentials:
+    eu-prod:
+      api_token: file-eu-token
+      zones: [example.eu]
+  tunnels:
+    home:
+      account_id: file-account
+      tunnel_id: file-tunnel
+      credential: eu-prod
+agent:
+  agents:
+    edge-1:
+      token: file-agent-token
+      default_tunnel: home
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing entries are matched with "-" written as "_"
+	t.Setenv("LABELGATE_CLOUDFLARE_CREDENTIALS_EU_PROD_ZONES", "example.eu,example.de")
+	t.Setenv("LABELGATE_CLOUDFLARE_CREDENTIALS_US_EAST_API_TOKEN", "env-us-token")
+	t.Setenv("LABELGATE_CLOUDFLARE_TUNNELS_HOME_TUNNEL_ID", "env-tunnel")
+	t.Setenv("LABELGATE_AGENT_AGENTS_EDGE_1_CONNECT_TO", "ws://edge-1:8081/ws")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cred := cfg.Cloudflare.Credentials["eu-prod"]; cred.APIToken != "file-eu-token" || !slices.Equal(cred.Zones, []string{"example.eu", "example.de"}) {
+		t.Errorf("expected eu-prod zones from env and token from file, got %+v", cred)
+	}
+	if cred := cfg.Cloudflare.Credentials["us_east"]; cred.APIToken != "env-us-token" {
+		t.Errorf("expected credential us_east from env, got %+v", cfg.Cloudflare.Credentials)
+	}
+	if _, ok := cfg.Cloudflare.Credentials["eu_prod"]; ok {
+		t.Error("expected env variables to update eu-prod, not add eu_prod")
+	}
+	if tunnel := cfg.Cloudflare.Tunnels["home"]; tunnel != (TunnelConfig{AccountID: "file-account", TunnelID: "env-tunnel", Credential: "eu-prod"}) {
+		t.Errorf("expected home tunnel ID from env and the rest from file, got %+v", tunnel)
+	}
+	if agent := cfg.Agent.Agents["edge-1"]; agent != (AgentEntryConfig{Token: "file-agent-token", DefaultTunnel: "home", ConnectTo: "ws://edge-1:8081/ws"}) {
+		t.Errorf("expected edge-1 connect_to from env and the rest from file, got %+v", agent)
+	}
+	if cfg.Cloudflare.APIToken != "file-token" {
+		t.Errorf("expected the default token from file, got %q", cfg.Cloudflare.APIToken)
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -42,7 +46,14 @@ func Load(configPath string) (*Config, error) {
 
 	// Apply environment variable overrides for nested configs
 	// that viper cannot auto-resolve (e.g. cloudflare flat fields + map children).
-	applyEnvOverrides(cfg)
+	if err := applyEnvOverrides(cfg); err != nil {
+		return nil, err
+	}
+
+	// Read secrets from _FILE variables and file:/env: references
+	if err := resolveSecrets(cfg); err != nil {
+		return nil, err
+	}
 
 	// Validate configuration
 	if err := validate(cfg); err != nil {
@@ -76,22 +87,50 @@ func setDefaults(v *viper.Viper, cfg *Config) {
 	v.SetDefault("cloudflare.api_token", cfg.Cloudflare.APIToken)
 	v.SetDefault("cloudflare.account_id", cfg.Cloudflare.AccountID)
 	v.SetDefault("cloudflare.tunnel_id", cfg.Cloudflare.TunnelID)
+	v.SetDefault("cloudflare.rate_limit", cfg.Cloudflare.RateLimit)
 
 	// Sync
 	v.SetDefault("sync.interval", cfg.Sync.Interval)
 	v.SetDefault("sync.remove_delay", cfg.Sync.RemoveDelay)
 	v.SetDefault("sync.orphan_ttl", cfg.Sync.OrphanTTL)
+	v.SetDefault("sync.workers", cfg.Sync.Workers)
+	v.SetDefault("sync.debounce", cfg.Sync.Debounce)
 
 	// Database
+	v.SetDefault("db.driver", cfg.Db.Driver)
 	v.SetDefault("db.path", cfg.Db.Path)
+	v.SetDefault("db.dsn", cfg.Db.DSN)
 	v.SetDefault("db.retention", cfg.Db.Retention)
+	v.SetDefault("db.cleanup_interval", cfg.Db.CleanupInterval)
 	v.SetDefault("db.vacuum_interval", cfg.Db.VacuumInterval)
+	v.SetDefault("db.checkpoint_interval", cfg.Db.CheckpointInterval)
+	v.SetDefault("db.integrity_check_interval", cfg.Db.IntegrityCheckInterval)
 
 	// API server
 	v.SetDefault("api.enabled", cfg.Api.Enabled)
 	v.SetDefault("api.address", cfg.Api.Address)
 	v.SetDefault("api.base_path", cfg.Api.BasePath)
 	v.SetDefault("api.token", cfg.Api.Token)
+	v.SetDefault("api.auth.access.enabled", cfg.Api.Auth.Access.Enabled)
+	v.SetDefault("api.auth.access.team_domain", cfg.Api.Auth.Access.TeamDomain)
+	v.SetDefault("api.auth.access.audience", cfg.Api.Auth.Access.Audience)
+	v.SetDefault("api.auth.access.jwks_file", cfg.Api.Auth.Access.JWKSFile)
+	v.SetDefault("api.auth.oidc.enabled", cfg.Api.Auth.OIDC.Enabled)
+	v.SetDefault("api.auth.oidc.issuer", cfg.Api.Auth.OIDC.Issuer)
+	v.SetDefault("api.auth.oidc.client_id", cfg.Api.Auth.OIDC.ClientID)
+	v.SetDefault("api.auth.oidc.client_secret", cfg.Api.Auth.OIDC.ClientSecret)
+	v.SetDefault("api.auth.oidc.redirect_url", cfg.Api.Auth.OIDC.RedirectURL)
+	v.SetDefault("api.auth.oidc.scopes", cfg.Api.Auth.OIDC.Scopes)
+	v.SetDefault("api.auth.oidc.groups_claim", cfg.Api.Auth.OIDC.GroupsClaim)
+	v.SetDefault("api.auth.oidc.session_secret", cfg.Api.Auth.OIDC.SessionSecret)
+	v.SetDefault("api.auth.oidc.session_ttl", cfg.Api.Auth.OIDC.SessionTTL)
+	v.SetDefault("api.tls.cert", cfg.Api.TLS.Cert)
+	v.SetDefault("api.tls.key", cfg.Api.TLS.Key)
+	v.SetDefault("api.tls.client_ca", cfg.Api.TLS.ClientCA)
+	v.SetDefault("api.tls.require_client_cert", cfg.Api.TLS.RequireClientCert)
+	v.SetDefault("api.socket", cfg.Api.Socket)
+	v.SetDefault("api.socket_mode", cfg.Api.SocketMode)
+	v.SetDefault("api.socket_group", cfg.Api.SocketGroup)
 
 	// Agent server (main instance)
 	v.SetDefault("agent.enabled", cfg.Agent.Enabled)
@@ -112,20 +151,41 @@ func setDefaults(v *viper.Viper, cfg *Config) {
 	v.SetDefault("connect.tls.cert", cfg.Connect.TLS.Cert)
 	v.SetDefault("connect.tls.key", cfg.Connect.TLS.Key)
 
+	// Leader election
+	v.SetDefault("leader.enabled", cfg.Leader.Enabled)
+	v.SetDefault("leader.backend", cfg.Leader.Backend)
+	v.SetDefault("leader.path", cfg.Leader.Path)
+	v.SetDefault("leader.identity", cfg.Leader.Identity)
+	v.SetDefault("leader.lease_duration", cfg.Leader.LeaseDuration)
+	v.SetDefault("leader.renew_interval", cfg.Leader.RenewInterval)
+
 	// Retry
 	v.SetDefault("retry.attempts", cfg.Retry.Attempts)
 	v.SetDefault("retry.delay", cfg.Retry.Delay)
 	v.SetDefault("retry.max_delay", cfg.Retry.MaxDelay)
 	v.SetDefault("retry.backoff", cfg.Retry.Backoff)
 
+	// Tracing
+	v.SetDefault("tracing.enabled", cfg.Tracing.Enabled)
+	v.SetDefault("tracing.endpoint", cfg.Tracing.Endpoint)
+	v.SetDefault("tracing.service_name", cfg.Tracing.ServiceName)
+	v.SetDefault("tracing.sample_ratio", cfg.Tracing.SampleRatio)
+
+	// Notifications
+	v.SetDefault("notifications.enabled", cfg.Notifications.Enabled)
+	v.SetDefault("notifications.retry_attempts", cfg.Notifications.RetryAttempts)
+	v.SetDefault("notifications.retry_delay", cfg.Notifications.RetryDelay)
+	v.SetDefault("notifications.queue_size", cfg.Notifications.QueueSize)
+	v.SetDefault("notifications.timeout", cfg.Notifications.Timeout)
+
 	// Skip credential validation
 	v.SetDefault("skip_credential_validation", cfg.SkipCredentialValidation)
 }
 
 // applyEnvOverrides applies environment variable overrides for configs
 // that viper doesn't automatically resolve well (mainly cloudflare flat fields
-// coexisting with map-type children).
-func applyEnvOverrides(cfg *Config) {
+// coexisting with map-type children, and the map entries themselves).
+func applyEnvOverrides(cfg *Config) error {
 	// Cloudflare default credential / tunnel from ENV.
 	// These are needed because viper has trouble with flat fields alongside map children.
 	if token := os.Getenv(EnvPrefix + "_CLOUDFLARE_API_TOKEN"); token != "" {
@@ -137,6 +197,9 @@ func applyEnvOverrides(cfg *Config) {
 	if tunnelID := os.Getenv(EnvPrefix + "_CLOUDFLARE_TUNNEL_ID"); tunnelID != "" {
 		cfg.Cloudflare.TunnelID = tunnelID
 	}
+
+	// Named credentials, tunnels and agents
+	return applyNamedEnvOverrides(cfg)
 }
 
 // validate validates the configuration.
@@ -178,6 +241,82 @@ func validate(cfg *Config) error {
 		}
 	}
 
+	// Polling drives the periodic reconcile
+	if cfg.Docker.PollInterval <= 0 {
+		return &ValidationError{Field: "docker.poll_interval", Message: "must be positive"}
+	}
+
+	// Concurrency and rate limiting
+	if cfg.Sync.Workers < 1 {
+		cfg.Sync.Workers = 1
+	}
+	if cfg.Cloudflare.RateLimit < 0 {
+		return &ValidationError{Field: "cloudflare.rate_limit", Message: "rate_limit must not be negative"}
+	}
+
+	// Database
+	switch cfg.Db.Driver {
+	case "sqlite":
+	case "postgres":
+		if cfg.Db.DSN == "" {
+			return &ValidationError{Field: "db.dsn", Message: "dsn is required for the postgres driver"}
+		}
+	default:
+		return &ValidationError{Field: "db.driver", Message: "unsupported driver: " + cfg.Db.Driver + " (supported: sqlite, postgres)"}
+	}
+
+	// Database maintenance
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"db.retention", cfg.Db.Retention},
+		{"db.cleanup_interval", cfg.Db.CleanupInterval},
+		{"db.vacuum_interval", cfg.Db.VacuumInterval},
+		{"db.checkpoint_interval", cfg.Db.CheckpointInterval},
+		{"db.integrity_check_interval", cfg.Db.IntegrityCheckInterval},
+	} {
+		if d.value < 0 {
+			return &ValidationError{Field: d.field, Message: "duration must not be negative"}
+		}
+	}
+
+	// Tracing
+	if cfg.Tracing.Enabled {
+		if cfg.Tracing.Endpoint == "" {
+			return &ValidationError{Field: "tracing.endpoint", Message: "endpoint is required when tracing is enabled"}
+		}
+		if u, err := url.Parse(cfg.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ValidationError{Field: "tracing.endpoint", Message: "must be an http:// or https:// URL: " + cfg.Tracing.Endpoint}
+		}
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			return &ValidationError{Field: "tracing.sample_ratio", Message: "must be between 0 and 1"}
+		}
+	}
+
+	// Notifications
+	if err := validateNotifications(&cfg.Notifications); err != nil {
+		return err
+	}
+
+	// API server
+	if err := validateApi(&cfg.Api); err != nil {
+		return err
+	}
+
+	// Leader election
+	if cfg.Leader.Enabled {
+		if cfg.Leader.Backend != "sqlite" {
+			return &ValidationError{Field: "leader.backend", Message: "unsupported backend: " + cfg.Leader.Backend + " (supported: sqlite)"}
+		}
+		if cfg.Leader.RenewInterval >= cfg.Leader.LeaseDuration {
+			return &ValidationError{Field: "leader.renew_interval", Message: "renew_interval must be shorter than lease_duration"}
+		}
+		if cfg.Leader.Path == "" {
+			cfg.Leader.Path = cfg.Db.Path
+		}
+	}
+
 	return nil
 }
 
@@ -226,9 +365,111 @@ func resolveConfigFile(v *viper.Viper, configPath string) error {
 	return nil
 }
 
-// Reload reloads configuration from file.
-// Uses the same resolution logic as Load with no explicit CLI path,
-// so it will re-read from ENV or default search paths.
-func Reload() (*Config, error) {
-	return Load("")
+// Reload reloads configuration from configPath (the CLI flag, may be
+// empty) and the environment, using the same resolution as Load. A config
+// that fails validation returns an error, so the caller can keep running
+// with its current configuration.
+func Reload(configPath string) (*Config, error) {
+	return Load(configPath)
+}
+
+// apiScopes are the scopes that can be granted in api.auth.roles.
+var apiScopes = []string{"read", "write", "agents-admin", "admin"}
+
+func validateApi(cfg *ApiConfig) error {
+	if cfg.Enabled && cfg.Address == "" && cfg.Socket == "" {
+		return &ValidationError{Field: "api.address", Message: "address or socket is required when the API is enabled"}
+	}
+
+	if (cfg.TLS.Cert == "") != (cfg.TLS.Key == "") {
+		return &ValidationError{Field: "api.tls", Message: "cert and key must be set together"}
+	}
+	if cfg.TLS.ClientCA != "" && cfg.TLS.Cert == "" {
+		return &ValidationError{Field: "api.tls.client_ca", Message: "client certificates require cert and key"}
+	}
+	if cfg.TLS.RequireClientCert && cfg.TLS.ClientCA == "" {
+		return &ValidationError{Field: "api.tls.require_client_cert", Message: "client_ca is required to verify client certificates"}
+	}
+
+	if cfg.Socket != "" {
+		if mode, err := strconv.ParseUint(cfg.SocketMode, 8, 32); err != nil || mode > 0o777 {
+			return &ValidationError{Field: "api.socket_mode", Message: "must be an octal file mode such as 0660: " + cfg.SocketMode}
+		}
+	}
+
+	return validateApiAuth(&cfg.Auth)
+}
+
+func validateApiAuth(cfg *ApiAuthConfig) error {
+	if cfg.Access.Enabled {
+		if cfg.Access.TeamDomain == "" {
+			return &ValidationError{Field: "api.auth.access.team_domain", Message: "team_domain is required when Access authentication is enabled"}
+		}
+		if cfg.Access.Audience == "" {
+			return &ValidationError{Field: "api.auth.access.audience", Message: "audience is required when Access authentication is enabled"}
+		}
+	}
+
+	if cfg.OIDC.Enabled {
+		if u, err := url.Parse(cfg.OIDC.Issuer); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return &ValidationError{Field: "api.auth.oidc.issuer", Message: "must be an http:// or https:// URL: " + cfg.OIDC.Issuer}
+		}
+		if cfg.OIDC.ClientID == "" {
+			return &ValidationError{Field: "api.auth.oidc.client_id", Message: "client_id is required when OIDC is enabled"}
+		}
+		if u, err := url.Parse(cfg.OIDC.RedirectURL); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return &ValidationError{Field: "api.auth.oidc.redirect_url", Message: "must be an http:// or https:// URL: " + cfg.OIDC.RedirectURL}
+		}
+		if cfg.OIDC.SessionTTL <= 0 {
+			return &ValidationError{Field: "api.auth.oidc.session_ttl", Message: "must be positive"}
+		}
+	}
+
+	for scope := range cfg.Roles {
+		if !slices.Contains(apiScopes, scope) {
+			return &ValidationError{Field: "api.auth.roles." + scope, Message: "unknown scope (valid: " + strings.Join(apiScopes, ", ") + ")"}
+		}
+	}
+	return nil
+}
+
+// notificationSinkTypes are the supported notifications.sinks.*.type values.
+var notificationSinkTypes = []string{"webhook", "slack", "discord", "ntfy", "gotify"}
+
+// notificationCategories are the event categories notifications can filter on.
+var notificationCategories = []string{"resource", "agent", "orphan", "drift", "reconcile", "container"}
+
+func validateNotifications(cfg *NotificationsConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.RetryAttempts < 1 {
+		cfg.RetryAttempts = 1
+	}
+	if cfg.RetryDelay <= 0 {
+		return &ValidationError{Field: "notifications.retry_delay", Message: "must be positive"}
+	}
+	if cfg.QueueSize < 1 {
+		return &ValidationError{Field: "notifications.queue_size", Message: "must be at least 1"}
+	}
+	if cfg.Timeout <= 0 {
+		return &ValidationError{Field: "notifications.timeout", Message: "must be positive"}
+	}
+
+	for name, sink := range cfg.Sinks {
+		field := "notifications.sinks." + name
+		if !slices.Contains(notificationSinkTypes, sink.Type) {
+			return &ValidationError{Field: field + ".type", Message: "unsupported type: " + sink.Type + " (supported: " + strings.Join(notificationSinkTypes, ", ") + ")"}
+		}
+		if u, err := url.Parse(sink.URL); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return &ValidationError{Field: field + ".url", Message: "must be an http:// or https:// URL: " + sink.URL}
+		}
+		for _, event := range sink.Events {
+			category, _, _ := strings.Cut(event, ".")
+			if !slices.Contains(notificationCategories, category) {
+				return &ValidationError{Field: field + ".events", Message: "unknown event: " + event + " (categories: " + strings.Join(notificationCategories, ", ") + ")"}
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Secret references: a secret setting whose value starts with one of these
+// prefixes is read from a file (e.g. a Docker secret) or another
+// environment variable.
+const (
+	secretFilePrefix = "file:"
+	secretEnvPrefix  = "env:"
+)
+
+// redactedValue replaces non-empty secrets in Redacted configs.
+const redactedValue = "[redacted]"
+
+// forEachSecret calls fn with the config key and value of every secret
+// setting of cfg, including those of named credentials, agents and sinks.
+// Sink URLs and extra headers count as secrets, since they often carry
+// credentials. Values changed by fn are stored in cfg.
+func forEachSecret(cfg *Config, fn func(key string, value *string) error) error {
+	for _, s := range []struct {
+		key   string
+		value *string
+	}{
+		{"cloudflare.api_token", &cfg.Cloudflare.APIToken},
+		{"docker.ssh.key_passphrase", &cfg.Docker.SSH.KeyPassphrase},
+		{"db.dsn", &cfg.Db.DSN},
+		{"api.token", &cfg.Api.Token},
+		{"api.auth.oidc.client_secret", &cfg.Api.Auth.OIDC.ClientSecret},
+		{"api.auth.oidc.session_secret", &cfg.Api.Auth.OIDC.SessionSecret},
+		{"agent.accept_token", &cfg.Agent.AcceptToken},
+		{"connect.token", &cfg.Connect.Token},
+	} {
+		if err := fn(s.key, s.value); err != nil {
+			return err
+		}
+	}
+
+	for name, cred := range cfg.Cloudflare.Credentials {
+		if err := fn("cloudflare.credentials."+name+".api_token", &cred.APIToken); err != nil {
+			return err
+		}
+		cfg.Cloudflare.Credentials[name] = cred
+	}
+	for id, entry := range cfg.Agent.Agents {
+		if err := fn("agent.agents."+id+".token", &entry.Token); err != nil {
+			return err
+		}
+		cfg.Agent.Agents[id] = entry
+	}
+	for name, sink := range cfg.Notifications.Sinks {
+		if err := fn("notifications.sinks."+name+".secret", &sink.Secret); err != nil {
+			return err
+		}
+		if err := fn("notifications.sinks."+name+".token", &sink.Token); err != nil {
+			return err
+		}
+		if err := fn("notifications.sinks."+name+".url", &sink.URL); err != nil {
+			return err
+		}
+		if err := forEachHeader("notifications.sinks."+name+".headers", sink.Headers, fn); err != nil {
+			return err
+		}
+		cfg.Notifications.Sinks[name] = sink
+	}
+	return forEachHeader("tracing.headers", cfg.Tracing.Headers, fn)
+}
+
+// forEachHeader calls fn with the value of every header in headers.
+func forEachHeader(key string, headers map[string]string, fn func(key string, value *string) error) error {
+	for name, value := range headers {
+		if err := fn(key+"."+name, &value); err != nil {
+			return err
+		}
+		headers[name] = value
+	}
+	return nil
+}
+
+// resolveSecrets reads every secret from the file named by its _FILE
+// environment variable (e.g. LABELGATE_API_TOKEN_FILE), or resolves a
+// file: or env: reference in its value.
+func resolveSecrets(cfg *Config) error {
+	return forEachSecret(cfg, func(key string, value *string) error {
+		env := envName(key)
+		if path := os.Getenv(env + "_FILE"); path != "" {
+			if os.Getenv(env) != "" {
+				return &ValidationError{Field: env + "_FILE", Message: "set only one of " + env + " and " + env + "_FILE"}
+			}
+			secret, err := readSecretFile(path)
+			if err != nil {
+				return &ValidationError{Field: env + "_FILE", Message: err.Error()}
+			}
+			*value = secret
+			return nil
+		}
+
+		switch {
+		case strings.HasPrefix(*value, secretFilePrefix):
+			secret, err := readSecretFile(strings.TrimPrefix(*value, secretFilePrefix))
+			if err != nil {
+				return &ValidationError{Field: key, Message: err.Error()}
+			}
+			*value = secret
+		case strings.HasPrefix(*value, secretEnvPrefix):
+			name := strings.TrimPrefix(*value, secretEnvPrefix)
+			secret, ok := os.LookupEnv(name)
+			if !ok {
+				return &ValidationError{Field: key, Message: "environment variable " + name + " is not set"}
+			}
+			*value = secret
+		}
+		return nil
+	})
+}
+
+// envName returns the environment variable of a config key, e.g.
+// LABELGATE_AGENT_AGENTS_EDGE_1_TOKEN for agent.agents.edge-1.token.
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// readSecretFile reads a secret, ignoring surrounding whitespace such as the
+// trailing newline of Docker secrets.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Redacted returns a copy of cfg with every non-empty secret replaced by
+// "[redacted]", for printing or logging the configuration.
+func (cfg *Config) Redacted() *Config {
+	c := *cfg
+	c.Cloudflare.Credentials = maps.Clone(cfg.Cloudflare.Credentials)
+	c.Agent.Agents = maps.Clone(cfg.Agent.Agents)
+	c.Notifications.Sinks = maps.Clone(cfg.Notifications.Sinks)
+	for name, sink := range c.Notifications.Sinks {
+		sink.Headers = maps.Clone(sink.Headers)
+		c.Notifications.Sinks[name] = sink
+	}
+	c.Tracing.Headers = maps.Clone(cfg.Tracing.Headers)
+	forEachSecret(&c, func(_ string, value *string) error {
+		if *value != "" {
+			*value = redactedValue
+		}
+		return nil
+	})
+	return &c
+}
+
+// Settings returns cfg as nested maps keyed like the config file, e.g. for
+// printing the effective configuration. Durations are formatted as strings.
+func (cfg *Config) Settings() map[string]any {
+	return settingsOf(reflect.ValueOf(cfg).Elem()).(map[string]any)
+}
+
+func settingsOf(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		settings := make(map[string]any, v.NumField())
+		for i := range v.NumField() {
+			settings[v.Type().Field(i).Tag.Get("mapstructure")] = settingsOf(v.Field(i))
+		}
+		return settings
+	case reflect.Map:
+		settings := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			settings[fmt.Sprint(iter.Key().Interface())] = settingsOf(iter.Value())
+		}
+		return settings
+	case reflect.Slice:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = settingsOf(v.Index(i))
+		}
+		return items
+	}
+	return v.Interface()
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// writeSecret writes content to a file in a temporary directory.
+func writeSecret(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveSecrets(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(t *testing.T, cfg *Config)
+		get       func(cfg *Config) string
+		want      string
+		wantField string // field of the expected ValidationError
+	}{
+		{
+			name:      "literal value",
+			configure: func(t *testing.T, cfg *Config) { cfg.Api.Token = "literal" },
+			get:       func(cfg *Config) string { return cfg.Api.Token },
+			want:      "literal",
+		},
+		{
+			name: "file reference",
+			configure: func(t *testing.T, cfg *Config) {
+				cfg.Api.Token = "file:" + writeSecret(t, "from-file\n")
+			},
+			get:  func(cfg *Config) string { return cfg.Api.Token },
+			want: "from-file",
+		},
+		{
+			name: "file reference with CRLF and spaces",
+			configure: func(t *testing.T, cfg *Config) {
+				cfg.Api.Token = "file:" + writeSecret(t, "  from-file \r\n\r\n")
+			},
+			get:  func(cfg *Config) string { return cfg.Api.Token },
+			want: "from-file",
+		},
+		{
+			name: "env reference",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("MY_API_TOKEN", "from-env")
+				cfg.Api.Token = "env:MY_API_TOKEN"
+			},
+			get:  func(cfg *Config) string { return cfg.Api.Token },
+			want: "from-env",
+		},
+		{
+			name: "env reference to an empty variable",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("MY_API_TOKEN", "")
+				cfg.Api.Token = "env:MY_API_TOKEN"
+			},
+			get:  func(cfg *Config) string { return cfg.Api.Token },
+			want: "",
+		},
+		{
+			name:      "env reference to an unset variable",
+			configure: func(t *testing.T, cfg *Config) { cfg.Api.Token = "env:LABELGATE_TEST_UNSET" },
+			wantField: "api.token",
+		},
+		{
+			name: "_FILE variable takes precedence over the config value",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_API_TOKEN_FILE", writeSecret(t, "from-env-file\n"))
+				cfg.Api.Token = "file:" + writeSecret(t, "from-config-file")
+			},
+			get:  func(cfg *Config) string { return cfg.Api.Token },
+			want: "from-env-file",
+		},
+		{
+			name: "_FILE variable and variable both set",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_API_TOKEN", "from-env")
+				t.Setenv("LABELGATE_API_TOKEN_FILE", writeSecret(t, "from-env-file"))
+			},
+			wantField: "LABELGATE_API_TOKEN_FILE",
+		},
+		{
+			name: "_FILE variable of a named credential",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_CLOUDFLARE_CREDENTIALS_EU_PROD_API_TOKEN_FILE", writeSecret(t, "cf-token\n"))
+				cfg.Cloudflare.Credentials = map[string]CredentialConfig{"eu-prod": {Zones: []string{"example.eu"}}}
+			},
+			get:  func(cfg *Config) string { return cfg.Cloudflare.Credentials["eu-prod"].APIToken },
+			want: "cf-token",
+		},
+		{
+			name: "_FILE variable of an agent",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_AGENT_AGENTS_EDGE_1_TOKEN_FILE", writeSecret(t, "agent-token\n"))
+				cfg.Agent.Agents = map[string]AgentEntryConfig{"edge-1": {}}
+			},
+			get:  func(cfg *Config) string { return cfg.Agent.Agents["edge-1"].Token },
+			want: "agent-token",
+		},
+		{
+			name: "missing _FILE",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_API_AUTH_OIDC_CLIENT_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+			},
+			wantField: "LABELGATE_API_AUTH_OIDC_CLIENT_SECRET_FILE",
+		},
+		{
+			name: "unreadable _FILE",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_CONNECT_TOKEN_FILE", t.TempDir())
+			},
+			wantField: "LABELGATE_CONNECT_TOKEN_FILE",
+		},
+		{
+			name: "env reference in a sink header",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("OPS_WEBHOOK_AUTH", "Bearer from-env")
+				cfg.Notifications.Sinks = map[string]NotificationSinkConfig{
+					"ops": {Type: "webhook", Headers: map[string]string{"authorization": "env:OPS_WEBHOOK_AUTH"}},
+				}
+			},
+			get:  func(cfg *Config) string { return cfg.Notifications.Sinks["ops"].Headers["authorization"] },
+			want: "Bearer from-env",
+		},
+		{
+			name: "_FILE variable of a sink URL",
+			configure: func(t *testing.T, cfg *Config) {
+				t.Setenv("LABELGATE_NOTIFICATIONS_SINKS_OPS_URL_FILE", writeSecret(t, "https://hooks.example.com/T0/B0/x\n"))
+				cfg.Notifications.Sinks = map[string]NotificationSinkConfig{"ops": {Type: "slack"}}
+			},
+			get:  func(cfg *Config) string { return cfg.Notifications.Sinks["ops"].URL },
+			want: "https://hooks.example.com/T0/B0/x",
+		},
+		{
+			name: "file reference in a tracing header",
+			configure: func(t *testing.T, cfg *Config) {
+				cfg.Tracing.Headers = map[string]string{"x-api-key": "file:" + writeSecret(t, "otlp-key\n")}
+			},
+			get:  func(cfg *Config) string { return cfg.Tracing.Headers["x-api-key"] },
+			want: "otlp-key",
+		},
+		{
+			name: "missing file reference",
+			configure: func(t *testing.T, cfg *Config) {
+				cfg.Cloudflare.Credentials = map[string]CredentialConfig{
+					"prod": {APIToken: "file:" + filepath.Join(t.TempDir(), "missing")},
+				}
+			},
+			wantField: "cloudflare.credentials.prod.api_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.configure(t, cfg)
+
+			err := resolveSecrets(cfg)
+			if tt.wantField != "" {
+				var verr *ValidationError
+				if !errors.As(err, &verr) || verr.Field != tt.wantField {
+					t.Fatalf("expected a validation error for %s, got %v", tt.wantField, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.get(cfg); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// secretField matches the config keys of secret settings.
+var secretField = regexp.MustCompile(`token|secret|passphrase|dsn|^url$`)
+
+// fillSecrets sets every string setting of v whose key matches secretField,
+// and every header value, including those of map entries, to a unique value
+// recorded in values.
+func fillSecrets(v reflect.Value, key string, values map[string]string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			fillSecrets(v.Field(i), key+"."+v.Type().Field(i).Tag.Get("mapstructure"), values)
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			entry := reflect.New(v.Type().Elem()).Elem()
+			entry.Set(iter.Value())
+			fillSecrets(entry, key+"."+iter.Key().String(), values)
+			v.SetMapIndex(iter.Key(), entry)
+		}
+	case reflect.String:
+		if secretField.MatchString(key[strings.LastIndex(key, ".")+1:]) || strings.Contains(key, ".headers.") {
+			values[key] = fmt.Sprintf("secret-%d", len(values))
+			v.SetString(values[key])
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Cloudflare.Credentials = map[string]CredentialConfig{"prod": {Zones: []string{"example.com"}}}
+	cfg.Cloudflare.Tunnels = map[string]TunnelConfig{"home": {AccountID: "account", TunnelID: "tunnel", Credential: "prod"}}
+	cfg.Agent.Agents = map[string]AgentEntryConfig{"edge-1": {DefaultTunnel: "home"}}
+	cfg.Notifications.Sinks = map[string]NotificationSinkConfig{"ops": {Type: "ntfy", Headers: map[string]string{"authorization": ""}}}
+	cfg.Tracing.Headers = map[string]string{"x-api-key": ""}
+
+	secrets := make(map[string]string)
+	fillSecrets(reflect.ValueOf(cfg).Elem(), "", secrets)
+	for _, key := range []string{
+		".cloudflare.api_token",
+		".cloudflare.credentials.prod.api_token",
+		".agent.accept_token",
+		".agent.agents.edge-1.token",
+		".api.token",
+		".api.auth.oidc.client_secret",
+		".api.auth.oidc.session_secret",
+		".connect.token",
+		".notifications.sinks.ops.token",
+		".notifications.sinks.ops.url",
+		".notifications.sinks.ops.headers.authorization",
+		".tracing.headers.x-api-key",
+	} {
+		if _, ok := secrets[key]; !ok {
+			t.Fatalf("expected %s to be a secret setting, got %v", key, secrets)
+		}
+	}
+
+	settings := fmt.Sprint(cfg.Redacted().Settings())
+	for key, value := range secrets {
+		if strings.Contains(settings, value+" ") || strings.Contains(settings, value+"]") {
+			t.Errorf("%s is not redacted", key)
+		}
+	}
+	if n := strings.Count(settings, redactedValue); n != len(secrets) {
+		t.Errorf("expected %d redacted settings, got %d", len(secrets), n)
+	}
+	for _, want := range []string{"tunnel_id:tunnel", "credential:prod", "default_tunnel:home", "zones:[example.com]"} {
+		if !strings.Contains(settings, want) {
+			t.Errorf("expected %q to be kept", want)
+		}
+	}
+
+	// The original is unchanged, including map entries
+	if cfg.Cloudflare.Credentials["prod"].APIToken != secrets[".cloudflare.credentials.prod.api_token"] ||
+		cfg.Agent.Agents["edge-1"].Token != secrets[".agent.agents.edge-1.token"] ||
+		cfg.Api.Auth.OIDC.ClientSecret != secrets[".api.auth.oidc.client_secret"] ||
+		cfg.Notifications.Sinks["ops"].Headers["authorization"] != secrets[".notifications.sinks.ops.headers.authorization"] ||
+		cfg.Tracing.Headers["x-api-key"] != secrets[".tracing.headers.x-api-key"] {
+		t.Error("Redacted modified the original config")
+	}
+
+	// Empty secrets stay empty, so unset settings remain visible
+	if got := DefaultConfig().Redacted().Api.Token; got != "" {
+		t.Errorf("expected an empty token to stay empty, got %q", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Package events provides an in-process publish/subscribe bus for live
+// labelgate events (reconcile passes, resource changes, agent connections and
+// container events), consumed by the API event stream and notifications.
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// Type identifies the kind of an event. Types are namespaced by category,
+// e.g. "resource.create" belongs to category "resource".
+type Type string
+
+const (
+	// TypeReconcileStarted is published when a reconcile pass begins.
+	TypeReconcileStarted Type = "reconcile.started"
+	// TypeReconcileFinished is published when a reconcile pass ends.
+	TypeReconcileFinished Type = "reconcile.finished"
+	// TypeAgentConnected is published when an agent authenticates.
+	TypeAgentConnected Type = "agent.connected"
+	// TypeAgentDisconnected is published when an agent connection closes.
+	TypeAgentDisconnected Type = "agent.disconnected"
+	// TypeOrphanCleanup is published before orphaned resources are deleted
+	// from Cloudflare.
+	TypeOrphanCleanup Type = "orphan.cleanup"
+	// TypeOrphanCleanupFailed is published when deleting an orphaned resource
+	// fails; the delete is retried on later passes without publishing again.
+	TypeOrphanCleanupFailed Type = "orphan.cleanup_failed"
+	// TypeDriftDetected is published when Cloudflare state was changed outside
+	// labelgate and is about to be overwritten.
+	TypeDriftDetected Type = "drift.detected"
+
+	// Resource changes are published as "resource.<action>" (see storage.ResourceAction)
+	// and container events as "container.<type>" (see types.EventType).
+)
+
+// Event is a single live event.
+type Event struct {
+	// ID increases monotonically per process; clients resume from it
+	ID   uint64    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Hostnames the event concerns, used for filtering (empty for global events)
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Data is the event payload
+	Data any `json:"data,omitempty"`
+}
+
+// Filter selects events for a subscriber. Empty fields match everything.
+type Filter struct {
+	// Types are exact types ("agent.connected") or categories ("agent")
+	Types []string
+	// Hostname only matches events that concern this hostname
+	Hostname string
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Event) bool {
+	if len(f.Types) > 0 {
+		matched := false
+		for _, t := range f.Types {
+			if string(e.Type) == t || strings.HasPrefix(string(e.Type), t+".") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Hostname != "" {
+		for _, h := range e.Hostnames {
+			if h == f.Hostname {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// historySize is the number of recent events kept for clients that reconnect.
+const historySize = 256
+
+// subscriberBuffer is the per-subscriber queue length; events are dropped for
+// subscribers that fall further behind.
+const subscriberBuffer = 64
+
+// Bus fans events out to subscribers. Publishing never blocks. A nil *Bus is
+// valid and discards everything, so components can publish unconditionally.
+type Bus struct {
+	mu      sync.Mutex
+	nextID  uint64
+	history []Event
+	subs    map[*Subscription]struct{}
+}
+
+// NewBus creates an event bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events matching its filter on C.
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	filter Filter
+	bus    *Bus
+	once   sync.Once
+}
+
+// Publish assigns the event an ID and timestamp and delivers it to matching
+// subscribers. Subscribers whose buffer is full miss the event.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	e.ID = b.nextID
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a subscriber. Buffered events with an ID greater than
+// afterID are replayed first (pass 0 for live events only). Call Close when done.
+func (b *Bus) Subscribe(filter Filter, afterID uint64) *Subscription {
+	c := make(chan Event, subscriberBuffer+historySize)
+	sub := &Subscription{C: c, c: c, filter: filter, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if afterID > 0 {
+		for _, e := range b.history {
+			if e.ID > afterID && filter.Match(e) {
+				c <- e
+			}
+		}
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.c)
+	})
+}
+
+// ResourceEvent converts a resource history entry into a live event.
+func ResourceEvent(e *storage.ResourceEvent) Event {
+	return Event{
+		Type:      Type("resource." + string(e.Action)),
+		Time:      e.CreatedAt.UTC(),
+		Hostnames: []string{e.Hostname},
+		Data:      e,
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case e := <-sub.C:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestBusFilters(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(Filter{Types: []string{"resource", string(TypeAgentConnected)}, Hostname: "a.example.com"}, 0)
+	defer sub.Close()
+
+	bus.Publish(Event{Type: "resource.create", Hostnames: []string{"b.example.com"}})
+	bus.Publish(Event{Type: TypeReconcileStarted, Hostnames: []string{"a.example.com"}})
+	bus.Publish(Event{Type: "resourcex.create", Hostnames: []string{"a.example.com"}})
+	bus.Publish(Event{Type: "resource.update", Hostnames: []string{"a.example.com"}})
+
+	e := receive(t, sub)
+	if e.Type != "resource.update" || e.ID != 4 {
+		t.Fatalf("expected resource.update with ID 4, got %s (ID %d)", e.Type, e.ID)
+	}
+	select {
+	case e := <-sub.C:
+		t.Fatalf("unexpected event %s", e.Type)
+	default:
+	}
+}
+
+func TestBusReplay(t *testing.T) {
+	bus := NewBus()
+	for i := 0; i < 3; i++ {
+		bus.Publish(Event{Type: TypeReconcileFinished})
+	}
+
+	sub := bus.Subscribe(Filter{}, 1)
+	defer sub.Close()
+	bus.Publish(Event{Type: TypeReconcileStarted})
+
+	for _, want := range []uint64{2, 3, 4} {
+		if e := receive(t, sub); e.ID != want {
+			t.Fatalf("expected event %d, got %d", want, e.ID)
+		}
+	}
+}
+
+func TestBusSlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(Filter{}, 0)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10*subscriberBuffer+historySize; i++ {
+			bus.Publish(Event{Type: TypeReconcileStarted})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+
+	sub.Close()
+	sub.Close() // idempotent
+	bus.Publish(Event{Type: TypeReconcileStarted})
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: TypeReconcileStarted})
+}
@@ -0,0 +1,252 @@
+// Package leader provides lease-based leader election for running multiple
+// labelgate main instances against the same Cloudflare zones.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Backend is a pluggable lease store used by the Elector.
+// Implementations must make TryAcquire atomic across all participating instances.
+type Backend interface {
+	// TryAcquire acquires the lease for identity, or renews it if identity
+	// already holds it. Returns false if another holder owns an unexpired lease.
+	TryAcquire(ctx context.Context, identity string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease if it is held by identity.
+	Release(ctx context.Context, identity string) error
+
+	// Holder returns the current lease holder and lease expiry.
+	// Returns an empty holder if no lease has been taken yet.
+	Holder(ctx context.Context) (string, time.Time, error)
+
+	// Close releases backend resources.
+	Close() error
+}
+
+// Config holds elector configuration.
+type Config struct {
+	Backend       Backend
+	Identity      string        // unique identity of this instance (default: hostname)
+	LeaseDuration time.Duration // how long a lease is valid without renewal
+	RenewInterval time.Duration // how often the lease is acquired/renewed
+
+	// OnStartedLeading is called in its own goroutine when this instance becomes
+	// leader. The context is cancelled when leadership is lost or on shutdown.
+	OnStartedLeading func(ctx context.Context)
+
+	// OnStoppedLeading is called after the OnStartedLeading goroutine has returned.
+	OnStoppedLeading func()
+}
+
+// Elector runs the acquire/renew loop and tracks leadership state.
+type Elector struct {
+	backend       Backend
+	identity      string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+	onStarted     func(ctx context.Context)
+	onStopped     func()
+
+	mu          sync.RWMutex
+	isLeader    bool
+	holder      string    // last observed lease holder
+	lastRenewed time.Time // last successful acquire/renew while leading
+
+	cancelLeading context.CancelFunc
+	leadingWG     sync.WaitGroup
+}
+
+// NewElector creates a new leader elector.
+func NewElector(cfg *Config) *Elector {
+	identity := cfg.Identity
+	if identity == "" {
+		identity = DefaultIdentity()
+	}
+
+	leaseDuration := cfg.LeaseDuration
+	if leaseDuration <= 0 {
+		leaseDuration = 15 * time.Second
+	}
+	renewInterval := cfg.RenewInterval
+	if renewInterval <= 0 || renewInterval >= leaseDuration {
+		renewInterval = leaseDuration / 3
+	}
+
+	return &Elector{
+		backend:       cfg.Backend,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+		renewInterval: renewInterval,
+		onStarted:     cfg.OnStartedLeading,
+		onStopped:     cfg.OnStoppedLeading,
+	}
+}
+
+// DefaultIdentity returns hostname-pid, which is unique per process on a host.
+func DefaultIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "labelgate"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// Run blocks until the context is cancelled, campaigning for leadership
+// every renew interval. On shutdown the lease is released so a follower
+// can take over without waiting for expiry.
+func (e *Elector) Run(ctx context.Context) error {
+	log.Info().
+		Str("identity", e.identity).
+		Dur("lease_duration", e.leaseDuration).
+		Dur("renew_interval", e.renewInterval).
+		Msg("Starting leader election")
+
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	e.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			e.shutdown()
+			return ctx.Err()
+		case <-ticker.C:
+			e.tick(ctx)
+		}
+	}
+}
+
+// tick performs a single acquire/renew attempt and handles state transitions.
+func (e *Elector) tick(ctx context.Context) {
+	// The lease runs from before the attempt at the latest
+	attempted := time.Now()
+	acquired, err := e.backend.TryAcquire(ctx, e.identity, e.leaseDuration)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		log.Warn().Err(err).Str("identity", e.identity).Msg("Leader lease acquire/renew failed")
+		// Keep leading while our own lease is valid; another instance cannot
+		// take over before it expires either. The next attempt is a renew
+		// interval away, so step down once less than that remains.
+		e.mu.RLock()
+		expiring := e.isLeader && time.Until(e.lastRenewed.Add(e.leaseDuration)) < e.renewInterval
+		e.mu.RUnlock()
+		if expiring {
+			e.stepDown("lease renewal failed and the lease is about to expire")
+		}
+		return
+	}
+
+	if acquired {
+		e.mu.Lock()
+		e.holder = e.identity
+		e.lastRenewed = attempted
+		wasLeader := e.isLeader
+		e.mu.Unlock()
+
+		if !wasLeader {
+			e.becomeLeader(ctx)
+		}
+		return
+	}
+
+	if holder, _, err := e.backend.Holder(ctx); err == nil {
+		e.mu.Lock()
+		e.holder = holder
+		e.mu.Unlock()
+	}
+	e.stepDown("lease held by another instance")
+}
+
+// becomeLeader transitions to leader and starts the leading callback.
+func (e *Elector) becomeLeader(ctx context.Context) {
+	leadCtx, cancel := context.WithCancel(ctx)
+
+	e.mu.Lock()
+	e.isLeader = true
+	e.cancelLeading = cancel
+	e.mu.Unlock()
+
+	log.Info().Str("identity", e.identity).Msg("Acquired leadership")
+
+	if e.onStarted == nil {
+		return
+	}
+	e.leadingWG.Add(1)
+	go func() {
+		defer e.leadingWG.Done()
+		e.onStarted(leadCtx)
+	}()
+}
+
+// stepDown transitions to follower, waiting for the leading callback to return.
+func (e *Elector) stepDown(reason string) {
+	e.mu.Lock()
+	if !e.isLeader {
+		e.mu.Unlock()
+		return
+	}
+	e.isLeader = false
+	cancel := e.cancelLeading
+	e.cancelLeading = nil
+	e.mu.Unlock()
+
+	log.Warn().Str("identity", e.identity).Str("reason", reason).Msg("Lost leadership")
+
+	if cancel != nil {
+		cancel()
+	}
+	e.leadingWG.Wait()
+
+	if e.onStopped != nil {
+		e.onStopped()
+	}
+}
+
+// shutdown stops leading and releases the lease.
+func (e *Elector) shutdown() {
+	e.mu.RLock()
+	wasLeader := e.isLeader
+	e.mu.RUnlock()
+
+	if !wasLeader {
+		return
+	}
+	e.stepDown("shutting down")
+
+	releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.backend.Release(releaseCtx, e.identity); err != nil {
+		log.Warn().Err(err).Str("identity", e.identity).Msg("Failed to release leader lease")
+		return
+	}
+	log.Info().Str("identity", e.identity).Msg("Released leader lease")
+}
+
+// IsLeader returns whether this instance currently holds leadership.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isLeader
+}
+
+// Identity returns this instance's election identity.
+func (e *Elector) Identity() string {
+	return e.identity
+}
+
+// Leader returns the last observed lease holder (may be empty).
+func (e *Elector) Leader() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.holder
+}
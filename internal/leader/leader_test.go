@@ -0,0 +1,196 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupTestBackend(t *testing.T) *SQLiteBackend {
+	t.Helper()
+	b, err := NewSQLiteBackend(filepath.Join(t.TempDir(), "lease.db"))
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestSQLiteBackend_AcquireAndExpiry(t *testing.T) {
+	b := setupTestBackend(t)
+	ctx := context.Background()
+
+	ok, err := b.TryAcquire(ctx, "a", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("a should acquire empty lease: ok=%v err=%v", ok, err)
+	}
+
+	// Renewal by the holder succeeds
+	if ok, _ := b.TryAcquire(ctx, "a", time.Minute); !ok {
+		t.Fatal("a should renew its own lease")
+	}
+
+	// Another identity cannot take an unexpired lease
+	if ok, _ := b.TryAcquire(ctx, "b", time.Minute); ok {
+		t.Fatal("b should not acquire a lease held by a")
+	}
+
+	holder, _, err := b.Holder(ctx)
+	if err != nil || holder != "a" {
+		t.Fatalf("expected holder a, got %q (err=%v)", holder, err)
+	}
+
+	// Expired lease can be taken over
+	if _, err := b.TryAcquire(ctx, "a", -time.Second); err != nil {
+		t.Fatalf("failed to shorten lease: %v", err)
+	}
+	if ok, _ := b.TryAcquire(ctx, "b", time.Minute); !ok {
+		t.Fatal("b should take over expired lease")
+	}
+}
+
+func TestSQLiteBackend_Release(t *testing.T) {
+	b := setupTestBackend(t)
+	ctx := context.Background()
+
+	b.TryAcquire(ctx, "a", time.Minute)
+
+	// Release by non-holder is a no-op
+	if err := b.Release(ctx, "b"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if ok, _ := b.TryAcquire(ctx, "b", time.Minute); ok {
+		t.Fatal("b should not acquire after foreign release")
+	}
+
+	if err := b.Release(ctx, "a"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if ok, _ := b.TryAcquire(ctx, "b", time.Minute); !ok {
+		t.Fatal("b should acquire after a released")
+	}
+}
+
+func TestElector_Failover(t *testing.T) {
+	b := setupTestBackend(t)
+
+	started := make(chan string, 2)
+	newElector := func(id string) *Elector {
+		return NewElector(&Config{
+			Backend:       b,
+			Identity:      id,
+			LeaseDuration: 300 * time.Millisecond,
+			RenewInterval: 50 * time.Millisecond,
+			OnStartedLeading: func(ctx context.Context) {
+				started <- id
+				<-ctx.Done()
+			},
+		})
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	a := newElector("a")
+	doneA := make(chan struct{})
+	go func() {
+		a.Run(ctxA)
+		close(doneA)
+	}()
+
+	if id := <-started; id != "a" {
+		t.Fatalf("expected a to lead first, got %s", id)
+	}
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	bElector := newElector("b")
+	go bElector.Run(ctxB)
+
+	time.Sleep(150 * time.Millisecond)
+	if bElector.IsLeader() {
+		t.Fatal("b must not lead while a holds the lease")
+	}
+	if bElector.Leader() != "a" {
+		t.Fatalf("b should observe a as leader, got %q", bElector.Leader())
+	}
+
+	// Shut down a; it releases the lease and b takes over
+	cancelA()
+	<-doneA
+	if a.IsLeader() {
+		t.Fatal("a should not be leader after shutdown")
+	}
+
+	select {
+	case id := <-started:
+		if id != "b" {
+			t.Fatalf("expected b to take over, got %s", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("b did not take over leadership")
+	}
+}
+
+// flakyBackend fails every acquire/renew while failing is set.
+type flakyBackend struct {
+	Backend
+
+	mu          sync.Mutex
+	failing     bool
+	lastRenewed time.Time
+}
+
+func (b *flakyBackend) TryAcquire(ctx context.Context, identity string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing {
+		return false, errors.New("database unavailable")
+	}
+	ok, err := b.Backend.TryAcquire(ctx, identity, ttl)
+	if ok {
+		b.lastRenewed = time.Now()
+	}
+	return ok, err
+}
+
+func (b *flakyBackend) fail() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failing = true
+	return b.lastRenewed
+}
+
+func TestElector_StepsDownBeforeLeaseExpires(t *testing.T) {
+	b := &flakyBackend{Backend: setupTestBackend(t)}
+	const leaseDuration = 400 * time.Millisecond
+
+	started := make(chan struct{}, 1)
+	stopped := make(chan time.Time, 1)
+	e := NewElector(&Config{
+		Backend:          b,
+		Identity:         "a",
+		LeaseDuration:    leaseDuration,
+		RenewInterval:    150 * time.Millisecond,
+		OnStartedLeading: func(ctx context.Context) { started <- struct{}{}; <-ctx.Done() },
+		OnStoppedLeading: func() { stopped <- time.Now() },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+	<-started
+
+	// Renewals fail from now on; the lease expires leaseDuration after the
+	// last successful one, when another instance may take over
+	expires := b.fail().Add(leaseDuration)
+	select {
+	case at := <-stopped:
+		if !at.Before(expires) {
+			t.Errorf("stepped down %v after the lease expired", at.Sub(expires))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("elector kept leading after its lease expired")
+	}
+}
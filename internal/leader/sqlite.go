@@ -0,0 +1,109 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// ensure SQLiteBackend implements Backend
+var _ Backend = (*SQLiteBackend)(nil)
+
+// leaseName is the row key for the single main-instance lease.
+const leaseName = "main"
+
+// SQLiteBackend stores the lease in a SQLite database. SQLite's file locking
+// makes the conditional upsert atomic across processes on the same host. WAL
+// mode relies on shared memory and network filesystems don't lock reliably,
+// so the database must be on a local disk: the backend is not safe for
+// instances on different hosts sharing an NFS/SMB volume.
+type SQLiteBackend struct {
+	db *sql.DB
+}
+
+// NewSQLiteBackend opens (or creates) the lease table in the database at path.
+// The path may be the same file as the main storage database.
+func NewSQLiteBackend(path string) (*SQLiteBackend, error) {
+	dir := filepath.Dir(path)
+	if dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create lease directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lease database: %w", err)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS leader_lease (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		expires_at INTEGER NOT NULL,
+		renewed_at INTEGER NOT NULL
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create lease table: %w", err)
+	}
+
+	return &SQLiteBackend{db: db}, nil
+}
+
+// TryAcquire acquires or renews the lease in a single conditional upsert.
+// The update only applies when we already hold the lease or it has expired.
+func (b *SQLiteBackend) TryAcquire(ctx context.Context, identity string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	query := `
+		INSERT INTO leader_lease (name, holder, expires_at, renewed_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			holder = excluded.holder,
+			expires_at = excluded.expires_at,
+			renewed_at = excluded.renewed_at
+		WHERE leader_lease.holder = excluded.holder OR leader_lease.expires_at < ?
+	`
+	result, err := b.db.ExecContext(ctx, query,
+		leaseName, identity, now.Add(ttl).UnixMilli(), now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Release expires the lease immediately if identity holds it.
+func (b *SQLiteBackend) Release(ctx context.Context, identity string) error {
+	_, err := b.db.ExecContext(ctx,
+		`UPDATE leader_lease SET expires_at = 0 WHERE name = ? AND holder = ?`,
+		leaseName, identity)
+	return err
+}
+
+// Holder returns the current lease holder and expiry.
+func (b *SQLiteBackend) Holder(ctx context.Context) (string, time.Time, error) {
+	var holder string
+	var expiresAt int64
+	err := b.db.QueryRowContext(ctx,
+		`SELECT holder, expires_at FROM leader_lease WHERE name = ?`, leaseName,
+	).Scan(&holder, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return holder, time.UnixMilli(expiresAt), nil
+}
+
+// Close closes the lease database.
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
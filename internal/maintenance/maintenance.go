@@ -0,0 +1,266 @@
+// Package maintenance schedules periodic database upkeep for the main instance:
+// retention cleanup, vacuum, WAL checkpointing and integrity checks.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// StatusKey is the sync state key holding the last run of every task, so any
+// instance (not only the one running maintenance) can report it.
+const StatusKey = "maintenance"
+
+// Task names.
+const (
+	TaskRetention  = "retention"
+	TaskVacuum     = "vacuum"
+	TaskCheckpoint = "checkpoint"
+	TaskIntegrity  = "integrity_check"
+)
+
+// Config holds scheduler configuration. A zero interval disables the task.
+type Config struct {
+	Storage storage.Storage
+
+	// Retention is how long to keep deleted resources and resource history (0 = forever)
+	Retention         time.Duration
+	RetentionInterval time.Duration
+
+	VacuumInterval     time.Duration
+	CheckpointInterval time.Duration
+	IntegrityInterval  time.Duration
+}
+
+// Result is the outcome of the last run of a task.
+type Result struct {
+	LastRun  time.Time `json:"last_run"`
+	Duration string    `json:"duration"`
+	Status   string    `json:"status"` // "success" or "error"
+	Detail   string    `json:"detail,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// task is a scheduled maintenance job.
+type task struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) (detail string, err error)
+	next     time.Time
+}
+
+// Scheduler runs maintenance tasks on their intervals.
+type Scheduler struct {
+	storage   storage.Storage
+	retention time.Duration
+	tasks     []*task
+
+	mu      sync.Mutex
+	results map[string]*Result
+}
+
+// NewScheduler creates a new maintenance scheduler.
+func NewScheduler(cfg *Config) *Scheduler {
+	s := &Scheduler{
+		storage:   cfg.Storage,
+		retention: cfg.Retention,
+		results:   make(map[string]*Result),
+	}
+
+	if cfg.Retention > 0 {
+		s.addTask(TaskRetention, cfg.RetentionInterval, s.runRetention)
+	}
+	s.addTask(TaskVacuum, cfg.VacuumInterval, func(ctx context.Context) (string, error) {
+		return "", s.storage.Vacuum(ctx)
+	})
+	s.addTask(TaskCheckpoint, cfg.CheckpointInterval, func(ctx context.Context) (string, error) {
+		return "", s.storage.Checkpoint(ctx)
+	})
+	s.addTask(TaskIntegrity, cfg.IntegrityInterval, func(ctx context.Context) (string, error) {
+		return "", s.storage.IntegrityCheck(ctx)
+	})
+
+	return s
+}
+
+func (s *Scheduler) addTask(name string, interval time.Duration, run func(ctx context.Context) (string, error)) {
+	if interval <= 0 {
+		return
+	}
+	s.tasks = append(s.tasks, &task{name: name, interval: interval, run: run})
+}
+
+// Run runs the scheduler until ctx is cancelled. Tasks resume from their last
+// recorded run, so restarts don't reset the schedule.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.tasks) == 0 {
+		log.Info().Msg("Database maintenance disabled")
+		<-ctx.Done()
+		return nil
+	}
+
+	s.loadResults(ctx)
+
+	now := time.Now()
+	for _, t := range s.tasks {
+		t.next = now.Add(t.interval)
+		if r, ok := s.results[t.name]; ok && !r.LastRun.IsZero() {
+			t.next = r.LastRun.Add(t.interval)
+		}
+		log.Debug().
+			Str("task", t.name).
+			Dur("interval", t.interval).
+			Time("next_run", t.next).
+			Msg("Scheduled database maintenance task")
+	}
+
+	for {
+		t := s.nextTask()
+		timer := time.NewTimer(time.Until(t.next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		s.runTask(ctx, t)
+		t.next = time.Now().Add(t.interval)
+	}
+}
+
+// RunTask runs a single task immediately, by name.
+func (s *Scheduler) RunTask(ctx context.Context, name string) error {
+	for _, t := range s.tasks {
+		if t.name == name {
+			s.runTask(ctx, t)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown or disabled maintenance task: %s", name)
+}
+
+// Results returns a copy of the last result of every task.
+func (s *Scheduler) Results() map[string]Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]Result, len(s.results))
+	for name, r := range s.results {
+		out[name] = *r
+	}
+	return out
+}
+
+// nextTask returns the task due first.
+func (s *Scheduler) nextTask() *task {
+	next := s.tasks[0]
+	for _, t := range s.tasks[1:] {
+		if t.next.Before(next.next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// runTask runs t, logs the outcome and persists it. A run interrupted by
+// shutdown is not recorded, so the task is rescheduled from its last result.
+func (s *Scheduler) runTask(ctx context.Context, t *task) {
+	start := time.Now()
+	detail, err := t.run(ctx)
+	duration := time.Since(start)
+
+	if err != nil && ctx.Err() != nil {
+		log.Info().
+			Str("task", t.name).
+			Dur("duration", duration).
+			Msg("Database maintenance task interrupted by shutdown")
+		return
+	}
+
+	result := &Result{
+		LastRun:  start,
+		Duration: duration.Round(time.Millisecond).String(),
+		Status:   "success",
+		Detail:   detail,
+	}
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		log.Error().Err(err).
+			Str("task", t.name).
+			Dur("duration", duration).
+			Msg("Database maintenance task failed")
+	} else {
+		log.Info().
+			Str("task", t.name).
+			Str("detail", detail).
+			Dur("duration", duration).
+			Msg("Database maintenance task completed")
+	}
+
+	s.mu.Lock()
+	s.results[t.name] = result
+	data, marshalErr := json.Marshal(s.results)
+	s.mu.Unlock()
+
+	if marshalErr == nil {
+		if err := s.storage.SetSyncState(ctx, StatusKey, string(data)); err != nil {
+			log.Warn().Err(err).Msg("Failed to save maintenance status")
+		}
+	}
+}
+
+// runRetention removes deleted resources and resource history past the retention period.
+func (s *Scheduler) runRetention(ctx context.Context) (string, error) {
+	cutoff := time.Now().Add(-s.retention)
+
+	resources, err := s.storage.CleanupDeletedResources(ctx, cutoff)
+	if err != nil {
+		return "", fmt.Errorf("failed to clean up deleted resources: %w", err)
+	}
+	events, err := s.storage.CleanupResourceEvents(ctx, cutoff)
+	if err != nil {
+		return "", fmt.Errorf("failed to clean up resource history: %w", err)
+	}
+
+	return fmt.Sprintf("removed %d deleted resources and %d history events", resources, events), nil
+}
+
+// loadResults restores the last results saved by any instance.
+func (s *Scheduler) loadResults(ctx context.Context) {
+	results, err := LoadStatus(ctx, s.storage)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load maintenance status")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, r := range results {
+		r := r
+		s.results[name] = &r
+	}
+}
+
+// LoadStatus reads the last maintenance results from storage.
+// Returns nil if maintenance never ran.
+func LoadStatus(ctx context.Context, store storage.Storage) (map[string]Result, error) {
+	data, err := store.GetSyncState(ctx, StatusKey)
+	if err != nil || data == "" {
+		return nil, err
+	}
+
+	var results map[string]Result
+	if err := json.Unmarshal([]byte(data), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
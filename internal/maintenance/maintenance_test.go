@@ -0,0 +1,130 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+func newTestStorage(t *testing.T) *storage.SQLiteStorage {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "labelgate.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Initialize(context.Background()); err != nil {
+		t.Fatalf("failed to initialize storage: %v", err)
+	}
+	return store
+}
+
+func TestScheduler_RunsDueTasks(t *testing.T) {
+	store := newTestStorage(t)
+	s := NewScheduler(&Config{
+		Storage:            store,
+		Retention:          time.Hour,
+		RetentionInterval:  20 * time.Millisecond,
+		CheckpointInterval: 20 * time.Millisecond,
+		IntegrityInterval:  20 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Run(ctx) }()
+
+	// Stop only once every task has run, so none is cut short
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.Results()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected every task to run, got %v", s.Results())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Fatalf("scheduler returned error: %v", err)
+	}
+
+	results := s.Results()
+	for _, name := range []string{TaskRetention, TaskCheckpoint, TaskIntegrity} {
+		r, ok := results[name]
+		if !ok {
+			t.Errorf("expected %s to have run", name)
+			continue
+		}
+		if r.Status != "success" {
+			t.Errorf("%s: expected success, got %s (%s)", name, r.Status, r.Error)
+		}
+	}
+	if _, ok := results[TaskVacuum]; ok {
+		t.Errorf("vacuum has no interval and must not run")
+	}
+
+	// Results are persisted for the API
+	saved, err := LoadStatus(context.Background(), store)
+	if err != nil {
+		t.Fatalf("failed to load status: %v", err)
+	}
+	if len(saved) != 3 {
+		t.Errorf("expected 3 persisted results, got %d", len(saved))
+	}
+}
+
+func TestScheduler_ResumesFromLastRun(t *testing.T) {
+	store := newTestStorage(t)
+	ctx := context.Background()
+
+	first := NewScheduler(&Config{Storage: store, VacuumInterval: time.Hour})
+	if err := first.RunTask(ctx, TaskVacuum); err != nil {
+		t.Fatalf("failed to run vacuum: %v", err)
+	}
+	lastRun := first.Results()[TaskVacuum].LastRun
+
+	// A restarted scheduler does not vacuum again before the interval elapsed
+	second := NewScheduler(&Config{Storage: store, VacuumInterval: time.Hour})
+	runCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	second.Run(runCtx)
+
+	if got := second.Results()[TaskVacuum].LastRun; !got.Equal(lastRun) {
+		t.Errorf("expected vacuum not to rerun, last run moved from %v to %v", lastRun, got)
+	}
+
+	if err := second.RunTask(ctx, TaskIntegrity); err == nil {
+		t.Errorf("expected error for disabled task")
+	}
+}
+
+func TestScheduler_ShutdownIsNotAFailure(t *testing.T) {
+	store := newTestStorage(t)
+	s := NewScheduler(&Config{Storage: store})
+	s.addTask("slow", time.Hour, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	s.addTask("broken", time.Hour, func(ctx context.Context) (string, error) {
+		return "", errors.New("disk I/O error")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.RunTask(ctx, "slow"); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := s.Results()["slow"]; ok {
+		t.Errorf("expected a run cancelled by shutdown not to be recorded, got %+v", r)
+	}
+
+	if err := s.RunTask(context.Background(), "broken"); err != nil {
+		t.Fatal(err)
+	}
+	if r := s.Results()["broken"]; r.Status != "error" || r.Error != "disk I/O error" {
+		t.Errorf("expected a failed run to be recorded, got %+v", r)
+	}
+}
@@ -0,0 +1,176 @@
+// Package metrics defines the Prometheus metrics exported by labelgate at
+// /metrics. Components update the collectors here directly.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+const namespace = "labelgate"
+
+// Registry holds every labelgate metric plus the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ReconcileDuration observes reconcile pass durations by scope ("full" or "scoped").
+	ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of reconcile passes.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"scope"})
+
+	// ReconcileTotal counts reconcile passes by scope and result ("success" or "error").
+	ReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Reconcile passes by outcome.",
+	}, []string{"scope", "result"})
+
+	// LastSuccessfulSync is the Unix time of the last reconcile pass without errors.
+	LastSuccessfulSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last reconcile pass that completed without errors.",
+	})
+
+	// CloudflareRequests counts Cloudflare API requests by endpoint, method and
+	// HTTP status ("error" when no response was received).
+	CloudflareRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cloudflare_requests_total",
+		Help:      "Cloudflare API requests by endpoint, method and status.",
+	}, []string{"endpoint", "method", "status"})
+
+	// CloudflareRequestDuration observes Cloudflare API request latency.
+	CloudflareRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cloudflare_request_duration_seconds",
+		Help:      "Cloudflare API request latency by endpoint and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "method"})
+
+	// AgentConnected is 1 while an agent is connected to this instance, 0 otherwise.
+	AgentConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "agent_connected",
+		Help:      "Whether an agent is connected (1) or not (0).",
+	}, []string{"agent"})
+
+	// AgentReportLatency observes the delay between an agent building a report
+	// and the main instance receiving it (assumes synchronised clocks).
+	AgentReportLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "agent_report_latency_seconds",
+		Help:      "Delay between an agent sending a report and it being received.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"agent"})
+
+	// DockerEventLag observes the delay between Docker emitting a container
+	// event and the reconciler handling it.
+	DockerEventLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "docker_event_lag_seconds",
+		Help:      "Delay between a Docker container event and its handling.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	})
+
+	// NotificationsTotal counts notification deliveries by sink and result
+	// ("sent", "failed" after all retries, or "dropped" when the queue is full).
+	NotificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notification deliveries by sink and result.",
+	}, []string{"sink", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ReconcileDuration,
+		ReconcileTotal,
+		LastSuccessfulSync,
+		CloudflareRequests,
+		CloudflareRequestDuration,
+		AgentConnected,
+		AgentReportLatency,
+		DockerEventLag,
+		NotificationsTotal,
+	)
+}
+
+// Handler serves the metrics in Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// resourcesDesc describes the resource counts read from storage at scrape time.
+var resourcesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "resources"),
+	"Managed resources by type and status.",
+	[]string{"type", "status"}, nil,
+)
+
+// resourceCollector reports resource counts straight from storage, so the
+// numbers are correct on every instance and after restarts.
+type resourceCollector struct {
+	store storage.Storage
+}
+
+// NewResourceCollector returns a collector for labelgate_resources backed by store.
+func NewResourceCollector(store storage.Storage) prometheus.Collector {
+	return &resourceCollector{store: store}
+}
+
+func (c *resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourcesDesc
+}
+
+func (c *resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	groups, err := c.store.CountResourcesByGroup(ctx, storage.ResourceFilter{})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to count resources for metrics")
+		ch <- prometheus.NewInvalidMetric(resourcesDesc, err)
+		return
+	}
+
+	counts := make(map[[2]string]int)
+	for _, g := range groups {
+		counts[[2]string{string(g.ResourceType), string(g.Status)}] += g.Count
+	}
+	for key, n := range counts {
+		ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, float64(n), key[0], key[1])
+	}
+}
+
+// idSegment matches path segments holding Cloudflare object IDs (32 hex
+// characters or UUIDs), which would make endpoint labels unbounded.
+var idSegment = regexp.MustCompile(`^([0-9a-fA-F]{32}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// CloudflareEndpoint turns a Cloudflare API path into a low-cardinality
+// endpoint label, e.g. /client/v4/zones/<id>/dns_records -> /zones/{id}/dns_records.
+func CloudflareEndpoint(path string) string {
+	path = strings.TrimPrefix(path, "/client/v4")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
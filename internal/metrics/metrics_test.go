@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+func TestCloudflareEndpoint(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/client/v4/zones", "/zones"},
+		{"/client/v4/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records", "/zones/{id}/dns_records"},
+		{"/client/v4/zones/023e105f4ecef8ad9ca31a8372d0c353/dns_records/372e67954025e0ba6aaa6d586b9e0b59", "/zones/{id}/dns_records/{id}"},
+		{"/client/v4/accounts/023e105f4ecef8ad9ca31a8372d0c353/cfd_tunnel/f70ff985-a4ef-4643-bbbc-4a0ed4fc8415/configurations", "/accounts/{id}/cfd_tunnel/{id}/configurations"},
+		{"/client/v4/user/tokens/verify", "/user/tokens/verify"},
+	}
+	for _, tt := range tests {
+		if got := CloudflareEndpoint(tt.path); got != tt.want {
+			t.Errorf("CloudflareEndpoint(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResourceCollector(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewSQLiteStorage(t.TempDir() + "/labelgate.db")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx); err != nil {
+		t.Fatalf("failed to initialize storage: %v", err)
+	}
+
+	for _, r := range []*storage.ManagedResource{
+		{ResourceType: storage.ResourceTypeDNS, Hostname: "a.example.com", RecordType: "A", ServiceName: "a", Status: storage.StatusActive},
+		{ResourceType: storage.ResourceTypeDNS, Hostname: "b.example.com", RecordType: "A", ServiceName: "b", Status: storage.StatusActive},
+		{ResourceType: storage.ResourceTypeDNS, Hostname: "c.example.com", RecordType: "A", ServiceName: "c", Status: storage.StatusOrphaned},
+	} {
+		if err := store.SaveResource(ctx, r); err != nil {
+			t.Fatalf("failed to save resource: %v", err)
+		}
+	}
+
+	expected := `
+# HELP labelgate_resources Managed resources by type and status.
+# TYPE labelgate_resources gauge
+labelgate_resources{status="active",type="dns"} 2
+labelgate_resources{status="orphaned",type="dns"} 1
+`
+	if err := testutil.CollectAndCompare(NewResourceCollector(store), strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package notify delivers selected live events (resource errors, agent
+// disconnects, orphan cleanups, drift) to outbound webhooks and chat services.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/metrics"
+)
+
+// TypeTest is the event type of notifications sent by Test.
+const TypeTest events.Type = "notification.test"
+
+// DefaultEvents are the events sent to sinks that don't configure a filter.
+var DefaultEvents = []string{
+	"resource.error",
+	string(events.TypeAgentDisconnected),
+	string(events.TypeOrphanCleanup),
+	string(events.TypeOrphanCleanupFailed),
+	string(events.TypeDriftDetected),
+}
+
+// Notification is a rendered event ready to be delivered.
+type Notification struct {
+	Event   events.Event
+	Title   string
+	Message string
+}
+
+// Sink delivers notifications to one target.
+type Sink interface {
+	Send(ctx context.Context, n *Notification) error
+}
+
+// sinkEntry is a configured sink with its event filter.
+type sinkEntry struct {
+	name   string
+	kind   string
+	sink   Sink
+	filter events.Filter
+}
+
+// delivery is a queued notification for one sink.
+type delivery struct {
+	sink    *sinkEntry
+	n       *Notification
+	attempt int
+}
+
+// Notifier subscribes to the event bus and delivers matching events to every
+// configured sink, retrying failed deliveries with exponential backoff.
+type Notifier struct {
+	sinks      []*sinkEntry
+	attempts   int
+	retryDelay time.Duration
+	queue      chan *delivery
+	wg         sync.WaitGroup
+}
+
+// New creates a notifier for the sinks in cfg.
+func New(cfg *config.NotificationsConfig) (*Notifier, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	n := &Notifier{
+		attempts:   cfg.RetryAttempts,
+		retryDelay: cfg.RetryDelay,
+		queue:      make(chan *delivery, cfg.QueueSize),
+	}
+
+	names := make([]string, 0, len(cfg.Sinks))
+	for name := range cfg.Sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sinkCfg := cfg.Sinks[name]
+		sink, err := NewSink(client, &sinkCfg)
+		if err != nil {
+			return nil, fmt.Errorf("notification sink %s: %w", name, err)
+		}
+		types := sinkCfg.Events
+		if len(types) == 0 {
+			types = DefaultEvents
+		}
+		n.sinks = append(n.sinks, &sinkEntry{
+			name:   name,
+			kind:   sinkCfg.Type,
+			sink:   sink,
+			filter: events.Filter{Types: types},
+		})
+	}
+	return n, nil
+}
+
+// SinkInfo describes a configured sink.
+type SinkInfo struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Events []string `json:"events"`
+}
+
+// Sinks returns the configured sinks, sorted by name.
+func (n *Notifier) Sinks() []SinkInfo {
+	infos := make([]SinkInfo, 0, len(n.sinks))
+	for _, s := range n.sinks {
+		infos = append(infos, SinkInfo{Name: s.name, Type: s.kind, Events: s.filter.Types})
+	}
+	return infos
+}
+
+// Run delivers events from bus until ctx is cancelled.
+func (n *Notifier) Run(ctx context.Context, bus *events.Bus) error {
+	sub := bus.Subscribe(events.Filter{}, 0)
+	defer sub.Close()
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliverLoop(ctx)
+	}()
+	defer n.wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			n.dispatch(e)
+		}
+	}
+}
+
+// dispatch queues e for every sink whose filter matches.
+func (n *Notifier) dispatch(e events.Event) {
+	var rendered *Notification
+	for _, s := range n.sinks {
+		if !s.filter.Match(e) {
+			continue
+		}
+		if rendered == nil {
+			rendered = Render(e)
+		}
+		n.enqueue(&delivery{sink: s, n: rendered})
+	}
+}
+
+// enqueue adds d to the delivery queue, dropping it if the queue is full.
+func (n *Notifier) enqueue(d *delivery) {
+	select {
+	case n.queue <- d:
+	default:
+		metrics.NotificationsTotal.WithLabelValues(d.sink.name, "dropped").Inc()
+		log.Warn().
+			Str("sink", d.sink.name).
+			Str("event", string(d.n.Event.Type)).
+			Msg("Notification queue full, dropping notification")
+	}
+}
+
+// deliverLoop sends queued notifications one at a time.
+func (n *Notifier) deliverLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-n.queue:
+			n.deliver(ctx, d)
+		}
+	}
+}
+
+// deliver sends d and schedules a retry if it fails.
+func (n *Notifier) deliver(ctx context.Context, d *delivery) {
+	d.attempt++
+	err := d.sink.sink.Send(ctx, d.n)
+	if err == nil {
+		metrics.NotificationsTotal.WithLabelValues(d.sink.name, "sent").Inc()
+		return
+	}
+
+	if d.attempt >= n.attempts || ctx.Err() != nil {
+		metrics.NotificationsTotal.WithLabelValues(d.sink.name, "failed").Inc()
+		log.Error().Err(err).
+			Str("sink", d.sink.name).
+			Str("event", string(d.n.Event.Type)).
+			Int("attempts", d.attempt).
+			Msg("Failed to deliver notification, giving up")
+		return
+	}
+
+	delay := n.retryDelay << (d.attempt - 1)
+	log.Warn().Err(err).
+		Str("sink", d.sink.name).
+		Str("event", string(d.n.Event.Type)).
+		Int("attempt", d.attempt).
+		Dur("retry_in", delay).
+		Msg("Failed to deliver notification, retrying")
+	time.AfterFunc(delay, func() {
+		if ctx.Err() == nil {
+			n.enqueue(d)
+		}
+	})
+}
+
+// TestResult is the outcome of a test notification for one sink.
+type TestResult struct {
+	Sink  string `json:"sink"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ErrUnknownSink is returned by Test for a sink name that isn't configured.
+type ErrUnknownSink string
+
+func (e ErrUnknownSink) Error() string {
+	return "unknown notification sink: " + string(e)
+}
+
+// Test sends a test notification synchronously, without retries, to the named
+// sink or to every sink when name is empty.
+func (n *Notifier) Test(ctx context.Context, name string) ([]TestResult, error) {
+	notification := Render(events.Event{Type: TypeTest, Time: time.Now().UTC()})
+
+	var results []TestResult
+	for _, s := range n.sinks {
+		if name != "" && s.name != name {
+			continue
+		}
+		result := TestResult{Sink: s.name, OK: true}
+		if err := s.sink.Send(ctx, notification); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	if name != "" && len(results) == 0 {
+		return nil, ErrUnknownSink(name)
+	}
+	return results, nil
+}
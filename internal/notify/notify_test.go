@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// recorder is a test HTTP endpoint that records request bodies and fails the
+// first failures requests.
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   []string
+	received chan struct{}
+}
+
+func newRecorder(failures int) (*recorder, *httptest.Server) {
+	rec := &recorder{failures: failures, received: make(chan struct{}, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, string(body))
+		fail := len(rec.requests) <= rec.failures
+		rec.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		rec.received <- struct{}{}
+	}))
+	return rec, srv
+}
+
+func (r *recorder) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for request %d", i+1)
+		}
+	}
+}
+
+func testConfig(sinks map[string]config.NotificationSinkConfig) *config.NotificationsConfig {
+	return &config.NotificationsConfig{
+		Enabled:       true,
+		Sinks:         sinks,
+		RetryAttempts: 3,
+		RetryDelay:    10 * time.Millisecond,
+		QueueSize:     10,
+		Timeout:       time.Second,
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	rec, srv := newRecorder(0)
+	defer srv.Close()
+
+	n, err := New(testConfig(map[string]config.NotificationSinkConfig{
+		"hook": {Type: "webhook", URL: srv.URL, Secret: "s3cret"},
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	results, err := n.Test(context.Background(), "hook")
+	if err != nil || len(results) != 1 || !results[0].OK {
+		t.Fatalf("Test = %+v, %v", results, err)
+	}
+
+	req, body := rec.requests[0], rec.bodies[0]
+	if got, want := req.Header.Get(HeaderSignature), Sign("s3cret", []byte(body)); got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+	if got := req.Header.Get(HeaderEvent); got != string(TypeTest) {
+		t.Fatalf("event header = %q", got)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Type != TypeTest || payload.Title == "" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestTestUnknownSink(t *testing.T) {
+	n, err := New(testConfig(nil))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := n.Test(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error for unknown sink")
+	}
+}
+
+func TestDispatchFiltersAndRetries(t *testing.T) {
+	rec, srv := newRecorder(1)
+	defer srv.Close()
+
+	n, err := New(testConfig(map[string]config.NotificationSinkConfig{
+		"chat": {Type: "slack", URL: srv.URL},
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.deliverLoop(ctx)
+
+	// Not in the default filter: must not be delivered
+	n.dispatch(events.Event{Type: events.TypeReconcileStarted})
+	n.dispatch(events.ResourceEvent(&storage.ResourceEvent{
+		ResourceType: storage.ResourceTypeDNS,
+		Hostname:     "app.example.com",
+		Action:       storage.ActionError,
+		Error:        "boom",
+		CreatedAt:    time.Now(),
+	}))
+
+	// First attempt fails, the retry succeeds
+	rec.wait(t, 2)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(rec.bodies))
+	}
+	for _, body := range rec.bodies {
+		if !strings.Contains(body, "Resource error: app.example.com") || !strings.Contains(body, "boom") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		event events.Event
+		title string
+	}{
+		{events.Event{Type: events.TypeAgentDisconnected, Data: map[string]string{"agent_id": "edge"}}, "Agent disconnected: edge"},
+		{events.Event{Type: events.TypeOrphanCleanup, Hostnames: []string{"a.example.com", "b.example.com"}, Data: map[string]any{"remove_delay": "30m0s"}}, "Deleting 2 orphaned resource(s)"},
+		{events.Event{Type: events.TypeOrphanCleanupFailed, Hostnames: []string{"a.example.com"}, Data: map[string]any{"error": "forbidden"}}, "Failed to delete orphaned resource: a.example.com"},
+		{events.Event{Type: events.TypeDriftDetected, Hostnames: []string{"a.example.com"}, Data: map[string]any{"tunnel": "default", "tunnel_id": "t1"}}, "Drift detected in tunnel default"},
+		{events.Event{Type: "container.start", Hostnames: []string{"a.example.com"}}, "container.start"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.event).Title; got != tt.title {
+			t.Errorf("Render(%s).Title = %q, want %q", tt.event.Type, got, tt.title)
+		}
+	}
+}
+
+func TestSinkPayloads(t *testing.T) {
+	rec, srv := newRecorder(0)
+	defer srv.Close()
+
+	n, err := New(testConfig(map[string]config.NotificationSinkConfig{
+		"discord": {Type: "discord", URL: srv.URL + "/discord"},
+		"gotify":  {Type: "gotify", URL: srv.URL, Token: "apptoken", Priority: 5},
+		"ntfy":    {Type: "ntfy", URL: srv.URL + "/alerts", Token: "tk", Priority: 4},
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := n.Test(context.Background(), ""); err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+
+	// Sinks are sent in name order
+	discord, gotify, ntfy := rec.requests[0], rec.requests[1], rec.requests[2]
+	if !strings.Contains(rec.bodies[0], `"content"`) {
+		t.Errorf("discord body = %s", rec.bodies[0])
+	}
+	if gotify.URL.Path != "/message" || gotify.Header.Get("X-Gotify-Key") != "apptoken" || !strings.Contains(rec.bodies[1], `"priority":5`) {
+		t.Errorf("gotify request = %s %v %s", gotify.URL.Path, gotify.Header, rec.bodies[1])
+	}
+	if ntfy.Header.Get("Title") != "Test notification" || ntfy.Header.Get("Priority") != "4" || ntfy.Header.Get("Authorization") != "Bearer tk" {
+		t.Errorf("ntfy headers = %v", ntfy.Header)
+	}
+	if discord.URL.Path != "/discord" {
+		t.Errorf("discord path = %s", discord.URL.Path)
+	}
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// maxListed is the number of hostnames listed in a message before truncating.
+const maxListed = 10
+
+// Render turns an event into a human-readable notification.
+func Render(e events.Event) *Notification {
+	n := &Notification{Event: e}
+
+	switch data := e.Data.(type) {
+	case *storage.ResourceEvent:
+		n.Title, n.Message = renderResource(data)
+		return n
+	case map[string]string:
+		if e.Type == events.TypeAgentDisconnected {
+			n.Title = "Agent disconnected: " + data["agent_id"]
+			n.Message = fmt.Sprintf("Agent %s disconnected; its containers are kept until it reconnects or is removed.", data["agent_id"])
+			return n
+		}
+		if e.Type == events.TypeAgentConnected {
+			n.Title = "Agent connected: " + data["agent_id"]
+			n.Message = fmt.Sprintf("Agent %s connected (%s mode).", data["agent_id"], data["mode"])
+			return n
+		}
+	case map[string]any:
+		switch e.Type {
+		case events.TypeOrphanCleanup:
+			n.Title = fmt.Sprintf("Deleting %d orphaned resource(s)", len(e.Hostnames))
+			n.Message = fmt.Sprintf("Removal delay (%v) expired, deleting from Cloudflare: %s", data["remove_delay"], listHostnames(e.Hostnames))
+			return n
+		case events.TypeOrphanCleanupFailed:
+			n.Title = "Failed to delete orphaned resource: " + listHostnames(e.Hostnames)
+			n.Message = fmt.Sprintf("Deleting %s from Cloudflare failed and will be retried: %v", listHostnames(e.Hostnames), data["error"])
+			return n
+		case events.TypeDriftDetected:
+			n.Title = fmt.Sprintf("Drift detected in tunnel %v", data["tunnel"])
+			n.Message = fmt.Sprintf("The configuration of tunnel %v (%v) was changed outside labelgate and is being restored for: %s", data["tunnel"], data["tunnel_id"], listHostnames(e.Hostnames))
+			return n
+		case events.TypeReconcileFinished:
+			if errMsg, ok := data["error"].(string); ok {
+				n.Title = "Reconcile failed"
+				n.Message = errMsg
+				return n
+			}
+			n.Title = "Reconcile finished"
+			n.Message = fmt.Sprintf("Reconcile %v finished in %vms.", data["reconcile_id"], data["duration_ms"])
+			return n
+		}
+	}
+
+	if e.Type == TypeTest {
+		n.Title = "Test notification"
+		n.Message = "This is a test notification from labelgate."
+		return n
+	}
+
+	n.Title = string(e.Type)
+	n.Message = listHostnames(e.Hostnames)
+	return n
+}
+
+// renderResource describes a resource history entry.
+func renderResource(e *storage.ResourceEvent) (string, string) {
+	owner := e.ContainerName
+	if e.AgentID != "" {
+		owner += " on agent " + e.AgentID
+	}
+
+	switch e.Action {
+	case storage.ActionError:
+		return "Resource error: " + e.Hostname,
+			fmt.Sprintf("%s for %s (container %s) failed: %s", e.ResourceType, e.Hostname, owner, e.Error)
+	case storage.ActionOrphan:
+		return "Resource orphaned: " + e.Hostname,
+			fmt.Sprintf("%s for %s is no longer referenced by a running container (last owner %s).", e.ResourceType, e.Hostname, owner)
+	default:
+		return fmt.Sprintf("Resource %s: %s", e.Action, e.Hostname),
+			fmt.Sprintf("%s for %s (container %s): %s", e.ResourceType, e.Hostname, owner, e.Action)
+	}
+}
+
+// listHostnames joins hostnames, truncating long lists.
+func listHostnames(hostnames []string) string {
+	if len(hostnames) <= maxListed {
+		return strings.Join(hostnames, ", ")
+	}
+	return strings.Join(hostnames[:maxListed], ", ") + fmt.Sprintf(" and %d more", len(hostnames)-maxListed)
+}
@@ -0,0 +1,195 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/events"
+)
+
+// Headers set on generic webhook deliveries.
+const (
+	HeaderEvent     = "X-Labelgate-Event"
+	HeaderDelivery  = "X-Labelgate-Delivery"
+	HeaderSignature = "X-Labelgate-Signature"
+)
+
+// NewSink creates the sink for cfg.Type.
+func NewSink(client *http.Client, cfg *config.NotificationSinkConfig) (Sink, error) {
+	base := httpSink{client: client, url: cfg.URL, headers: cfg.Headers}
+	switch cfg.Type {
+	case "webhook":
+		return &webhookSink{httpSink: base, secret: cfg.Secret}, nil
+	case "slack":
+		return &slackSink{httpSink: base}, nil
+	case "discord":
+		return &discordSink{httpSink: base}, nil
+	case "ntfy":
+		return &ntfySink{httpSink: base, token: cfg.Token, priority: cfg.Priority}, nil
+	case "gotify":
+		base.url = strings.TrimSuffix(base.url, "/")
+		if !strings.HasSuffix(base.url, "/message") {
+			base.url += "/message"
+		}
+		return &gotifySink{httpSink: base, token: cfg.Token, priority: cfg.Priority}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s", cfg.Type)
+	}
+}
+
+// httpSink posts a body to a URL and treats any non-2xx response as a failure.
+type httpSink struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+func (s *httpSink) post(ctx context.Context, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (s *httpSink) postJSON(ctx context.Context, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, "application/json", body, headers)
+}
+
+// WebhookPayload is the body of generic webhook deliveries.
+type WebhookPayload struct {
+	ID        uint64      `json:"id"`
+	Type      events.Type `json:"type"`
+	Time      time.Time   `json:"time"`
+	Hostnames []string    `json:"hostnames,omitempty"`
+	Title     string      `json:"title"`
+	Message   string      `json:"message"`
+	Data      any         `json:"data,omitempty"`
+}
+
+// webhookSink posts the full event as JSON. With a secret, the body is signed
+// with HMAC-SHA256 in the X-Labelgate-Signature header ("sha256=<hex>").
+type webhookSink struct {
+	httpSink
+	secret string
+}
+
+func (s *webhookSink) Send(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(WebhookPayload{
+		ID:        n.Event.ID,
+		Type:      n.Event.Type,
+		Time:      n.Event.Time,
+		Hostnames: n.Event.Hostnames,
+		Title:     n.Title,
+		Message:   n.Message,
+		Data:      n.Event.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		HeaderEvent:    string(n.Event.Type),
+		HeaderDelivery: strconv.FormatUint(n.Event.ID, 10),
+	}
+	if s.secret != "" {
+		headers[HeaderSignature] = Sign(s.secret, body)
+	}
+	return s.post(ctx, "application/json", body, headers)
+}
+
+// Sign returns the X-Labelgate-Signature value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// slackSink posts a Slack incoming webhook message (also accepted by
+// Mattermost and Rocket.Chat).
+type slackSink struct {
+	httpSink
+}
+
+func (s *slackSink) Send(ctx context.Context, n *Notification) error {
+	return s.postJSON(ctx, map[string]string{"text": "*" + n.Title + "*\n" + n.Message}, nil)
+}
+
+// discordSink posts a Discord webhook message.
+type discordSink struct {
+	httpSink
+}
+
+func (s *discordSink) Send(ctx context.Context, n *Notification) error {
+	return s.postJSON(ctx, map[string]string{"content": "**" + n.Title + "**\n" + n.Message}, nil)
+}
+
+// ntfySink publishes to an ntfy topic URL.
+type ntfySink struct {
+	httpSink
+	token    string
+	priority int
+}
+
+func (s *ntfySink) Send(ctx context.Context, n *Notification) error {
+	category, _, _ := strings.Cut(string(n.Event.Type), ".")
+	headers := map[string]string{
+		"Title": n.Title,
+		"Tags":  "labelgate," + category,
+	}
+	if s.priority > 0 {
+		headers["Priority"] = strconv.Itoa(s.priority)
+	}
+	if s.token != "" {
+		headers["Authorization"] = "Bearer " + s.token
+	}
+	return s.post(ctx, "text/plain; charset=utf-8", []byte(n.Message), headers)
+}
+
+// gotifySink posts a Gotify application message.
+type gotifySink struct {
+	httpSink
+	token    string
+	priority int
+}
+
+func (s *gotifySink) Send(ctx context.Context, n *Notification) error {
+	payload := map[string]any{"title": n.Title, "message": n.Message}
+	if s.priority > 0 {
+		payload["priority"] = s.priority
+	}
+	return s.postJSON(ctx, payload, map[string]string{"X-Gotify-Key": s.token})
+}
@@ -7,10 +7,12 @@ import (
 	"strings"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/channinghe/labelgate/internal/cloudflare"
 	"github.com/channinghe/labelgate/internal/operator"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
 	"github.com/channinghe/labelgate/internal/types"
 )
 
@@ -21,6 +23,7 @@ var _ operator.AccessOperator = (*AccessOperatorImpl)(nil)
 type AccessOperatorImpl struct {
 	credManager *cloudflare.CredentialManager
 	storage     storage.Storage
+	workers     int // max concurrent application operations
 }
 
 // NewAccessOperator creates a new Access operator.
@@ -28,6 +31,14 @@ func NewAccessOperator(credManager *cloudflare.CredentialManager, store storage.
 	return &AccessOperatorImpl{
 		credManager: credManager,
 		storage:     store,
+		workers:     operator.DefaultWorkers,
+	}
+}
+
+// SetWorkers sets the maximum number of concurrent application operations.
+func (o *AccessOperatorImpl) SetWorkers(n int) {
+	if n > 0 {
+		o.workers = n
 	}
 }
 
@@ -63,7 +74,7 @@ func (o *AccessOperatorImpl) CheckPermissions(ctx context.Context) error {
 // This method is called by the reconciler with the list of all desired containers.
 // The reconciler is responsible for resolving access references and creating
 // ResolvedAccessBindings. This operator simply acts on the resolved bindings.
-func (o *AccessOperatorImpl) Reconcile(ctx context.Context, desired []*types.ParsedContainer) error {
+func (o *AccessOperatorImpl) Reconcile(ctx context.Context, desired []*types.ParsedContainer, scope operator.Scope) error {
 	// Access reconciliation is handled through ResolveAndReconcileAccess
 	// which is called by the reconciler directly.
 	// The standard Reconcile interface is a no-op for access.
@@ -71,10 +82,13 @@ func (o *AccessOperatorImpl) Reconcile(ctx context.Context, desired []*types.Par
 }
 
 // ReconcileBindings reconciles access bindings (called by the reconciler).
-func (o *AccessOperatorImpl) ReconcileBindings(ctx context.Context, bindings []*types.ResolvedAccessBinding) error {
+func (o *AccessOperatorImpl) ReconcileBindings(ctx context.Context, bindings []*types.ResolvedAccessBinding, scope operator.Scope) error {
 	// Build desired state map: hostname -> binding
 	desiredMap := make(map[string]*types.ResolvedAccessBinding)
 	for _, binding := range bindings {
+		if !scope.Has(binding.Hostname) {
+			continue
+		}
 		desiredMap[binding.Hostname] = binding
 	}
 
@@ -91,70 +105,33 @@ func (o *AccessOperatorImpl) ReconcileBindings(ctx context.Context, bindings []*
 	// Build current state map
 	currentMap := make(map[string]*storage.ManagedResource)
 	for _, r := range resources {
+		if !scope.Has(r.Hostname) {
+			continue
+		}
 		currentMap[r.Hostname] = r
 	}
 
-	// Create or update
+	// Create or update concurrently, one task per hostname
+	tasks := make([]operator.Task, 0, len(desiredMap))
 	for hostname, binding := range desiredMap {
 		existing, hasExisting := currentMap[hostname]
-
-		if hasExisting {
-			// Update existing (also retry errors, reactivate orphaned)
-			if err := o.updateAccess(ctx, existing, binding); err != nil {
-				log.Error().Err(err).
-					Str("hostname", hostname).
-					Msg("Failed to update Access Application")
-				// Mark resource as error
-				if updateErr := o.storage.UpdateResourceError(ctx, existing.ID, storage.StatusError, err.Error()); updateErr != nil {
-					log.Error().Err(updateErr).Str("hostname", hostname).Msg("Failed to update resource error status")
-				}
-			} else {
-				// Success: clear any previous error, reactivate if orphaned
-				if existing.LastError != "" || existing.Status != storage.StatusActive {
-					_ = o.storage.UpdateResourceError(ctx, existing.ID, storage.StatusActive, "")
+		delete(currentMap, hostname)
+
+		tasks = append(tasks, operator.Task{
+			Key: hostname,
+			Run: func(ctx context.Context) {
+				ctx, span := tracing.Start(ctx, "access.application", attribute.String("labelgate.hostname", hostname))
+				defer span.End()
+
+				if hasExisting {
+					o.reconcileExisting(ctx, existing, binding)
+				} else {
+					o.reconcileNew(ctx, binding)
 				}
-			}
-			delete(currentMap, hostname)
-		} else {
-			// Create new
-			resource, err := o.EnsureAccess(ctx, binding)
-			if err != nil {
-				log.Error().Err(err).
-					Str("hostname", hostname).
-					Msg("Failed to create Access Application")
-			errAppName := binding.PolicyDef.AppName
-			if errAppName == "" {
-				errAppName = fmt.Sprintf("labelgate:%s", binding.Hostname)
-			}
-			errDecision := ""
-			if len(binding.PolicyDef.Policies) > 0 {
-				errDecision = binding.PolicyDef.Policies[0].Decision
-			}
-			errResource := &storage.ManagedResource{
-				ResourceType:     storage.ResourceTypeAccessApp,
-				Hostname:         hostname,
-				AccessAppName:    errAppName,
-				AccessPolicyName: binding.PolicyDef.Name,
-				AccessDecision:   errDecision,
-				ContainerID:      binding.ContainerID,
-				ContainerName:    binding.ContainerName,
-				ServiceName:      binding.ServiceName,
-				AgentID:          binding.AgentID,
-				Status:           storage.StatusError,
-				LastError:        err.Error(),
-				CleanupEnabled:   binding.Cleanup,
-			}
-				if saveErr := o.storage.SaveResource(ctx, errResource); saveErr != nil {
-					log.Error().Err(saveErr).Str("hostname", hostname).Msg("Failed to save error resource")
-				}
-				continue
-			}
-			log.Info().
-				Str("hostname", hostname).
-				Str("resource_id", resource.ID).
-				Msg("Created Access Application resource")
-		}
+			},
+		})
 	}
+	operator.RunTasks(ctx, o.workers, tasks)
 
 	// Handle orphaned resources (not in desired state)
 	for hostname, resource := range currentMap {
@@ -177,6 +154,75 @@ func (o *AccessOperatorImpl) ReconcileBindings(ctx context.Context, bindings []*
 	return nil
 }
 
+// reconcileExisting updates a stored Access Application (also retries errors
+// and reactivates orphaned resources).
+func (o *AccessOperatorImpl) reconcileExisting(ctx context.Context, existing *storage.ManagedResource, binding *types.ResolvedAccessBinding) {
+	// Rebind the application first, so ownership is right even if the update fails
+	operator.Handover(ctx, o.storage, existing, storage.ResourceOwner{
+		ContainerID:   binding.ContainerID,
+		ContainerName: binding.ContainerName,
+		ServiceName:   binding.ServiceName,
+		AgentID:       binding.AgentID,
+	})
+
+	if err := o.updateAccess(ctx, existing, binding); err != nil {
+		log.Error().Err(err).
+			Str("hostname", binding.Hostname).
+			Msg("Failed to update Access Application")
+		// Mark resource as error
+		if updateErr := o.storage.UpdateResourceError(ctx, existing.ID, storage.StatusError, err.Error()); updateErr != nil {
+			log.Error().Err(updateErr).Str("hostname", binding.Hostname).Msg("Failed to update resource error status")
+		}
+		return
+	}
+
+	// Success: clear any previous error, reactivate if orphaned
+	if existing.LastError != "" || existing.Status != storage.StatusActive {
+		_ = o.storage.UpdateResourceError(ctx, existing.ID, storage.StatusActive, "")
+	}
+}
+
+// reconcileNew creates an Access Application that has no stored resource yet.
+func (o *AccessOperatorImpl) reconcileNew(ctx context.Context, binding *types.ResolvedAccessBinding) {
+	hostname := binding.Hostname
+	resource, err := o.EnsureAccess(ctx, binding)
+	if err != nil {
+		log.Error().Err(err).
+			Str("hostname", hostname).
+			Msg("Failed to create Access Application")
+		errAppName := binding.PolicyDef.AppName
+		if errAppName == "" {
+			errAppName = fmt.Sprintf("labelgate:%s", binding.Hostname)
+		}
+		errDecision := ""
+		if len(binding.PolicyDef.Policies) > 0 {
+			errDecision = binding.PolicyDef.Policies[0].Decision
+		}
+		errResource := &storage.ManagedResource{
+			ResourceType:     storage.ResourceTypeAccessApp,
+			Hostname:         hostname,
+			AccessAppName:    errAppName,
+			AccessPolicyName: binding.PolicyDef.Name,
+			AccessDecision:   errDecision,
+			ContainerID:      binding.ContainerID,
+			ContainerName:    binding.ContainerName,
+			ServiceName:      binding.ServiceName,
+			AgentID:          binding.AgentID,
+			Status:           storage.StatusError,
+			LastError:        err.Error(),
+			CleanupEnabled:   binding.Cleanup,
+		}
+		if saveErr := o.storage.SaveResource(ctx, errResource); saveErr != nil {
+			log.Error().Err(saveErr).Str("hostname", hostname).Msg("Failed to save error resource")
+		}
+		return
+	}
+	log.Info().
+		Str("hostname", hostname).
+		Str("resource_id", resource.ID).
+		Msg("Created Access Application resource")
+}
+
 // EnsureAccess creates or updates an Access Application for a resolved binding.
 func (o *AccessOperatorImpl) EnsureAccess(ctx context.Context, binding *types.ResolvedAccessBinding) (*storage.ManagedResource, error) {
 	// GetTunnelClient returns the API client + credential with AccountID.
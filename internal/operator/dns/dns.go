@@ -6,14 +6,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/channinghe/labelgate/internal/cloudflare"
 	"github.com/channinghe/labelgate/internal/operator"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
 	"github.com/channinghe/labelgate/internal/types"
 )
 
@@ -24,6 +27,7 @@ var _ operator.DNSOperator = (*DNSOperatorImpl)(nil)
 type DNSOperatorImpl struct {
 	credManager *cloudflare.CredentialManager
 	storage     storage.Storage
+	workers     int // max concurrent record operations
 }
 
 // NewDNSOperator creates a new DNS operator.
@@ -31,6 +35,14 @@ func NewDNSOperator(credManager *cloudflare.CredentialManager, store storage.Sto
 	return &DNSOperatorImpl{
 		credManager: credManager,
 		storage:     store,
+		workers:     operator.DefaultWorkers,
+	}
+}
+
+// SetWorkers sets the maximum number of concurrent record operations.
+func (o *DNSOperatorImpl) SetWorkers(n int) {
+	if n > 0 {
+		o.workers = n
 	}
 }
 
@@ -40,11 +52,14 @@ func (o *DNSOperatorImpl) Name() string {
 }
 
 // Reconcile ensures DNS records match the desired state.
-func (o *DNSOperatorImpl) Reconcile(ctx context.Context, desired []*types.ParsedContainer) error {
+func (o *DNSOperatorImpl) Reconcile(ctx context.Context, desired []*types.ParsedContainer, scope operator.Scope) error {
 	// Build desired state map: hostname -> service config
 	desiredMap := make(map[string]*desiredDNS)
 	for _, container := range desired {
 		for _, svc := range container.DNSServices {
+			if !scope.Has(svc.Hostname) {
+				continue
+			}
 			key := svc.Hostname + ":" + string(svc.Type)
 			if existing, ok := desiredMap[key]; ok {
 				// Conflict - first container wins
@@ -75,69 +90,45 @@ func (o *DNSOperatorImpl) Reconcile(ctx context.Context, desired []*types.Parsed
 	// Build current state map
 	currentMap := make(map[string]*storage.ManagedResource)
 	for _, r := range resources {
+		if !scope.Has(r.Hostname) {
+			continue
+		}
 		key := r.Hostname + ":" + r.RecordType
 		currentMap[key] = r
 	}
 
-	// Reconcile: create, update, or delete
-	for key, desired := range desiredMap {
+	// Reconcile: create or update concurrently, one task per record.
+	// Tasks for the same hostname (e.g. A and AAAA) run sequentially.
+	keys := make([]string, 0, len(desiredMap))
+	for key := range desiredMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tasks := make([]operator.Task, 0, len(keys))
+	for _, key := range keys {
+		desired := desiredMap[key]
 		current, exists := currentMap[key]
-		if !exists {
-		// Create new record
-		if resource, err := o.CreateDNSRecord(ctx, desired.container.Info, desired.service); err != nil {
-				log.Error().Err(err).
-					Str("hostname", desired.service.Hostname).
-					Msg("Failed to create DNS record")
-			// Save resource in error state so Dashboard can see the failure
-			errResource := &storage.ManagedResource{
-				ResourceType:   storage.ResourceTypeDNS,
-				Hostname:       desired.service.Hostname,
-				RecordType:     string(desired.service.Type),
-				Content:        desired.service.Target,
-				Proxied:        desired.service.Proxied,
-				TTL:            desired.service.TTL,
-				ContainerID:    desired.container.Info.ID,
-				ContainerName:  desired.container.Info.Name,
-				ServiceName:    desired.service.ServiceName,
-				AgentID:        desired.container.AgentID,
-				Status:         storage.StatusError,
-				LastError:      err.Error(),
-				CleanupEnabled: desired.service.Cleanup,
-			}
-				if saveErr := o.storage.SaveResource(ctx, errResource); saveErr != nil {
-					log.Error().Err(saveErr).Str("hostname", desired.service.Hostname).Msg("Failed to save error resource")
-				}
-			} else if desired.container.AgentID != "" {
-				// CreateDNSRecord doesn't know about AgentID; patch it after creation
-				resource.AgentID = desired.container.AgentID
-				_ = o.storage.SaveResource(ctx, resource)
-			}
-		} else {
-			// Update AgentID if it changed (e.g. resource was local, now from agent)
-			if current.AgentID != desired.container.AgentID {
-				current.AgentID = desired.container.AgentID
-				_ = o.storage.SaveResource(ctx, current)
-			}
-			// Check if update needed (also retry errors, reactivate orphaned)
-			if current.Status == storage.StatusError || current.Status == storage.StatusOrphaned || needsUpdate(current, desired.service) {
-				if err := o.UpdateDNSRecord(ctx, current, desired.service); err != nil {
-					log.Error().Err(err).
-						Str("hostname", desired.service.Hostname).
-						Msg("Failed to update DNS record")
-					// Mark resource as error
-					if updateErr := o.storage.UpdateResourceError(ctx, current.ID, storage.StatusError, err.Error()); updateErr != nil {
-						log.Error().Err(updateErr).Str("hostname", desired.service.Hostname).Msg("Failed to update resource error status")
-					}
+		delete(currentMap, key)
+
+		tasks = append(tasks, operator.Task{
+			Key: desired.service.Hostname,
+			Run: func(ctx context.Context) {
+				ctx, span := tracing.Start(ctx, "dns.record",
+					attribute.String("labelgate.hostname", desired.service.Hostname),
+					attribute.String("labelgate.record_type", string(desired.service.Type)),
+				)
+				defer span.End()
+
+				if exists {
+					o.reconcileExisting(ctx, current, desired)
 				} else {
-					// Success: clear any previous error, reactivate if orphaned
-					if current.LastError != "" || current.Status != storage.StatusActive {
-						_ = o.storage.UpdateResourceError(ctx, current.ID, storage.StatusActive, "")
-					}
+					o.reconcileNew(ctx, desired)
 				}
-			}
-			delete(currentMap, key)
-		}
+			},
+		})
 	}
+	operator.RunTasks(ctx, o.workers, tasks)
 
 	// Handle orphaned resources (in currentMap but not in desiredMap).
 	// All orphans get the same status; cleanup_enabled determines whether
@@ -162,6 +153,80 @@ func (o *DNSOperatorImpl) Reconcile(ctx context.Context, desired []*types.Parsed
 	return nil
 }
 
+// reconcileNew creates a DNS record that has no stored resource yet.
+func (o *DNSOperatorImpl) reconcileNew(ctx context.Context, desired *desiredDNS) {
+	resource, err := o.CreateDNSRecord(ctx, desired.container.Info, desired.service)
+	if err != nil {
+		log.Error().Err(err).
+			Str("hostname", desired.service.Hostname).
+			Msg("Failed to create DNS record")
+		// Save resource in error state so Dashboard can see the failure
+		errResource := &storage.ManagedResource{
+			ResourceType:   storage.ResourceTypeDNS,
+			Hostname:       desired.service.Hostname,
+			RecordType:     string(desired.service.Type),
+			Content:        desired.service.Target,
+			Proxied:        desired.service.Proxied,
+			TTL:            desired.service.TTL,
+			ContainerID:    desired.container.Info.ID,
+			ContainerName:  desired.container.Info.Name,
+			ServiceName:    desired.service.ServiceName,
+			AgentID:        desired.container.AgentID,
+			Status:         storage.StatusError,
+			LastError:      err.Error(),
+			CleanupEnabled: desired.service.Cleanup,
+		}
+		if saveErr := o.storage.SaveResource(ctx, errResource); saveErr != nil {
+			log.Error().Err(saveErr).Str("hostname", desired.service.Hostname).Msg("Failed to save error resource")
+		}
+		return
+	}
+
+	if desired.container.AgentID != "" {
+		// CreateDNSRecord doesn't know about AgentID; patch it after creation
+		resource.AgentID = desired.container.AgentID
+		_ = o.storage.SaveResource(ctx, resource)
+	}
+}
+
+// reconcileExisting updates a stored DNS record if it drifted, errored or was orphaned.
+func (o *DNSOperatorImpl) reconcileExisting(ctx context.Context, current *storage.ManagedResource, desired *desiredDNS) {
+	// Rebind the record if a new container took over the hostname
+	operator.Handover(ctx, o.storage, current, storage.ResourceOwner{
+		ContainerID:   desired.container.Info.ID,
+		ContainerName: desired.container.Info.Name,
+		ServiceName:   desired.service.ServiceName,
+		AgentID:       desired.container.AgentID,
+	})
+
+	// Update AgentID if it changed (e.g. resource was local, now from agent)
+	if current.AgentID != desired.container.AgentID {
+		current.AgentID = desired.container.AgentID
+		_ = o.storage.SaveResource(ctx, current)
+	}
+
+	// Check if update needed (also retry errors, reactivate orphaned)
+	if current.Status != storage.StatusError && current.Status != storage.StatusOrphaned && !needsUpdate(current, desired.service) {
+		return
+	}
+
+	if err := o.UpdateDNSRecord(ctx, current, desired.service); err != nil {
+		log.Error().Err(err).
+			Str("hostname", desired.service.Hostname).
+			Msg("Failed to update DNS record")
+		// Mark resource as error
+		if updateErr := o.storage.UpdateResourceError(ctx, current.ID, storage.StatusError, err.Error()); updateErr != nil {
+			log.Error().Err(updateErr).Str("hostname", desired.service.Hostname).Msg("Failed to update resource error status")
+		}
+		return
+	}
+
+	// Success: clear any previous error, reactivate if orphaned
+	if current.LastError != "" || current.Status != storage.StatusActive {
+		_ = o.storage.UpdateResourceError(ctx, current.ID, storage.StatusActive, "")
+	}
+}
+
 // Create creates a resource (generic interface).
 func (o *DNSOperatorImpl) Create(ctx context.Context, resource *storage.ManagedResource) error {
 	// This is called from generic reconciler, convert to DNS-specific
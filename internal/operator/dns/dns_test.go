@@ -0,0 +1,66 @@
+package dns
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/channinghe/labelgate/internal/operator"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+func TestReconcile_OrphansOnlyInScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    operator.Scope
+		orphaned map[string]bool
+	}{
+		{"scoped", operator.NewScope("a.example.com"), map[string]bool{"a.example.com": true, "b.example.com": false}},
+		{"full", nil, map[string]bool{"a.example.com": true, "b.example.com": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "labelgate.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			if err := store.Initialize(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make(map[string]string)
+			for _, hostname := range []string{"a.example.com", "b.example.com"} {
+				resource := &storage.ManagedResource{
+					ResourceType: storage.ResourceTypeDNS,
+					CFID:         "cf-" + hostname,
+					Hostname:     hostname,
+					RecordType:   "A",
+					Content:      "1.2.3.4",
+					Status:       storage.StatusActive,
+				}
+				if err := store.SaveResource(ctx, resource); err != nil {
+					t.Fatal(err)
+				}
+				ids[hostname] = resource.ID
+			}
+
+			// No container wants either record; orphaning makes no API calls
+			if err := NewDNSOperator(nil, store).Reconcile(ctx, nil, tt.scope); err != nil {
+				t.Fatal(err)
+			}
+
+			for hostname, want := range tt.orphaned {
+				resource, err := store.GetResource(ctx, ids[hostname])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := resource.Status == storage.StatusOrphaned; got != want {
+					t.Errorf("%s: orphaned = %v, want %v", hostname, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package operator
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// Handover rebinds resource to owner when a different container now claims it,
+// e.g. a service recreated under a new container (blue/green deploy). The
+// Cloudflare resource is kept as is, so there is no delete/create gap.
+// It reports whether ownership moved; resource is updated in place.
+func Handover(ctx context.Context, store storage.Storage, resource *storage.ManagedResource, owner storage.ResourceOwner) bool {
+	if resource.ContainerID == owner.ContainerID {
+		return false
+	}
+
+	if err := store.RebindResource(ctx, resource.ID, resource.ContainerID, owner); err != nil {
+		log.Error().Err(err).
+			Str("hostname", resource.Hostname).
+			Str("from", resource.ContainerName).
+			Str("to", owner.ContainerName).
+			Msg("Failed to hand over resource")
+		return false
+	}
+
+	log.Info().
+		Str("hostname", resource.Hostname).
+		Str("type", string(resource.ResourceType)).
+		Str("path", resource.Path).
+		Str("from", resource.ContainerName).
+		Str("to", owner.ContainerName).
+		Msg("Handed over resource to new container")
+
+	resource.ContainerID = owner.ContainerID
+	resource.ContainerName = owner.ContainerName
+	resource.ServiceName = owner.ServiceName
+	resource.AgentID = owner.AgentID
+	return true
+}
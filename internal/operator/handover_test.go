@@ -0,0 +1,67 @@
+package operator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+func TestHandover(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "labelgate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	resource := &storage.ManagedResource{
+		ResourceType:  storage.ResourceTypeTunnelIngress,
+		Hostname:      "app.example.com",
+		Path:          "/api",
+		Service:       "http://blue:8080",
+		ContainerID:   "blue-id",
+		ContainerName: "blue",
+		ServiceName:   "api",
+		Status:        storage.StatusActive,
+	}
+	if err := store.SaveResource(ctx, resource); err != nil {
+		t.Fatal(err)
+	}
+	green := storage.ResourceOwner{ContainerID: "green-id", ContainerName: "green", ServiceName: "api", AgentID: "edge"}
+
+	// The current owner keeps the resource
+	if Handover(ctx, store, resource, storage.ResourceOwner{ContainerID: "blue-id", ContainerName: "blue"}) {
+		t.Error("expected no handover to the current owner")
+	}
+
+	// A stale copy owned by another container is not rebound
+	stale := *resource
+	stale.ContainerID = "gone-id"
+	if Handover(ctx, store, &stale, green) {
+		t.Error("expected no handover from a container that no longer owns the resource")
+	}
+	if stale.ContainerID != "gone-id" {
+		t.Errorf("expected the stale copy to be unchanged, got owner %q", stale.ContainerID)
+	}
+
+	if !Handover(ctx, store, resource, green) {
+		t.Fatal("expected a handover to green")
+	}
+	stored, err := store.GetResource(ctx, resource.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*storage.ManagedResource{resource, stored} {
+		if r.ContainerID != "green-id" || r.ContainerName != "green" || r.ServiceName != "api" || r.AgentID != "edge" {
+			t.Errorf("expected green to own the resource, got %+v", r)
+		}
+	}
+	if stored.Service != "http://blue:8080" || stored.Status != storage.StatusActive {
+		t.Errorf("expected the resource itself to be unchanged, got %+v", stored)
+	}
+}
@@ -3,6 +3,7 @@ package operator
 
 import (
 	"context"
+	"sort"
 
 	"github.com/channinghe/labelgate/internal/storage"
 	"github.com/channinghe/labelgate/internal/types"
@@ -14,7 +15,9 @@ type Operator interface {
 	Name() string
 
 	// Reconcile ensures the desired state matches actual state.
-	Reconcile(ctx context.Context, desired []*types.ParsedContainer) error
+	// desired is always the full desired state; scope limits which hostnames
+	// are created, updated or orphaned (nil = all).
+	Reconcile(ctx context.Context, desired []*types.ParsedContainer, scope Scope) error
 
 	// Create creates a resource.
 	Create(ctx context.Context, resource *storage.ManagedResource) error
@@ -60,7 +63,8 @@ type AccessOperator interface {
 
 	// ReconcileBindings reconciles resolved access bindings (desired vs actual state).
 	// Called by the reconciler after resolving cross-container access references.
-	ReconcileBindings(ctx context.Context, bindings []*types.ResolvedAccessBinding) error
+	// scope limits which hostnames are touched (nil = all).
+	ReconcileBindings(ctx context.Context, bindings []*types.ResolvedAccessBinding, scope Scope) error
 
 	// EnsureAccess creates or updates an Access Application for a resolved binding.
 	EnsureAccess(ctx context.Context, binding *types.ResolvedAccessBinding) (*storage.ManagedResource, error)
@@ -72,3 +76,45 @@ type AccessOperator interface {
 	// Returns nil if permissions are valid, error otherwise.
 	CheckPermissions(ctx context.Context) error
 }
+
+// Scope is a set of hostnames an incremental reconcile is limited to.
+// A nil Scope means a full reconcile.
+type Scope map[string]struct{}
+
+// NewScope creates a scope containing the given hostnames.
+func NewScope(hostnames ...string) Scope {
+	s := make(Scope, len(hostnames))
+	for _, h := range hostnames {
+		s.Add(h)
+	}
+	return s
+}
+
+// Add adds a hostname to the scope.
+func (s Scope) Add(hostname string) {
+	if hostname != "" {
+		s[hostname] = struct{}{}
+	}
+}
+
+// Hostnames returns the hostnames in scope, sorted (nil for a full scope).
+func (s Scope) Hostnames() []string {
+	if s == nil {
+		return nil
+	}
+	hostnames := make([]string, 0, len(s))
+	for h := range s {
+		hostnames = append(hostnames, h)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// Has reports whether hostname is in scope. A nil Scope contains every hostname.
+func (s Scope) Has(hostname string) bool {
+	if s == nil {
+		return true
+	}
+	_, ok := s[hostname]
+	return ok
+}
@@ -0,0 +1,90 @@
+package operator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultWorkers is the default number of concurrent reconcile workers per operator.
+const DefaultWorkers = 8
+
+// Task is a unit of reconcile work.
+type Task struct {
+	// Key orders related work: tasks sharing a key run sequentially in
+	// submission order. Operators use the hostname (or tunnel ID) as key.
+	Key string
+
+	// Run performs the work. Errors are handled (logged/persisted) by the task itself.
+	Run func(ctx context.Context)
+}
+
+// RunTasks runs tasks on at most workers goroutines and blocks until all
+// dispatched tasks have finished.
+//
+// Tasks are grouped by key; each group runs on a single worker so operations
+// on the same hostname never race. Groups are dispatched ordered by zone
+// (hostname labels compared right to left), so the work for one zone is
+// always issued in the same order. Remaining tasks are skipped once ctx is done.
+func RunTasks(ctx context.Context, workers int, tasks []Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	// Group by key, preserving submission order within a group
+	groups := make(map[string][]Task)
+	var keys []string
+	for _, t := range tasks {
+		if _, ok := groups[t.Key]; !ok {
+			keys = append(keys, t.Key)
+		}
+		groups[t.Key] = append(groups[t.Key], t)
+	}
+	slices.SortStableFunc(keys, func(a, b string) int {
+		return strings.Compare(zoneOrderKey(a), zoneOrderKey(b))
+	})
+
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+
+	queue := make(chan []Task)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, t := range group {
+					if ctx.Err() != nil {
+						break
+					}
+					t.Run(ctx)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, key := range keys {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case queue <- groups[key]:
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// zoneOrderKey reverses hostname labels ("a.example.com" -> "com.example.a")
+// so that sorting groups hostnames of the same zone together.
+func zoneOrderKey(hostname string) string {
+	labels := strings.Split(strings.TrimSuffix(hostname, "."), ".")
+	slices.Reverse(labels)
+	return strings.Join(labels, ".")
+}
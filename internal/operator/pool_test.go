@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunTasks_BoundedAndOrderedPerKey(t *testing.T) {
+	const workers = 3
+
+	var running, maxRunning int32
+	var mu sync.Mutex
+	order := make(map[string][]int)
+
+	var tasks []Task
+	for i := 0; i < 5; i++ {
+		for _, host := range []string{"a.example.com", "b.example.com", "c.example.org", "d.example.net", "e.example.com"} {
+			seq := i
+			tasks = append(tasks, Task{
+				Key: host,
+				Run: func(ctx context.Context) {
+					n := atomic.AddInt32(&running, 1)
+					for {
+						m := atomic.LoadInt32(&maxRunning)
+						if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+							break
+						}
+					}
+					time.Sleep(2 * time.Millisecond)
+					mu.Lock()
+					order[host] = append(order[host], seq)
+					mu.Unlock()
+					atomic.AddInt32(&running, -1)
+				},
+			})
+		}
+	}
+
+	RunTasks(context.Background(), workers, tasks)
+
+	if maxRunning > workers {
+		t.Errorf("expected at most %d concurrent tasks, got %d", workers, maxRunning)
+	}
+	if maxRunning < 2 {
+		t.Errorf("expected tasks to run concurrently, max concurrency was %d", maxRunning)
+	}
+	for host, seqs := range order {
+		if len(seqs) != 5 {
+			t.Fatalf("%s: expected 5 tasks, got %d", host, len(seqs))
+		}
+		for i, seq := range seqs {
+			if seq != i {
+				t.Errorf("%s: tasks ran out of order: %v", host, seqs)
+				break
+			}
+		}
+	}
+}
+
+func TestRunTasks_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ran int32
+	RunTasks(ctx, 2, []Task{
+		{Key: "a", Run: func(context.Context) { atomic.AddInt32(&ran, 1) }},
+		{Key: "b", Run: func(context.Context) { atomic.AddInt32(&ran, 1) }},
+	})
+	if ran != 0 {
+		t.Errorf("expected no tasks to run after cancel, %d ran", ran)
+	}
+}
+
+func TestZoneOrderKey(t *testing.T) {
+	if got := zoneOrderKey("app.example.com."); got != "com.example.app" {
+		t.Errorf("unexpected zone order key: %s", got)
+	}
+}
@@ -4,13 +4,18 @@ package tunnel
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/channinghe/labelgate/internal/cloudflare"
+	"github.com/channinghe/labelgate/internal/events"
 	"github.com/channinghe/labelgate/internal/operator"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
 	"github.com/channinghe/labelgate/internal/types"
 )
 
@@ -22,6 +27,8 @@ type TunnelOperatorImpl struct {
 	credManager   *cloudflare.CredentialManager
 	storage       storage.Storage
 	autoCreateDNS bool // automatically create CNAME records for tunnel hostnames
+	workers       int  // max concurrent tunnels / CNAME checks
+	bus           *events.Bus
 }
 
 // NewTunnelOperator creates a new Tunnel operator.
@@ -30,9 +37,22 @@ func NewTunnelOperator(credManager *cloudflare.CredentialManager, store storage.
 		credManager:   credManager,
 		storage:       store,
 		autoCreateDNS: true, // enabled by default
+		workers:       operator.DefaultWorkers,
 	}
 }
 
+// SetWorkers sets the maximum number of concurrent tunnels and CNAME checks.
+func (o *TunnelOperatorImpl) SetWorkers(n int) {
+	if n > 0 {
+		o.workers = n
+	}
+}
+
+// SetEventBus publishes drift detected in tunnel configurations to bus.
+func (o *TunnelOperatorImpl) SetEventBus(bus *events.Bus) {
+	o.bus = bus
+}
+
 // SetAutoCreateDNS enables/disables automatic DNS record creation.
 func (o *TunnelOperatorImpl) SetAutoCreateDNS(enabled bool) {
 	o.autoCreateDNS = enabled
@@ -44,7 +64,10 @@ func (o *TunnelOperatorImpl) Name() string {
 }
 
 // Reconcile ensures Tunnel ingress rules match the desired state.
-func (o *TunnelOperatorImpl) Reconcile(ctx context.Context, desired []*types.ParsedContainer) error {
+// A tunnel's configuration is a single document, so a scoped reconcile still
+// pushes every ingress rule of each tunnel that contains a scoped hostname,
+// and skips tunnels that don't.
+func (o *TunnelOperatorImpl) Reconcile(ctx context.Context, desired []*types.ParsedContainer, scope operator.Scope) error {
 	// Group services by tunnel
 	tunnelServices := make(map[string][]*desiredTunnel)
 
@@ -74,8 +97,12 @@ func (o *TunnelOperatorImpl) Reconcile(ctx context.Context, desired []*types.Par
 
 	// Build current state map by tunnel
 	currentByTunnel := make(map[string]map[string]*storage.ManagedResource)
+	scopedTunnels := make(map[string]bool) // tunnel IDs with a stored resource in scope
 	for _, r := range resources {
 		tunnelID := r.TunnelID
+		if scope.Has(r.Hostname) {
+			scopedTunnels[tunnelID] = true
+		}
 		if currentByTunnel[tunnelID] == nil {
 			currentByTunnel[tunnelID] = make(map[string]*storage.ManagedResource)
 		}
@@ -83,55 +110,95 @@ func (o *TunnelOperatorImpl) Reconcile(ctx context.Context, desired []*types.Par
 		currentByTunnel[tunnelID][key] = r
 	}
 
-	// Reconcile each tunnel
-	for tunnelName, services := range tunnelServices {
-		if err := o.reconcileTunnel(ctx, tunnelName, services, currentByTunnel); err != nil {
+	// Resolve tunnel credentials and hand each tunnel its own slice of current
+	// state, so tunnels can be reconciled concurrently without sharing maps.
+	tunnelNames := make([]string, 0, len(tunnelServices))
+	for tunnelName := range tunnelServices {
+		tunnelNames = append(tunnelNames, tunnelName)
+	}
+	sort.Strings(tunnelNames)
+
+	// CNAME checks of all tunnels are collected and run once every tunnel is
+	// configured, so they share the worker limit instead of nesting pools
+	var dnsMu sync.Mutex
+	var dnsTasks []operator.Task
+
+	tasks := make([]operator.Task, 0, len(tunnelNames))
+	for _, tunnelName := range tunnelNames {
+		client, tunnelCred, err := o.credManager.GetTunnelClient(tunnelName)
+		if err == nil && tunnelCred == nil {
+			err = fmt.Errorf("tunnel credential not found: %s", tunnelName)
+		}
+		if err != nil {
 			log.Error().Err(err).
 				Str("tunnel", tunnelName).
 				Msg("Failed to reconcile tunnel")
+			continue
 		}
+
+		current := currentByTunnel[tunnelCred.TunnelID]
+		if current == nil {
+			current = make(map[string]*storage.ManagedResource)
+		}
+		delete(currentByTunnel, tunnelCred.TunnelID)
+
+		services := tunnelServices[tunnelName]
+		if !scopedTunnels[tunnelCred.TunnelID] && !servicesInScope(services, scope) {
+			continue
+		}
+		tasks = append(tasks, operator.Task{
+			// Tunnel configuration is a single document; names sharing an ID must not race
+			Key: tunnelCred.TunnelID,
+			Run: func(ctx context.Context) {
+				ctx, span := tracing.Start(ctx, "tunnel.configuration",
+					attribute.String("labelgate.tunnel", tunnelName),
+					attribute.String("labelgate.tunnel_id", tunnelCred.TunnelID),
+				)
+				tunnelDNSTasks, err := o.reconcileTunnel(ctx, tunnelName, client, tunnelCred, services, current)
+				tracing.End(span, err)
+				dnsMu.Lock()
+				dnsTasks = append(dnsTasks, tunnelDNSTasks...)
+				dnsMu.Unlock()
+				if err != nil {
+					log.Error().Err(err).
+						Str("tunnel", tunnelName).
+						Msg("Failed to reconcile tunnel")
+				}
+			},
+		})
 	}
+	operator.RunTasks(ctx, o.workers, tasks)
+	operator.RunTasks(ctx, o.workers, dnsTasks)
 
 	// Handle orphaned resources in tunnels that have no desired services
 	for _, tunnelResources := range currentByTunnel {
-		for _, resource := range tunnelResources {
-			if resource.Status != storage.StatusOrphaned {
-				if err := o.storage.UpdateResourceStatus(ctx, resource.ID, storage.StatusOrphaned); err != nil {
-					log.Error().Err(err).
-						Str("hostname", resource.Hostname).
-						Msg("Failed to mark resource as orphaned")
-				} else {
-					log.Info().
-						Str("hostname", resource.Hostname).
-						Str("service_name", resource.ServiceName).
-						Str("container", resource.ContainerName).
-						Bool("cleanup_enabled", resource.CleanupEnabled).
-						Msg("Tunnel ingress orphaned, no longer referenced by running containers")
-				}
+		for key, resource := range tunnelResources {
+			if !scope.Has(resource.Hostname) {
+				delete(tunnelResources, key)
 			}
 		}
+		o.orphanResources(ctx, tunnelResources)
 	}
 
 	return nil
 }
 
-// reconcileTunnel reconciles a single tunnel's ingress rules.
-func (o *TunnelOperatorImpl) reconcileTunnel(ctx context.Context, tunnelName string, desired []*desiredTunnel, currentByTunnel map[string]map[string]*storage.ManagedResource) error {
-	// Get tunnel client
-	client, tunnelCred, err := o.credManager.GetTunnelClient(tunnelName)
-	if err != nil {
-		return err
-	}
-
-	if tunnelCred == nil {
-		return fmt.Errorf("tunnel credential not found: %s", tunnelName)
+// servicesInScope reports whether any desired service hostname is in scope.
+func servicesInScope(services []*desiredTunnel, scope operator.Scope) bool {
+	for _, d := range services {
+		if scope.Has(d.service.Hostname) {
+			return true
+		}
 	}
+	return false
+}
 
+// reconcileTunnel reconciles a single tunnel's ingress rules and returns the
+// CNAME checks its hostnames still need. Resources left in current (no longer
+// desired) are marked as orphaned.
+func (o *TunnelOperatorImpl) reconcileTunnel(ctx context.Context, tunnelName string, client *cloudflare.Client, tunnelCred *cloudflare.TunnelCredential, desired []*desiredTunnel, current map[string]*storage.ManagedResource) ([]operator.Task, error) {
 	tunnelID := tunnelCred.TunnelID
-	current := currentByTunnel[tunnelID]
-	if current == nil {
-		current = make(map[string]*storage.ManagedResource)
-	}
+	defer o.orphanResources(ctx, current)
 
 	// Build desired ingress rules
 	var ingresses []*types.TunnelIngress
@@ -169,6 +236,9 @@ func (o *TunnelOperatorImpl) reconcileTunnel(ctx context.Context, tunnelName str
 	currentConfig, getErr := tunnelClient.GetTunnelConfiguration(ctx, tunnelID)
 	if getErr == nil {
 		configChanged = !ingressConfigEqual(currentConfig, ingresses)
+		if configChanged && storedInSync(desiredMap, current) {
+			o.publishDrift(tunnelName, tunnelID, desiredMap)
+		}
 	}
 
 	if !configChanged {
@@ -207,19 +277,28 @@ func (o *TunnelOperatorImpl) reconcileTunnel(ctx context.Context, tunnelName str
 				}
 			}
 		}
-		return err
+		return nil, err
 	}
 
 	// Auto-create DNS CNAME records for tunnel hostnames
 	// Cloudflare API does not auto-create DNS records (unlike Dashboard UI)
+	var dnsTasks []operator.Task
 	if o.autoCreateDNS {
-		o.ensureTunnelDNSRecords(ctx, tunnelID, desiredMap, current)
+		dnsTasks = o.tunnelDNSTasks(tunnelID, desiredMap, current)
 	}
 
 	// Update storage for new/updated resources
 	for key, d := range desiredMap {
 		existing, exists := current[key]
 		if exists {
+			// Rebind the rule if a new container took over this hostname/path
+			operator.Handover(ctx, o.storage, existing, storage.ResourceOwner{
+				ContainerID:   d.container.Info.ID,
+				ContainerName: d.container.Info.Name,
+				ServiceName:   d.service.ServiceName,
+				AgentID:       d.container.AgentID,
+			})
+
 			dirty := existing.Service != d.service.Service ||
 				existing.CleanupEnabled != d.service.Cleanup ||
 				existing.AgentID != d.container.AgentID ||
@@ -270,36 +349,74 @@ func (o *TunnelOperatorImpl) reconcileTunnel(ctx context.Context, tunnelName str
 		}
 	}
 
-	// Mark remaining current resources as orphaned
-	for _, resource := range current {
-		if resource.Status != storage.StatusOrphaned {
-			if err := o.storage.UpdateResourceStatus(ctx, resource.ID, storage.StatusOrphaned); err != nil {
-				log.Error().Err(err).Str("hostname", resource.Hostname).Msg("Failed to mark as orphaned")
-			} else {
-				log.Info().
-					Str("hostname", resource.Hostname).
-					Str("service_name", resource.ServiceName).
-					Str("container", resource.ContainerName).
-					Bool("cleanup_enabled", resource.CleanupEnabled).
-					Msg("Tunnel ingress orphaned, no longer referenced by running containers")
-			}
+	return dnsTasks, nil
+}
+
+// storedInSync reports whether the stored rules already match the desired
+// ones. If they do and the remote configuration still differs, the tunnel was
+// changed outside labelgate.
+func storedInSync(desired map[string]*desiredTunnel, current map[string]*storage.ManagedResource) bool {
+	if len(desired) != len(current) {
+		return false
+	}
+	for key, d := range desired {
+		resource, ok := current[key]
+		if !ok || resource.Status != storage.StatusActive || resource.Service != d.service.Service {
+			return false
 		}
 	}
+	return true
+}
 
-	// Remove processed tunnel from map
-	delete(currentByTunnel, tunnelID)
+// publishDrift reports a tunnel configuration that drifted from labelgate's state.
+func (o *TunnelOperatorImpl) publishDrift(tunnelName, tunnelID string, desired map[string]*desiredTunnel) {
+	hostnames := make([]string, 0, len(desired))
+	for _, d := range desired {
+		hostnames = append(hostnames, d.service.Hostname)
+	}
+	sort.Strings(hostnames)
 
-	return nil
+	log.Warn().
+		Str("tunnel", tunnelName).
+		Str("tunnel_id", tunnelID).
+		Msg("Tunnel configuration drifted from labelgate state, overwriting")
+	o.bus.Publish(events.Event{
+		Type:      events.TypeDriftDetected,
+		Hostnames: hostnames,
+		Data:      map[string]any{"resource_type": storage.ResourceTypeTunnelIngress, "tunnel": tunnelName, "tunnel_id": tunnelID},
+	})
 }
 
-// ensureTunnelDNSRecords creates CNAME records for tunnel hostnames.
+// orphanResources marks resources no longer referenced by running containers as orphaned.
+func (o *TunnelOperatorImpl) orphanResources(ctx context.Context, resources map[string]*storage.ManagedResource) {
+	for _, resource := range resources {
+		if resource.Status == storage.StatusOrphaned {
+			continue
+		}
+		if err := o.storage.UpdateResourceStatus(ctx, resource.ID, storage.StatusOrphaned); err != nil {
+			log.Error().Err(err).
+				Str("hostname", resource.Hostname).
+				Msg("Failed to mark resource as orphaned")
+		} else {
+			log.Info().
+				Str("hostname", resource.Hostname).
+				Str("service_name", resource.ServiceName).
+				Str("container", resource.ContainerName).
+				Bool("cleanup_enabled", resource.CleanupEnabled).
+				Msg("Tunnel ingress orphaned, no longer referenced by running containers")
+		}
+	}
+}
+
+// tunnelDNSTasks returns tasks creating CNAME records for tunnel hostnames.
 // Cloudflare API does not auto-create DNS records when adding tunnel ingress rules
 // (unlike the Dashboard UI), so we need to create them manually.
 // The current map is used to skip API calls for hostnames already tracked as active.
-func (o *TunnelOperatorImpl) ensureTunnelDNSRecords(ctx context.Context, tunnelID string, desired map[string]*desiredTunnel, current map[string]*storage.ManagedResource) {
+func (o *TunnelOperatorImpl) tunnelDNSTasks(tunnelID string, desired map[string]*desiredTunnel, current map[string]*storage.ManagedResource) []operator.Task {
 	// Target for tunnel CNAME: <tunnel_id>.cfargotunnel.com
 	tunnelTarget := tunnelID + ".cfargotunnel.com"
 
+	var tasks []operator.Task
 	for key, d := range desired {
 		hostname := d.service.Hostname
 		if hostname == "" {
@@ -312,84 +429,98 @@ func (o *TunnelOperatorImpl) ensureTunnelDNSRecords(ctx context.Context, tunnelI
 			continue
 		}
 
-		// Get DNS client for this hostname's zone
-		dnsClient, err := o.getDNSClientForHostname(hostname)
-		if err != nil {
-			log.Warn().
-				Err(err).
-				Str("hostname", hostname).
-				Msg("Cannot create DNS client for tunnel hostname")
-			continue
-		}
+		tasks = append(tasks, operator.Task{
+			Key: hostname,
+			Run: func(ctx context.Context) {
+				ctx, span := tracing.Start(ctx, "tunnel.dns_record", attribute.String("labelgate.hostname", hostname))
+				defer span.End()
 
-		// Check if CNAME record already exists
-		existingRecord, err := dnsClient.GetRecordByName(ctx, hostname, types.DNSTypeCNAME)
-		if err == nil && existingRecord != nil {
-			// Record exists, check if it's already pointing to tunnel
-			if existingRecord.Content == tunnelTarget {
-				log.Debug().
-					Str("hostname", hostname).
-					Msg("DNS CNAME already exists for tunnel")
-				continue
-			}
+				o.ensureTunnelDNSRecord(ctx, hostname, tunnelTarget)
+			},
+		})
+	}
+	return tasks
+}
 
-			// Record exists but points elsewhere
-			log.Warn().
-				Str("hostname", hostname).
-				Str("existing_type", string(existingRecord.Type)).
-				Str("existing_content", existingRecord.Content).
-				Str("expected_content", tunnelTarget).
-				Msg("DNS record exists but doesn't point to tunnel, updating")
-
-			// Update the record to point to tunnel
-			existingRecord.Type = types.DNSTypeCNAME
-			existingRecord.Content = tunnelTarget
-			existingRecord.Proxied = true
-			if _, err := dnsClient.UpdateRecord(ctx, existingRecord); err != nil {
-				log.Error().
-					Err(err).
-					Str("hostname", hostname).
-					Msg("Failed to update DNS record to point to tunnel")
-			} else {
-				log.Info().
-					Str("hostname", hostname).
-					Str("target", tunnelTarget).
-					Msg("Updated DNS CNAME for tunnel")
-			}
-			continue
-		}
+// ensureTunnelDNSRecord creates or repoints the CNAME for a single tunnel hostname.
+func (o *TunnelOperatorImpl) ensureTunnelDNSRecord(ctx context.Context, hostname, tunnelTarget string) {
+	// Get DNS client for this hostname's zone
+	dnsClient, err := o.getDNSClientForHostname(hostname)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("hostname", hostname).
+			Msg("Cannot create DNS client for tunnel hostname")
+		return
+	}
 
-		// Create new CNAME record
-		record := &types.DNSRecord{
-			Type:    types.DNSTypeCNAME,
-			Name:    hostname,
-			Content: tunnelTarget,
-			Proxied: true,
-			TTL:     1, // Auto TTL
+	// Check if CNAME record already exists
+	existingRecord, err := dnsClient.GetRecordByName(ctx, hostname, types.DNSTypeCNAME)
+	if err == nil && existingRecord != nil {
+		// Record exists, check if it's already pointing to tunnel
+		if existingRecord.Content == tunnelTarget {
+			log.Debug().
+				Str("hostname", hostname).
+				Msg("DNS CNAME already exists for tunnel")
+			return
 		}
 
-		createdRecord, err := dnsClient.CreateRecord(ctx, record)
-		if err != nil {
-			// Check if error is because record already exists
-			if strings.Contains(err.Error(), "already exists") {
-				log.Debug().
-					Str("hostname", hostname).
-					Msg("DNS record already exists")
-				continue
-			}
+		// Record exists but points elsewhere
+		log.Warn().
+			Str("hostname", hostname).
+			Str("existing_type", string(existingRecord.Type)).
+			Str("existing_content", existingRecord.Content).
+			Str("expected_content", tunnelTarget).
+			Msg("DNS record exists but doesn't point to tunnel, updating")
+
+		// Update the record to point to tunnel
+		existingRecord.Type = types.DNSTypeCNAME
+		existingRecord.Content = tunnelTarget
+		existingRecord.Proxied = true
+		if _, err := dnsClient.UpdateRecord(ctx, existingRecord); err != nil {
 			log.Error().
 				Err(err).
 				Str("hostname", hostname).
-				Msg("Failed to create DNS CNAME for tunnel")
-			continue
+				Msg("Failed to update DNS record to point to tunnel")
+		} else {
+			log.Info().
+				Str("hostname", hostname).
+				Str("target", tunnelTarget).
+				Msg("Updated DNS CNAME for tunnel")
 		}
+		return
+	}
+
+	// Create new CNAME record
+	record := &types.DNSRecord{
+		Type:    types.DNSTypeCNAME,
+		Name:    hostname,
+		Content: tunnelTarget,
+		Proxied: true,
+		TTL:     1, // Auto TTL
+	}
 
-		log.Info().
+	createdRecord, err := dnsClient.CreateRecord(ctx, record)
+	if err != nil {
+		// Check if error is because record already exists
+		if strings.Contains(err.Error(), "already exists") {
+			log.Debug().
+				Str("hostname", hostname).
+				Msg("DNS record already exists")
+			return
+		}
+		log.Error().
+			Err(err).
 			Str("hostname", hostname).
-			Str("target", tunnelTarget).
-			Str("record_id", createdRecord.ID).
-			Msg("Created DNS CNAME for tunnel")
+			Msg("Failed to create DNS CNAME for tunnel")
+		return
 	}
+
+	log.Info().
+		Str("hostname", hostname).
+		Str("target", tunnelTarget).
+		Str("record_id", createdRecord.ID).
+		Msg("Created DNS CNAME for tunnel")
 }
 
 // getDNSClientForHostname returns a DNS client for the hostname's zone.
@@ -112,6 +112,17 @@ func (p *DockerProvider) Close() error {
 	return nil
 }
 
+// Ping checks that the Docker daemon is reachable.
+func (p *DockerProvider) Ping(ctx context.Context) error {
+	if p.client == nil {
+		return fmt.Errorf("Docker client not connected")
+	}
+	if _, err := p.client.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping Docker: %w", err)
+	}
+	return nil
+}
+
 // ListContainers returns all running containers.
 func (p *DockerProvider) ListContainers(ctx context.Context) ([]*types.ContainerInfo, error) {
 	if p.client == nil {
@@ -232,6 +243,12 @@ func (p *DockerProvider) Watch(ctx context.Context, events chan<- *types.Contain
 			switch msg.Action {
 			case "start":
 				eventType = types.EventStart
+			case "kill":
+				// Only signals that stop the container mark it as stopping
+				if !isStopSignal(msg.Actor.Attributes["signal"]) {
+					continue
+				}
+				eventType = types.EventKill
 			case "stop":
 				eventType = types.EventStop
 			case "die":
@@ -247,7 +264,7 @@ func (p *DockerProvider) Watch(ctx context.Context, events chan<- *types.Contain
 				ContainerID:   msg.Actor.ID,
 				ContainerName: msg.Actor.Attributes["name"],
 				Labels:        msg.Actor.Attributes,
-				Timestamp:     time.Unix(msg.Time, msg.TimeNano),
+				Timestamp:     eventTime(msg),
 			}
 
 			// Apply filter if configured
@@ -266,6 +283,25 @@ func (p *DockerProvider) Watch(ctx context.Context, events chan<- *types.Contain
 	}
 }
 
+// eventTime returns when a Docker event happened. TimeNano holds the full
+// time in nanoseconds; older daemons may only set Time, in seconds.
+func eventTime(msg dockerevents.Message) time.Time {
+	if msg.TimeNano != 0 {
+		return time.Unix(0, msg.TimeNano)
+	}
+	return time.Unix(msg.Time, 0)
+}
+
+// isStopSignal reports whether a kill event signal terminates the container
+// (SIGINT, SIGQUIT, SIGKILL, SIGTERM), as opposed to e.g. a SIGHUP reload.
+func isStopSignal(signal string) bool {
+	switch strings.TrimPrefix(strings.ToUpper(signal), "SIG") {
+	case "2", "3", "9", "15", "INT", "QUIT", "KILL", "TERM":
+		return true
+	}
+	return false
+}
+
 // createHTTPClient creates an HTTP client for TCP connections.
 func (p *DockerProvider) createHTTPClient() (*http.Client, error) {
 	transport := &http.Transport{
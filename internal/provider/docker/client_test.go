@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	dockerevents "github.com/docker/docker/api/types/events"
+)
+
+func TestEventTime(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	tests := []struct {
+		name string
+		msg  dockerevents.Message
+		want time.Time
+	}{
+		{"nanoseconds", dockerevents.Message{Time: at.Unix(), TimeNano: at.UnixNano()}, at},
+		{"seconds only", dockerevents.Message{Time: at.Unix()}, at.Truncate(time.Second)},
+	}
+
+	for _, tt := range tests {
+		if got := eventTime(tt.msg); !got.Equal(tt.want) {
+			t.Errorf("%s: eventTime() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsStopSignal(t *testing.T) {
+	tests := []struct {
+		signal string
+		want   bool
+	}{
+		{"SIGTERM", true},
+		{"TERM", true},
+		{"sigint", true},
+		{"SIGQUIT", true},
+		{"SIGKILL", true},
+		{"15", true},
+		{"9", true},
+		{"2", true},
+		{"3", true},
+		{"SIGHUP", false},
+		{"1", false},
+		{"SIGUSR1", false},
+		{"SIGWINCH", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isStopSignal(tt.signal); got != tt.want {
+			t.Errorf("isStopSignal(%q) = %v, want %v", tt.signal, got, tt.want)
+		}
+	}
+}
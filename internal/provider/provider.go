@@ -18,6 +18,9 @@ type Provider interface {
 	// Close closes the provider connection.
 	Close() error
 
+	// Ping checks that the container runtime is still reachable.
+	Ping(ctx context.Context) error
+
 	// ListContainers returns all running containers.
 	ListContainers(ctx context.Context) ([]*types.ContainerInfo, error)
 
@@ -0,0 +1,91 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/operator"
+	"github.com/channinghe/labelgate/internal/storage"
+)
+
+// ResourceStateError is returned when a manual action does not apply to a
+// resource in its current status.
+type ResourceStateError struct {
+	Action string
+	Status storage.ResourceStatus
+}
+
+func (e *ResourceStateError) Error() string {
+	return fmt.Sprintf("cannot %s a resource in status %s", e.Action, e.Status)
+}
+
+// RetryResource re-runs reconciliation for the hostname of a resource in
+// error status and returns the resource as stored afterwards.
+func (r *Reconciler) RetryResource(ctx context.Context, id string) (*storage.ManagedResource, error) {
+	resource, err := r.storage.GetResource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if resource.Status != storage.StatusError {
+		return nil, &ResourceStateError{Action: "retry", Status: resource.Status}
+	}
+
+	if err := r.reconcileScoped(ctx, operator.NewScope(resource.Hostname)); err != nil {
+		return nil, err
+	}
+
+	// A successful retry may have replaced or removed the record
+	resource, err = r.storage.GetResource(ctx, id)
+	if storage.IsNotFound(err) {
+		return nil, nil
+	}
+	return resource, err
+}
+
+// ForceDeleteResource deletes an orphaned resource from Cloudflare and storage
+// immediately, without waiting for remove_delay or cleanup being enabled.
+func (r *Reconciler) ForceDeleteResource(ctx context.Context, id string) error {
+	resource, err := r.storage.GetResource(ctx, id)
+	if err != nil {
+		return err
+	}
+	if resource.Status != storage.StatusOrphaned && resource.Status != storage.StatusPendingCleanup {
+		return &ResourceStateError{Action: "force-delete", Status: resource.Status}
+	}
+
+	if err := r.deleteFromCloudflare(ctx, resource); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("hostname", resource.Hostname).
+		Str("resource_type", string(resource.ResourceType)).
+		Str("service_name", resource.ServiceName).
+		Msg("Force-deleted orphaned resource from Cloudflare and storage")
+	return nil
+}
+
+// ForgetResource removes a resource from storage without touching Cloudflare,
+// leaving the Cloudflare object unmanaged. Active resources can't be forgotten
+// since the next reconcile would recreate them.
+func (r *Reconciler) ForgetResource(ctx context.Context, id string) error {
+	resource, err := r.storage.GetResource(ctx, id)
+	if err != nil {
+		return err
+	}
+	if resource.Status == storage.StatusActive {
+		return &ResourceStateError{Action: "forget", Status: resource.Status}
+	}
+
+	if err := r.storage.DeleteResource(ctx, id); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("hostname", resource.Hostname).
+		Str("resource_type", string(resource.ResourceType)).
+		Msg("Forgot resource; Cloudflare object is no longer managed")
+	return nil
+}
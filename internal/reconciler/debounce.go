@@ -0,0 +1,42 @@
+package reconciler
+
+import (
+	"time"
+
+	"github.com/channinghe/labelgate/internal/operator"
+)
+
+// maxDebounceFactor bounds how long a batch may stay open: a steady stream of
+// events is flushed after maxDebounceFactor debounce windows at the latest.
+const maxDebounceFactor = 10
+
+// eventBatch coalesces container events into a single incremental reconcile.
+type eventBatch struct {
+	scope  operator.Scope // affected hostnames
+	full   bool           // an event requires a full reconcile
+	events int            // number of coalesced events
+	first  time.Time      // when the first pending event arrived
+}
+
+// add merges an event's affected hostnames into the batch.
+func (b *eventBatch) add(scope operator.Scope, full bool) {
+	if b.events == 0 {
+		b.first = time.Now()
+		b.scope = operator.NewScope()
+	}
+	b.events++
+	b.full = b.full || full
+	for hostname := range scope {
+		b.scope.Add(hostname)
+	}
+}
+
+// empty reports whether the batch has no pending events.
+func (b *eventBatch) empty() bool {
+	return b.events == 0
+}
+
+// reset clears the batch.
+func (b *eventBatch) reset() {
+	*b = eventBatch{}
+}
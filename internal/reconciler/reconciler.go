@@ -7,16 +7,22 @@ import (
 	"errors"
 	"fmt"
 	"hash/fnv"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/metrics"
 	"github.com/channinghe/labelgate/internal/operator"
 	"github.com/channinghe/labelgate/internal/provider"
 	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/tracing"
 	"github.com/channinghe/labelgate/internal/types"
 	"github.com/channinghe/labelgate/pkg/labels"
 )
@@ -29,9 +35,13 @@ type Reconciler struct {
 	dnsOp       operator.DNSOperator
 	tunnelOp    operator.TunnelOperator
 	accessOp    operator.AccessOperator
-	interval    time.Duration
-	orphanTTL   time.Duration // 0 = never auto-clean orphans from DB
-	removeDelay time.Duration // delay before cleaning up orphaned CF resources
+	bus         *events.Bus   // live events (nil = disabled)
+
+	// Timings can change at runtime; the Run loop is notified through timingsChanged
+	timings        Timings
+	timingsMu      sync.RWMutex
+	timingsChanged chan struct{}
+
 	mu          sync.RWMutex
 	containers  map[string]*types.ParsedContainer   // containerID -> parsed container
 	agentData         map[string][]*types.ParsedContainer // agentID -> containers
@@ -45,6 +55,14 @@ type Reconciler struct {
 	agentReady     chan struct{}
 	agentReadyOnce sync.Once
 
+	// passMu serializes reconcile passes from the loop and manual actions,
+	// which would otherwise race on Cloudflare records and tunnel configs
+	passMu sync.Mutex
+
+	// Orphans announced for cleanup, so each is announced once and each
+	// failure reported once: resource ID -> delete failed. Guarded by passMu.
+	orphanCleanups map[string]bool
+
 	// Sync state exposed for the API layer
 	startedAt     time.Time
 	lastSyncTime  time.Time
@@ -52,6 +70,14 @@ type Reconciler struct {
 	syncMu        sync.RWMutex
 }
 
+// Timings are the reconciler durations that can change at runtime.
+type Timings struct {
+	PollInterval time.Duration
+	OrphanTTL    time.Duration // 0 = never auto-clean orphans from DB
+	RemoveDelay  time.Duration // delay before cleaning up orphaned CF resources
+	Debounce     time.Duration // window for coalescing container events (0 = none)
+}
+
 // Config holds reconciler configuration.
 type Config struct {
 	Provider       provider.Provider
@@ -63,7 +89,9 @@ type Config struct {
 	PollInterval   time.Duration
 	OrphanTTL      time.Duration // 0 = never auto-clean orphans from DB
 	RemoveDelay    time.Duration // delay before cleaning up orphaned CF resources
+	Debounce       time.Duration // window for coalescing container events (0 = none)
 	ExpectedAgents []string      // agent IDs that must report before initial reconcile
+	Events         *events.Bus   // live event bus (nil = disabled)
 }
 
 // NewReconciler creates a new reconciler.
@@ -75,13 +103,19 @@ func NewReconciler(cfg *Config) *Reconciler {
 		dnsOp:          cfg.DNSOperator,
 		tunnelOp:       cfg.TunnelOp,
 		accessOp:       cfg.AccessOp,
-		interval:       cfg.PollInterval,
-		orphanTTL:      cfg.OrphanTTL,
-		removeDelay:    cfg.RemoveDelay,
+		bus:            cfg.Events,
+		timings: Timings{
+			PollInterval: cfg.PollInterval,
+			OrphanTTL:    cfg.OrphanTTL,
+			RemoveDelay:  cfg.RemoveDelay,
+			Debounce:     cfg.Debounce,
+		},
+		timingsChanged: make(chan struct{}, 1),
 		containers:     make(map[string]*types.ParsedContainer),
 		agentData:         make(map[string][]*types.ParsedContainer),
 		agentFingerprints: make(map[string]uint64),
 		agentTrigger:      make(chan struct{}, 1),
+		orphanCleanups:    make(map[string]bool),
 		expectedAgents: cfg.ExpectedAgents,
 		agentReady:     make(chan struct{}),
 		startedAt:      time.Now(),
@@ -115,11 +149,19 @@ func (r *Reconciler) Run(ctx context.Context) error {
 	}()
 
 	// Start periodic sync
-	ticker := time.NewTicker(r.interval)
+	timings := r.Timings()
+	ticker := time.NewTicker(timings.PollInterval)
 	defer ticker.Stop()
 
+	// Container events are coalesced and reconciled incrementally
+	var batch eventBatch
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
 	log.Info().
-		Dur("interval", r.interval).
+		Dur("interval", timings.PollInterval).
+		Dur("debounce", timings.Debounce).
 		Msg("Started reconciliation loop (event-driven + periodic)")
 
 	for {
@@ -128,10 +170,50 @@ func (r *Reconciler) Run(ctx context.Context) error {
 			return ctx.Err()
 
 		case event := <-eventsChan:
-			r.handleEvent(ctx, event)
+			if !event.Timestamp.IsZero() {
+				// Remote hosts' clocks may run slightly ahead of ours
+				metrics.DockerEventLag.Observe(max(time.Since(event.Timestamp).Seconds(), 0))
+			}
+			scope, full := r.handleEvent(ctx, event)
+			r.bus.Publish(events.Event{
+				Type:      events.Type("container." + string(event.Type)),
+				Hostnames: scope.Hostnames(),
+				Data: map[string]string{
+					"container_id":   event.ContainerID,
+					"container_name": event.ContainerName,
+				},
+			})
+			if !full && len(scope) == 0 {
+				continue
+			}
+			batch.add(scope, full)
+
+			// Flush immediately without debounce, or when a steady stream of
+			// events has kept the batch open for too long
+			window := r.Timings().Debounce
+			if window <= 0 || time.Since(batch.first) >= maxDebounceFactor*window {
+				debounce.Stop()
+				r.flushBatch(ctx, &batch)
+				continue
+			}
+			debounce.Reset(window)
+
+		case <-r.timingsChanged:
+			timings = r.Timings()
+			ticker.Reset(timings.PollInterval)
+			log.Info().
+				Dur("interval", timings.PollInterval).
+				Dur("debounce", timings.Debounce).
+				Msg("Updated reconciliation timings")
+
+		case <-debounce.C:
+			r.flushBatch(ctx, &batch)
 
 		case <-r.agentTrigger:
 			log.Debug().Msg("Agent data updated, triggering reconciliation")
+			// A full reconcile also covers any pending container events
+			debounce.Stop()
+			batch.reset()
 			if err := r.reconcile(ctx); err != nil {
 				log.Error().Err(err).Msg("Reconciliation after agent update failed")
 			}
@@ -142,6 +224,8 @@ func (r *Reconciler) Run(ctx context.Context) error {
 				log.Error().Err(err).Msg("Periodic container sync failed")
 				continue
 			}
+			debounce.Stop()
+			batch.reset()
 			if err := r.reconcile(ctx); err != nil {
 				log.Error().Err(err).Msg("Periodic reconciliation failed")
 			}
@@ -149,6 +233,28 @@ func (r *Reconciler) Run(ctx context.Context) error {
 	}
 }
 
+// flushBatch reconciles the hostnames collected from container events.
+func (r *Reconciler) flushBatch(ctx context.Context, batch *eventBatch) {
+	if batch.empty() {
+		return
+	}
+	scope := batch.scope
+	if batch.full {
+		scope = nil
+	}
+
+	log.Debug().
+		Int("events", batch.events).
+		Int("hostnames", len(batch.scope)).
+		Bool("full", batch.full).
+		Msg("Reconciling after container events")
+
+	batch.reset()
+	if err := r.reconcileScoped(ctx, scope); err != nil {
+		log.Error().Err(err).Msg("Reconciliation after container events failed")
+	}
+}
+
 // waitForAgents blocks until all expected agents have reported or the timeout expires.
 func (r *Reconciler) waitForAgents(ctx context.Context) {
 	const timeout = 5 * time.Second
@@ -183,71 +289,128 @@ func (r *Reconciler) waitForAgents(ctx context.Context) {
 	}
 }
 
-// handleEvent handles a container event.
-func (r *Reconciler) handleEvent(ctx context.Context, event *types.ContainerEvent) {
+// handleEvent applies a container event to the in-memory container state and
+// returns the hostnames it affects. full is true when the change can affect
+// hostnames beyond the container's own (e.g. it defines access policies that
+// other containers reference).
+func (r *Reconciler) handleEvent(ctx context.Context, event *types.ContainerEvent) (scope operator.Scope, full bool) {
 	log.Debug().
 		Str("type", string(event.Type)).
 		Str("container", event.ContainerName).
 		Str("id", event.ContainerID).
 		Msg("Handling container event")
 
+	var parsed *types.ParsedContainer
 	switch event.Type {
 	case types.EventStart:
 		// Get full container info
 		container, err := r.provider.GetContainer(ctx, event.ContainerID)
 		if err != nil {
 			log.Error().Err(err).Str("id", event.ContainerID).Msg("Failed to get container info")
-			return
+			return nil, false
 		}
 
 		// Parse labels
-		parsed := r.parseContainer(container, event.AgentID)
-		if parsed == nil {
-			return
-		}
+		parsed = r.parseContainer(container, event.AgentID)
 
+	case types.EventKill:
+		// Keep the container but let a replacement win its hostnames
 		r.mu.Lock()
-		r.containers[event.ContainerID] = parsed
+		current, ok := r.containers[event.ContainerID]
+		if ok && !current.Stopping {
+			stopping := *current
+			stopping.Stopping = true
+			r.containers[event.ContainerID] = &stopping
+		}
 		r.mu.Unlock()
-
-		// Trigger reconciliation
-		if err := r.reconcile(ctx); err != nil {
-			log.Error().Err(err).Msg("Reconciliation after container start failed")
+		if !ok || current.Stopping {
+			return nil, false
 		}
 
+		scope = operator.NewScope()
+		addContainerHostnames(scope, current)
+		return scope, len(current.AccessPolicies) > 0
+
 	case types.EventStop, types.EventDie:
+		// Container removed from desired state below
+
+	case types.EventDestroy:
 		r.mu.Lock()
 		delete(r.containers, event.ContainerID)
 		r.mu.Unlock()
+		return nil, false
 
-		// Trigger reconciliation
-		if err := r.reconcile(ctx); err != nil {
-			log.Error().Err(err).Msg("Reconciliation after container stop failed")
-		}
+	default:
+		return nil, false
+	}
 
-	case types.EventDestroy:
-		r.mu.Lock()
+	r.mu.Lock()
+	previous := r.containers[event.ContainerID]
+	if parsed != nil {
+		r.containers[event.ContainerID] = parsed
+	} else {
 		delete(r.containers, event.ContainerID)
-		r.mu.Unlock()
+	}
+	r.mu.Unlock()
+
+	scope = operator.NewScope()
+	for _, c := range []*types.ParsedContainer{previous, parsed} {
+		if c == nil {
+			continue
+		}
+		if len(c.AccessPolicies) > 0 {
+			full = true
+		}
+		addContainerHostnames(scope, c)
+	}
+
+	// Include hostnames the container owns in storage, in case the in-memory
+	// state missed an earlier change (e.g. labels removed on recreate)
+	owned, err := r.storage.ListResources(ctx, storage.ResourceFilter{ContainerID: event.ContainerID})
+	if err != nil {
+		log.Warn().Err(err).Str("id", event.ContainerID).Msg("Failed to list resources owned by container")
+	}
+	for _, res := range owned {
+		scope.Add(res.Hostname)
+	}
+
+	return scope, full
+}
+
+// addContainerHostnames adds every DNS and tunnel hostname of a container to scope.
+func addContainerHostnames(scope operator.Scope, c *types.ParsedContainer) {
+	for _, svc := range c.DNSServices {
+		scope.Add(svc.Hostname)
+	}
+	for _, svc := range c.TunnelServices {
+		scope.Add(svc.Hostname)
 	}
 }
 
 // syncContainers syncs the current container state from the provider.
-func (r *Reconciler) syncContainers(ctx context.Context) error {
+func (r *Reconciler) syncContainers(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "docker.sync")
+	defer func() { tracing.End(span, err) }()
+
 	containers, err := r.provider.ListContainers(ctx)
 	if err != nil {
 		return err
 	}
+	span.SetAttributes(attribute.Int("labelgate.containers", len(containers)))
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Clear and rebuild
+	// Clear and rebuild, keeping the stopping mark of containers still shutting down
+	previous := r.containers
 	r.containers = make(map[string]*types.ParsedContainer)
 
 	for _, container := range containers {
 		parsed := r.parseContainer(container, "")
 		if parsed != nil {
+			if prev, ok := previous[container.ID]; ok && prev.Stopping {
+				parsed.Stopping = true
+			}
 			r.containers[container.ID] = parsed
 		}
 	}
@@ -292,12 +455,14 @@ func (r *Reconciler) syncContainers(ctx context.Context) error {
 func (r *Reconciler) parseContainer(container *types.ContainerInfo, agentID string) *types.ParsedContainer {
 	result := r.parser.Parse(container.Labels)
 
-	// Log any parse errors
+	// Log any parse errors; they are also kept for the containers API
+	var errs []string
 	for _, err := range result.Errors {
 		log.Warn().
 			Err(err).
 			Str("container", container.Name).
 			Msg("Label parsing error")
+		errs = append(errs, err.Error())
 	}
 
 	// Check hostname conflicts
@@ -306,10 +471,12 @@ func (r *Reconciler) parseContainer(container *types.ContainerInfo, agentID stri
 			Err(err).
 			Str("container", container.Name).
 			Msg("Hostname conflict detected")
+		errs = append(errs, err.Error())
 		// Still return the parsed result, but one of the services will be ignored
 	}
 
-	if len(result.DNSServices) == 0 && len(result.TunnelServices) == 0 && len(result.AccessPolicies) == 0 {
+	// Containers with only invalid labels are kept so their errors are visible
+	if len(result.DNSServices) == 0 && len(result.TunnelServices) == 0 && len(result.AccessPolicies) == 0 && len(errs) == 0 {
 		return nil
 	}
 
@@ -319,23 +486,60 @@ func (r *Reconciler) parseContainer(container *types.ContainerInfo, agentID stri
 		TunnelServices: result.TunnelServices,
 		AccessPolicies: result.AccessPolicies,
 		AgentID:        agentID,
+		Priority:       result.Priority,
+		Rollout:        result.Rollout,
+		Errors:         errs,
 	}
 }
 
-// reconcile performs the actual reconciliation.
+// reconcile performs a full reconciliation.
 func (r *Reconciler) reconcile(ctx context.Context) error {
+	return r.reconcileScoped(ctx, nil)
+}
+
+// reconcileScoped performs the actual reconciliation, limited to the hostnames
+// in scope (nil = full). Desired state and conflict resolution always consider
+// all containers so that scoped and full runs agree. Passes run one at a
+// time; the returned error is that of this pass.
+func (r *Reconciler) reconcileScoped(ctx context.Context, scope operator.Scope) error {
+	r.passMu.Lock()
+	defer r.passMu.Unlock()
+
+	// Attribute every storage change of this pass to it in resource history
+	reconcileID := uuid.New().String()
+	ctx = storage.WithReconcileID(ctx, reconcileID)
+	log.Debug().Str("reconcile_id", reconcileID).Int("scope", len(scope)).Msg("Reconciling")
+
+	ctx, span := tracing.Start(ctx, "reconcile",
+		attribute.String("labelgate.reconcile_id", reconcileID),
+		attribute.Bool("labelgate.reconcile.full", scope == nil),
+		attribute.StringSlice("labelgate.reconcile.hostnames", scope.Hostnames()),
+	)
+
+	started := time.Now()
+	r.bus.Publish(events.Event{
+		Type:      events.TypeReconcileStarted,
+		Hostnames: scope.Hostnames(),
+		Data:      map[string]any{"reconcile_id": reconcileID, "full": scope == nil},
+	})
+
 	r.mu.RLock()
 	desired := r.getDesiredState()
 	r.mu.RUnlock()
 
-	// Filter out hostname conflicts across all containers
-	desired = r.filterHostnameConflicts(desired)
+	// Filter out hostname conflicts across all containers and record the losers
+	desired, conflicts := r.filterHostnameConflicts(desired)
+	if err := r.storage.ReplaceConflicts(ctx, conflicts); err != nil {
+		log.Error().Err(err).Msg("Failed to save hostname conflicts")
+	}
 
 	var errs []error
 
 	// Reconcile DNS
 	if r.dnsOp != nil {
-		if err := r.dnsOp.Reconcile(ctx, desired); err != nil {
+		if err := runOperator(ctx, "dns", func(ctx context.Context) error {
+			return r.dnsOp.Reconcile(ctx, desired, scope)
+		}); err != nil {
 			log.Error().Err(err).Msg("DNS reconciliation failed")
 			errs = append(errs, fmt.Errorf("dns: %w", err))
 		}
@@ -343,7 +547,9 @@ func (r *Reconciler) reconcile(ctx context.Context) error {
 
 	// Reconcile Tunnel
 	if r.tunnelOp != nil {
-		if err := r.tunnelOp.Reconcile(ctx, desired); err != nil {
+		if err := runOperator(ctx, "tunnel", func(ctx context.Context) error {
+			return r.tunnelOp.Reconcile(ctx, desired, scope)
+		}); err != nil {
 			log.Error().Err(err).Msg("Tunnel reconciliation failed")
 			errs = append(errs, fmt.Errorf("tunnel: %w", err))
 		}
@@ -353,33 +559,75 @@ func (r *Reconciler) reconcile(ctx context.Context) error {
 	// Always call ReconcileBindings even with empty bindings so orphan cleanup runs.
 	if r.accessOp != nil {
 		bindings := r.resolveAccessReferences(desired)
-		if err := r.accessOp.ReconcileBindings(ctx, bindings); err != nil {
+		if err := runOperator(ctx, "access", func(ctx context.Context) error {
+			return r.accessOp.ReconcileBindings(ctx, bindings, scope)
+		}); err != nil {
 			log.Error().Err(err).Msg("Access reconciliation failed")
 			errs = append(errs, fmt.Errorf("access: %w", err))
 		}
 	}
 
 	// Process orphaned resources with cleanup_enabled=true whose remove_delay has expired
-	r.processOrphanedCleanups(ctx)
+	runOperator(ctx, "orphan_cleanup", func(ctx context.Context) error {
+		r.processOrphanedCleanups(ctx)
+		return nil
+	})
 
 	// Clean up orphaned DB records (cleanup_enabled=false) that exceeded the TTL
-	if r.orphanTTL > 0 {
+	if r.Timings().OrphanTTL > 0 {
 		r.cleanupExpiredOrphans(ctx)
 	}
 
 	// Update sync state for API layer — errors.Join returns nil when errs is empty
+	syncErr := errors.Join(errs...)
 	r.syncMu.Lock()
 	r.lastSyncTime = time.Now()
-	r.lastSyncError = errors.Join(errs...)
+	r.lastSyncError = syncErr
 	r.syncMu.Unlock()
 
-	return nil
+	scopeLabel, result := "scoped", "success"
+	if scope == nil {
+		scopeLabel = "full"
+	}
+	if syncErr != nil {
+		result = "error"
+	} else {
+		metrics.LastSuccessfulSync.SetToCurrentTime()
+	}
+	metrics.ReconcileDuration.WithLabelValues(scopeLabel).Observe(time.Since(started).Seconds())
+	metrics.ReconcileTotal.WithLabelValues(scopeLabel, result).Inc()
+
+	finished := map[string]any{
+		"reconcile_id": reconcileID,
+		"full":         scope == nil,
+		"duration_ms":  time.Since(started).Milliseconds(),
+	}
+	if syncErr != nil {
+		finished["error"] = syncErr.Error()
+	}
+	r.bus.Publish(events.Event{
+		Type:      events.TypeReconcileFinished,
+		Hostnames: scope.Hostnames(),
+		Data:      finished,
+	})
+	tracing.End(span, syncErr)
+
+	return syncErr
+}
+
+// runOperator runs one step of a reconcile pass in its own span.
+func runOperator(ctx context.Context, name string, run func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, "reconcile."+name)
+	err := run(ctx)
+	tracing.End(span, err)
+	return err
 }
 
 // processOrphanedCleanups deletes CF resources for orphaned entries with
 // cleanup_enabled=true whose remove_delay has expired, then hard-deletes from storage.
 func (r *Reconciler) processOrphanedCleanups(ctx context.Context) {
-	cutoff := time.Now().Add(-r.removeDelay)
+	removeDelay := r.Timings().RemoveDelay
+	cutoff := time.Now().Add(-removeDelay)
 	resources, err := r.storage.ListOrphanedForCleanup(ctx, cutoff)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to list orphaned resources for cleanup")
@@ -392,44 +640,54 @@ func (r *Reconciler) processOrphanedCleanups(ctx context.Context) {
 
 	log.Info().
 		Int("count", len(resources)).
-		Dur("remove_delay", r.removeDelay).
+		Dur("remove_delay", removeDelay).
 		Msg("Processing orphaned resources scheduled for cleanup")
 
+	// Announce orphans that became due; those left over from earlier
+	// passes (failed deletes) were announced already
+	due := make(map[string]bool, len(resources))
+	var announced []*storage.ManagedResource
+	var hostnames []string
 	for _, resource := range resources {
-		// Delete from Cloudflare first, then hard-delete from DB.
-		// operator.Delete() already hard-deletes the DB record on success.
-		var deleteErr error
-		switch resource.ResourceType {
-		case storage.ResourceTypeDNS:
-			if r.dnsOp == nil {
-				log.Warn().Str("hostname", resource.Hostname).Msg("DNS operator not configured, skipping orphan cleanup")
-				continue
-			}
-			deleteErr = r.dnsOp.Delete(ctx, resource)
-		case storage.ResourceTypeTunnelIngress:
-			if r.tunnelOp == nil {
-				log.Warn().Str("hostname", resource.Hostname).Msg("Tunnel operator not configured, skipping orphan cleanup")
-				continue
-			}
-			deleteErr = r.tunnelOp.Delete(ctx, resource)
-		case storage.ResourceTypeAccessApp:
-			if r.accessOp == nil {
-				log.Warn().Str("hostname", resource.Hostname).Msg("Access operator not configured, skipping orphan cleanup")
-				continue
-			}
-			deleteErr = r.accessOp.Delete(ctx, resource)
-		default:
-			_ = r.storage.DeleteResource(ctx, resource.ID)
-			continue
+		due[resource.ID] = true
+		if _, ok := r.orphanCleanups[resource.ID]; !ok {
+			r.orphanCleanups[resource.ID] = false
+			announced = append(announced, resource)
+			hostnames = append(hostnames, resource.Hostname)
 		}
+	}
+	for id := range r.orphanCleanups {
+		if !due[id] {
+			delete(r.orphanCleanups, id) // readopted, forgotten or deleted elsewhere
+		}
+	}
+	if len(announced) > 0 {
+		r.bus.Publish(events.Event{
+			Type:      events.TypeOrphanCleanup,
+			Hostnames: hostnames,
+			Data:      map[string]any{"resources": announced, "remove_delay": removeDelay.String()},
+		})
+	}
 
+	for _, resource := range resources {
+		// Delete from Cloudflare first, then hard-delete from DB.
+		deleteErr := r.deleteFromCloudflare(ctx, resource)
 		if deleteErr != nil {
 			log.Error().Err(deleteErr).
 				Str("hostname", resource.Hostname).
 				Str("resource_type", string(resource.ResourceType)).
 				Str("service_name", resource.ServiceName).
 				Msg("Failed to clean up orphaned resource from Cloudflare")
+			if !r.orphanCleanups[resource.ID] {
+				r.orphanCleanups[resource.ID] = true
+				r.bus.Publish(events.Event{
+					Type:      events.TypeOrphanCleanupFailed,
+					Hostnames: []string{resource.Hostname},
+					Data:      map[string]any{"resource": resource, "error": deleteErr.Error()},
+				})
+			}
 		} else {
+			delete(r.orphanCleanups, resource.ID)
 			log.Info().
 				Str("hostname", resource.Hostname).
 				Str("resource_type", string(resource.ResourceType)).
@@ -439,11 +697,36 @@ func (r *Reconciler) processOrphanedCleanups(ctx context.Context) {
 	}
 }
 
+// deleteFromCloudflare deletes a resource from Cloudflare through its operator.
+// operator.Delete() hard-deletes the DB record on success.
+func (r *Reconciler) deleteFromCloudflare(ctx context.Context, resource *storage.ManagedResource) error {
+	switch resource.ResourceType {
+	case storage.ResourceTypeDNS:
+		if r.dnsOp == nil {
+			return fmt.Errorf("DNS operator not configured")
+		}
+		return r.dnsOp.Delete(ctx, resource)
+	case storage.ResourceTypeTunnelIngress:
+		if r.tunnelOp == nil {
+			return fmt.Errorf("tunnel operator not configured")
+		}
+		return r.tunnelOp.Delete(ctx, resource)
+	case storage.ResourceTypeAccessApp:
+		if r.accessOp == nil {
+			return fmt.Errorf("access operator not configured")
+		}
+		return r.accessOp.Delete(ctx, resource)
+	default:
+		return r.storage.DeleteResource(ctx, resource.ID)
+	}
+}
+
 // cleanupExpiredOrphans hard-deletes orphaned DB records that have been orphaned
 // longer than the configured orphan TTL. This only touches the database, NOT Cloudflare.
 // (Cloudflare resources are preserved for cleanup_enabled=false orphans.)
 func (r *Reconciler) cleanupExpiredOrphans(ctx context.Context) {
-	cutoff := time.Now().Add(-r.orphanTTL)
+	orphanTTL := r.Timings().OrphanTTL
+	cutoff := time.Now().Add(-orphanTTL)
 	expired, err := r.storage.ListExpiredOrphans(ctx, cutoff)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to list expired orphaned resources")
@@ -456,7 +739,7 @@ func (r *Reconciler) cleanupExpiredOrphans(ctx context.Context) {
 
 	log.Info().
 		Int("count", len(expired)).
-		Dur("orphan_ttl", r.orphanTTL).
+		Dur("orphan_ttl", orphanTTL).
 		Msg("Cleaning up expired orphaned resources from database")
 
 	for _, resource := range expired {
@@ -478,7 +761,8 @@ func (r *Reconciler) cleanupExpiredOrphans(ctx context.Context) {
 	}
 }
 
-// getDesiredState returns the desired state from all sources.
+// getDesiredState returns the desired state from all sources, ordered by
+// conflict precedence (see containerLess) so "first wins" is deterministic.
 func (r *Reconciler) getDesiredState() []*types.ParsedContainer {
 	var desired []*types.ParsedContainer
 
@@ -492,99 +776,263 @@ func (r *Reconciler) getDesiredState() []*types.ParsedContainer {
 		desired = append(desired, containers...)
 	}
 
+	sort.SliceStable(desired, func(i, j int) bool {
+		return containerLess(desired[i], desired[j])
+	})
+
 	return desired
 }
 
+// containerLess reports whether a takes precedence over b in a hostname
+// conflict: running before stopping containers, then higher priority label,
+// then the older container, then the lower container ID as a final tie-breaker.
+func containerLess(a, b *types.ParsedContainer) bool {
+	if a.Stopping != b.Stopping {
+		return !a.Stopping
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if !a.Info.Created.Equal(b.Info.Created) {
+		return a.Info.Created.Before(b.Info.Created)
+	}
+	return a.Info.ID < b.Info.ID
+}
+
+// conflictReason explains why loser lost a conflict against winner.
+func conflictReason(winner, loser *types.ParsedContainer, winnerService string) string {
+	switch {
+	case winner == loser:
+		return fmt.Sprintf("hostname already used by service %q in the same container", winnerService)
+	case loser.Stopping && !winner.Stopping:
+		return fmt.Sprintf("container is stopping, handed over to %s", winner.Info.Name)
+	case winner.Priority != loser.Priority:
+		return fmt.Sprintf("lower priority (%d) than %s (%d)", loser.Priority, winner.Info.Name, winner.Priority)
+	case !winner.Info.Created.Equal(loser.Info.Created):
+		return fmt.Sprintf("created after %s", winner.Info.Name)
+	default:
+		return fmt.Sprintf("container ID sorts after %s", winner.Info.Name)
+	}
+}
+
+// serviceOwner identifies the service that claimed a hostname.
+type serviceOwner struct {
+	container *types.ParsedContainer
+	service   string
+}
+
 // filterHostnameConflicts checks for hostname conflicts across all containers
-// and returns a filtered desired state with conflicting services removed.
+// and returns a filtered desired state with losing services removed, plus a
+// record for every losing service. containers must be in precedence order.
 //
 // Rules:
 //   - DNS+Tunnel same hostname: remove the tunnel service (CF auto-creates
 //     CNAME for tunnels which would conflict with explicit DNS records)
-//   - DNS duplicate hostname across containers: first container wins (logged)
-//   - Tunnel duplicate hostname across containers: first container wins (logged)
-func (r *Reconciler) filterHostnameConflicts(containers []*types.ParsedContainer) []*types.ParsedContainer {
-	// First pass: collect all DNS hostnames (first-wins)
-	dnsHostnames := make(map[string]string)    // hostname -> container name
-	tunnelHostnames := make(map[string]string) // hostname -> container name
-	conflictedHostnames := make(map[string]bool)
+//   - DNS duplicate hostname+type: the container with precedence wins
+//   - Tunnel duplicate hostname+path: the container with precedence wins,
+//     unless a rollout assigns the rule (see rolloutOwners)
+func (r *Reconciler) filterHostnameConflicts(containers []*types.ParsedContainer) ([]*types.ParsedContainer, []*storage.Conflict) {
+	dnsOwners := make(map[string]serviceOwner)    // hostname:type -> owner
+	dnsHostnames := make(map[string]serviceOwner) // hostname -> first DNS owner
+	tunnelOwners := make(map[string]serviceOwner) // hostname:path -> owner
+	droppedDNS := make(map[*types.DNSService]bool)
+	droppedTunnels := make(map[*types.TunnelService]bool)
+	var conflicts []*storage.Conflict
+
+	addConflict := func(resourceType storage.ResourceType, hostname string, loser *types.ParsedContainer, loserService string, winner serviceOwner, reason string) {
+		log.Warn().
+			Str("hostname", hostname).
+			Str("resource_type", string(resourceType)).
+			Str("container", loser.Info.Name).
+			Str("service", loserService).
+			Str("winner", winner.container.Info.Name).
+			Str("reason", reason).
+			Msg("Hostname conflict, service not applied")
+		conflicts = append(conflicts, &storage.Conflict{
+			Hostname:            hostname,
+			ResourceType:        resourceType,
+			ContainerID:         loser.Info.ID,
+			ContainerName:       loser.Info.Name,
+			ServiceName:         loserService,
+			AgentID:             loser.AgentID,
+			WinnerContainerID:   winner.container.Info.ID,
+			WinnerContainerName: winner.container.Info.Name,
+			WinnerServiceName:   winner.service,
+			Reason:              reason,
+		})
+	}
 
+	// First pass: DNS services claim hostnames in precedence order
 	for _, c := range containers {
 		for _, svc := range c.DNSServices {
-			if existing, ok := dnsHostnames[svc.Hostname]; ok {
-				log.Warn().
-					Str("hostname", svc.Hostname).
-					Str("existing", existing).
-					Str("new", c.Info.Name).
-					Msg("DNS hostname conflict across containers, first wins")
-			} else {
-				dnsHostnames[svc.Hostname] = c.Info.Name
+			key := svc.Hostname + ":" + string(svc.Type)
+			if winner, ok := dnsOwners[key]; ok {
+				droppedDNS[svc] = true
+				addConflict(storage.ResourceTypeDNS, svc.Hostname, c, svc.ServiceName, winner,
+					conflictReason(winner.container, c, winner.service))
+				continue
 			}
-		}
-		for _, svc := range c.TunnelServices {
-			if existing, ok := tunnelHostnames[svc.Hostname]; ok {
-				log.Warn().
-					Str("hostname", svc.Hostname).
-					Str("existing", existing).
-					Str("new", c.Info.Name).
-					Msg("Tunnel hostname conflict across containers, first wins")
-			} else {
-				tunnelHostnames[svc.Hostname] = c.Info.Name
+			owner := serviceOwner{container: c, service: svc.ServiceName}
+			dnsOwners[key] = owner
+			if _, ok := dnsHostnames[svc.Hostname]; !ok {
+				dnsHostnames[svc.Hostname] = owner
 			}
 		}
 	}
 
-	// Detect DNS vs Tunnel conflicts
-	for hostname, dnsContainer := range dnsHostnames {
-		if tunnelContainer, ok := tunnelHostnames[hostname]; ok {
-			conflictedHostnames[hostname] = true
-			log.Error().
-				Str("hostname", hostname).
-				Str("dns_container", dnsContainer).
-				Str("tunnel_container", tunnelContainer).
-				Msg("Hostname cannot be used for both DNS and Tunnel, removing tunnel service")
+	// Second pass: tunnel services, which never win against a DNS record
+	rollouts := rolloutOwners(containers)
+	for _, c := range containers {
+		for _, svc := range c.TunnelServices {
+			if dnsOwner, ok := dnsHostnames[svc.Hostname]; ok {
+				droppedTunnels[svc] = true
+				addConflict(storage.ResourceTypeTunnelIngress, svc.Hostname, c, svc.ServiceName, dnsOwner,
+					fmt.Sprintf("hostname is used by DNS service %q of %s", dnsOwner.service, dnsOwner.container.Info.Name))
+				continue
+			}
+			key := svc.Hostname + ":" + svc.Path
+			if owner, ok := rollouts[key]; ok && owner.container != c {
+				droppedTunnels[svc] = true
+				addConflict(storage.ResourceTypeTunnelIngress, svc.Hostname, c, svc.ServiceName, owner.serviceOwner,
+					rolloutReason(owner, c))
+				continue
+			}
+			if winner, ok := tunnelOwners[key]; ok {
+				droppedTunnels[svc] = true
+				addConflict(storage.ResourceTypeTunnelIngress, svc.Hostname, c, svc.ServiceName, winner,
+					conflictReason(winner.container, c, winner.service))
+				continue
+			}
+			tunnelOwners[key] = serviceOwner{container: c, service: svc.ServiceName}
 		}
 	}
 
-	if len(conflictedHostnames) == 0 {
-		return containers
+	if len(conflicts) == 0 {
+		return containers, nil
 	}
 
-	// Second pass: rebuild containers with conflicted tunnel services removed
+	// Rebuild containers with losing services removed
 	result := make([]*types.ParsedContainer, 0, len(containers))
 	for _, c := range containers {
-		// Filter tunnel services to remove conflicted hostnames
-		hasConflict := false
+		dnsServices := make([]*types.DNSService, 0, len(c.DNSServices))
+		for _, svc := range c.DNSServices {
+			if !droppedDNS[svc] {
+				dnsServices = append(dnsServices, svc)
+			}
+		}
+		tunnelServices := make([]*types.TunnelService, 0, len(c.TunnelServices))
 		for _, svc := range c.TunnelServices {
-			if conflictedHostnames[svc.Hostname] {
-				hasConflict = true
-				break
+			if !droppedTunnels[svc] {
+				tunnelServices = append(tunnelServices, svc)
 			}
 		}
 
-		if !hasConflict {
+		if len(dnsServices) == len(c.DNSServices) && len(tunnelServices) == len(c.TunnelServices) {
 			result = append(result, c)
 			continue
 		}
 
-		// Create a copy with filtered tunnel services
-		filtered := make([]*types.TunnelService, 0, len(c.TunnelServices))
-		for _, svc := range c.TunnelServices {
-			if !conflictedHostnames[svc.Hostname] {
-				filtered = append(filtered, svc)
-			}
-		}
-
 		result = append(result, &types.ParsedContainer{
 			Info:           c.Info,
-			DNSServices:    c.DNSServices,
-			TunnelServices: filtered,
+			DNSServices:    dnsServices,
+			TunnelServices: tunnelServices,
 			AccessPolicies: c.AccessPolicies,
 			AgentID:        c.AgentID,
+			Priority:       c.Priority,
+			Rollout:        c.Rollout,
+			Stopping:       c.Stopping,
+		})
+	}
+
+	return result, conflicts
+}
+
+// rolloutOwner is the owner a rollout assigns to a tunnel rule.
+type rolloutOwner struct {
+	serviceOwner
+	rollout *types.ParsedContainer // the container rolling out
+}
+
+// rolloutOwners assigns the tunnel rules (hostname:path) that a running
+// container with a rollout weight shares with other running containers: it
+// takes over that percentage of them, rounded down, and the container with
+// precedence among the others keeps the rest. Rules are taken in a fixed hash
+// order, so raising the weight only adds rules. Rules shared by several
+// containers with a rollout weight follow precedence. containers must be in
+// precedence order.
+func rolloutOwners(containers []*types.ParsedContainer) map[string]rolloutOwner {
+	claims := make(map[string][]serviceOwner) // hostname:path -> running claimants in precedence order
+	for _, c := range containers {
+		if c.Stopping {
+			continue
+		}
+		for _, svc := range c.TunnelServices {
+			key := svc.Hostname + ":" + svc.Path
+			claims[key] = append(claims[key], serviceOwner{container: c, service: svc.ServiceName})
+		}
+	}
+
+	owners := make(map[string]rolloutOwner)
+	for _, w := range containers {
+		if w.Rollout == 0 || w.Stopping {
+			continue
+		}
+
+		var shared []string
+		for _, svc := range w.TunnelServices {
+			key := svc.Hostname + ":" + svc.Path
+			if slices.Contains(shared, key) {
+				continue
+			}
+			others, weighted := 0, false
+			for _, claim := range claims[key] {
+				if claim.container != w {
+					others++
+					weighted = weighted || claim.container.Rollout > 0
+				}
+			}
+			if others > 0 && !weighted {
+				shared = append(shared, key)
+			}
+		}
+
+		sort.Slice(shared, func(i, j int) bool {
+			hi, hj := rolloutHash(shared[i]), rolloutHash(shared[j])
+			if hi != hj {
+				return hi < hj
+			}
+			return shared[i] < shared[j]
 		})
+		take := len(shared) * w.Rollout / 100
+		for i, key := range shared {
+			for _, claim := range claims[key] {
+				if (claim.container == w) == (i < take) {
+					owners[key] = rolloutOwner{serviceOwner: claim, rollout: w}
+					break
+				}
+			}
+		}
 	}
+	return owners
+}
 
-	return result
+// rolloutHash orders the tunnel rules of a rollout.
+func rolloutHash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// rolloutReason explains why loser lost a tunnel rule assigned by a rollout.
+func rolloutReason(owner rolloutOwner, loser *types.ParsedContainer) string {
+	switch {
+	case loser.Stopping:
+		return conflictReason(owner.container, loser, owner.service)
+	case owner.container == owner.rollout:
+		return fmt.Sprintf("rolled out to %s (rollout %d%%)", owner.container.Info.Name, owner.rollout.Rollout)
+	default:
+		return fmt.Sprintf("kept by %s during the rollout of %s (rollout %d%%)", owner.container.Info.Name, owner.rollout.Info.Name, owner.rollout.Rollout)
+	}
 }
 
 // resolveAccessReferences resolves access policy references from DNS and Tunnel services.
@@ -738,7 +1186,8 @@ func (r *Reconciler) RemoveAgentData(agentID string) {
 		Msg("Removed agent data")
 }
 
-// TriggerReconcile triggers an immediate reconciliation.
+// TriggerReconcile runs a full reconciliation immediately, after any pass in
+// progress, and returns its error.
 func (r *Reconciler) TriggerReconcile(ctx context.Context) error {
 	return r.reconcile(ctx)
 }
@@ -751,6 +1200,26 @@ func (r *Reconciler) GetContainers() []*types.ParsedContainer {
 	return r.getDesiredState()
 }
 
+// Timings returns the current reconciler timings.
+func (r *Reconciler) Timings() Timings {
+	r.timingsMu.RLock()
+	defer r.timingsMu.RUnlock()
+	return r.timings
+}
+
+// SetTimings replaces the reconciler timings, e.g. on a configuration
+// reload. A running loop picks up the new poll interval immediately.
+func (r *Reconciler) SetTimings(t Timings) {
+	r.timingsMu.Lock()
+	r.timings = t
+	r.timingsMu.Unlock()
+
+	select {
+	case r.timingsChanged <- struct{}{}:
+	default:
+	}
+}
+
 // StartedAt returns when the reconciler started.
 func (r *Reconciler) StartedAt() time.Time {
 	return r.startedAt
@@ -0,0 +1,583 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/channinghe/labelgate/internal/events"
+	"github.com/channinghe/labelgate/internal/operator"
+	"github.com/channinghe/labelgate/internal/storage"
+	"github.com/channinghe/labelgate/internal/types"
+)
+
+// fakeProvider serves a fixed set of containers and forwards events sent to
+// its events channel.
+type fakeProvider struct {
+	mu         sync.Mutex
+	containers map[string]*types.ContainerInfo
+	events     chan *types.ContainerEvent
+}
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{
+		containers: make(map[string]*types.ContainerInfo),
+		events:     make(chan *types.ContainerEvent, 100),
+	}
+}
+
+func (p *fakeProvider) Name() string                      { return "fake" }
+func (p *fakeProvider) Connect(ctx context.Context) error { return nil }
+func (p *fakeProvider) Close() error                      { return nil }
+func (p *fakeProvider) Ping(ctx context.Context) error    { return nil }
+
+func (p *fakeProvider) ListContainers(ctx context.Context) ([]*types.ContainerInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	containers := make([]*types.ContainerInfo, 0, len(p.containers))
+	for _, c := range p.containers {
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
+
+func (p *fakeProvider) GetContainer(ctx context.Context, id string) (*types.ContainerInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.containers[id]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("container %s not found", id)
+}
+
+func (p *fakeProvider) Watch(ctx context.Context, events chan<- *types.ContainerEvent) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-p.events:
+			events <- event
+		}
+	}
+}
+
+// add registers a container and returns its ID.
+func (p *fakeProvider) add(name string, labels map[string]string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := fmt.Sprintf("%-12s", name+"-id")
+	p.containers[id] = &types.ContainerInfo{ID: id, Name: name, Labels: labels, State: "running", Created: time.Now()}
+	return id
+}
+
+// start sends a start event for container id.
+func (p *fakeProvider) start(id string) {
+	p.events <- &types.ContainerEvent{Type: types.EventStart, ContainerID: id, Timestamp: time.Now()}
+}
+
+// kill sends a kill event for container id.
+func (p *fakeProvider) kill(id string) {
+	p.events <- &types.ContainerEvent{Type: types.EventKill, ContainerID: id, Timestamp: time.Now()}
+}
+
+// recordingDNS is a DNS operator recording the scope of each reconcile.
+type recordingDNS struct {
+	operator.DNSOperator
+	scopes chan operator.Scope
+
+	mu      sync.Mutex
+	desired []*types.ParsedContainer
+}
+
+func (o *recordingDNS) Reconcile(ctx context.Context, desired []*types.ParsedContainer, scope operator.Scope) error {
+	o.mu.Lock()
+	o.desired = desired
+	o.mu.Unlock()
+	o.scopes <- scope
+	return nil
+}
+
+// owner returns the name of the container whose DNS service for hostname
+// was passed to the last reconcile.
+func (o *recordingDNS) owner(hostname string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, c := range o.desired {
+		for _, svc := range c.DNSServices {
+			if svc.Hostname == hostname {
+				return c.Info.Name
+			}
+		}
+	}
+	return ""
+}
+
+// next returns the scope of the next reconcile, failing after timeout.
+func (o *recordingDNS) next(t *testing.T, timeout time.Duration) operator.Scope {
+	t.Helper()
+	select {
+	case scope := <-o.scopes:
+		return scope
+	case <-time.After(timeout):
+		t.Fatal("no reconcile")
+		return nil
+	}
+}
+
+// expectNone fails if a reconcile happens within d.
+func (o *recordingDNS) expectNone(t *testing.T, d time.Duration) {
+	t.Helper()
+	select {
+	case scope := <-o.scopes:
+		t.Errorf("unexpected reconcile with scope %v", hostnames(scope))
+	case <-time.After(d):
+	}
+}
+
+// hostnames returns the sorted hostnames of scope.
+func hostnames(scope operator.Scope) []string {
+	names := make([]string, 0, len(scope))
+	for name := range scope {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func dnsLabels(hostname string) map[string]string {
+	return map[string]string{
+		"labelgate.dns.web.hostname": hostname,
+		"labelgate.dns.web.type":     "A",
+		"labelgate.dns.web.target":   "1.2.3.4",
+	}
+}
+
+// runTestReconciler runs a reconciler with the given debounce window until
+// the test ends, after its initial full reconcile.
+// newTestStorage returns an initialized SQLite storage in a temporary directory.
+func newTestStorage(t *testing.T) storage.Storage {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "labelgate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// startReconciler runs r until the test ends.
+func startReconciler(t *testing.T, r *Reconciler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func runTestReconciler(t *testing.T, p *fakeProvider, debounce time.Duration) *recordingDNS {
+	t.Helper()
+	dns := &recordingDNS{scopes: make(chan operator.Scope, 100)}
+	r := NewReconciler(&Config{
+		Provider:     p,
+		Storage:      newTestStorage(t),
+		LabelPrefix:  "labelgate",
+		DNSOperator:  dns,
+		PollInterval: time.Hour,
+		Debounce:     debounce,
+	})
+	startReconciler(t, r)
+
+	if scope := dns.next(t, 5*time.Second); scope != nil {
+		t.Fatalf("expected an initial full reconcile, got scope %v", hostnames(scope))
+	}
+	return dns
+}
+
+func TestRun_DebouncesEventBurst(t *testing.T) {
+	p := newFakeProvider()
+	dns := runTestReconciler(t, p, 100*time.Millisecond)
+
+	ids := []string{
+		p.add("web-1", dnsLabels("a.example.com")),
+		p.add("web-2", dnsLabels("b.example.com")),
+		p.add("web-3", dnsLabels("c.example.com")),
+	}
+	for _, id := range ids {
+		p.start(id)
+	}
+
+	scope := dns.next(t, 2*time.Second)
+	if got := fmt.Sprint(hostnames(scope)); got != "[a.example.com b.example.com c.example.com]" {
+		t.Errorf("expected one scoped reconcile of the burst, got %s", got)
+	}
+	dns.expectNone(t, 300*time.Millisecond)
+}
+
+func TestRun_FlushesSteadyEventStream(t *testing.T) {
+	p := newFakeProvider()
+	const window = 30 * time.Millisecond
+	dns := runTestReconciler(t, p, window)
+	id := p.add("web", dnsLabels("a.example.com"))
+
+	// Events arriving faster than the window would keep the batch open
+	// forever; it is flushed after maxDebounceFactor windows instead
+	deadline := time.Now().Add(3 * maxDebounceFactor * window)
+	for time.Now().Before(deadline) {
+		p.start(id)
+		select {
+		case scope := <-dns.scopes:
+			if scope == nil || !scope.Has("a.example.com") {
+				t.Errorf("expected a scoped reconcile of a.example.com, got %v", hostnames(scope))
+			}
+			return
+		case <-time.After(window / 3):
+		}
+	}
+	t.Fatal("steady event stream was never flushed")
+}
+
+func TestRun_AccessPolicyChangeReconcilesFully(t *testing.T) {
+	p := newFakeProvider()
+	dns := runTestReconciler(t, p, 50*time.Millisecond)
+
+	labels := dnsLabels("a.example.com")
+	labels["labelgate.access.internal.policy.decision"] = "allow"
+	labels["labelgate.access.internal.policy.include.emails"] = "admin@example.com"
+	policies := p.add("policies", labels)
+	web := p.add("web", dnsLabels("b.example.com"))
+
+	// Other containers may reference the policies, so scoping would miss them
+	p.start(web)
+	p.start(policies)
+	if scope := dns.next(t, 2*time.Second); scope != nil {
+		t.Errorf("expected a full reconcile, got scope %v", hostnames(scope))
+	}
+	dns.expectNone(t, 200*time.Millisecond)
+
+	// A container without policies stays scoped
+	p.start(web)
+	if scope := dns.next(t, 2*time.Second); scope == nil || !scope.Has("b.example.com") || scope.Has("a.example.com") {
+		t.Errorf("expected a reconcile scoped to b.example.com, got %v", hostnames(scope))
+	}
+}
+
+func TestRun_KillHandsOverToReplacement(t *testing.T) {
+	p := newFakeProvider()
+	blue := p.add("blue", dnsLabels("app.example.com"))
+	time.Sleep(time.Millisecond)
+	p.add("green", dnsLabels("app.example.com"))
+	dns := runTestReconciler(t, p, 50*time.Millisecond)
+
+	// The older container wins until it is told to stop
+	if got := dns.owner("app.example.com"); got != "blue" {
+		t.Fatalf("expected blue to own app.example.com, got %q", got)
+	}
+
+	// A repeated signal changes nothing
+	p.kill(blue)
+	p.kill(blue)
+	scope := dns.next(t, 2*time.Second)
+	if scope == nil || !scope.Has("app.example.com") {
+		t.Errorf("expected a reconcile scoped to app.example.com, got %v", hostnames(scope))
+	}
+	if got := dns.owner("app.example.com"); got != "green" {
+		t.Errorf("expected green to take over app.example.com, got %q", got)
+	}
+	dns.expectNone(t, 200*time.Millisecond)
+}
+
+// blockingDNS holds each reconcile until it is given the result to return.
+type blockingDNS struct {
+	operator.DNSOperator
+	entered chan struct{}
+	results chan error
+
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+func (o *blockingDNS) Reconcile(ctx context.Context, desired []*types.ParsedContainer, scope operator.Scope) error {
+	n := o.active.Add(1)
+	defer o.active.Add(-1)
+	for {
+		m := o.maxActive.Load()
+		if n <= m || o.maxActive.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	o.entered <- struct{}{}
+	return <-o.results
+}
+
+func TestTriggerReconcile_WaitsForRunningPass(t *testing.T) {
+	dns := &blockingDNS{entered: make(chan struct{}, 10), results: make(chan error)}
+	r := NewReconciler(&Config{
+		Provider:     newFakeProvider(),
+		Storage:      newTestStorage(t),
+		LabelPrefix:  "labelgate",
+		DNSOperator:  dns,
+		PollInterval: time.Hour,
+	})
+	startReconciler(t, r)
+
+	// The scheduled initial pass is in progress
+	select {
+	case <-dns.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no initial reconcile")
+	}
+
+	manual := make(chan error, 1)
+	go func() { manual <- r.TriggerReconcile(context.Background()) }()
+
+	select {
+	case <-dns.entered:
+		t.Fatal("manual reconcile started during the scheduled one")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The scheduled pass fails; the manual pass then runs and succeeds
+	dns.results <- fmt.Errorf("scheduled pass failed")
+	select {
+	case <-dns.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("manual reconcile did not run")
+	}
+	dns.results <- nil
+
+	select {
+	case err := <-manual:
+		if err != nil {
+			t.Errorf("expected the manual pass's own result, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("manual reconcile did not return")
+	}
+	if n := dns.maxActive.Load(); n != 1 {
+		t.Errorf("expected passes to run one at a time, got %d at once", n)
+	}
+}
+
+// deletingDNS deletes resources from storage, or fails while fail is set.
+type deletingDNS struct {
+	operator.DNSOperator
+	store storage.Storage
+	fail  bool
+}
+
+func (o *deletingDNS) Delete(ctx context.Context, resource *storage.ManagedResource) error {
+	if o.fail {
+		return fmt.Errorf("forbidden")
+	}
+	return o.store.DeleteResource(ctx, resource.ID)
+}
+
+func TestProcessOrphanedCleanups_PublishesOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	if err := store.SaveResource(ctx, &storage.ManagedResource{
+		ResourceType:   storage.ResourceTypeDNS,
+		Hostname:       "old.example.com",
+		RecordType:     "A",
+		Content:        "1.2.3.4",
+		ContainerID:    "container123",
+		ServiceName:    "web",
+		Status:         storage.StatusOrphaned,
+		CleanupEnabled: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond) // past the (zero) removal delay
+
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.Filter{Types: []string{"orphan"}}, 0)
+	defer sub.Close()
+	published := func() []events.Type {
+		var seen []events.Type
+		for {
+			select {
+			case e := <-sub.C:
+				seen = append(seen, e.Type)
+			default:
+				return seen
+			}
+		}
+	}
+
+	dns := &deletingDNS{store: store, fail: true}
+	r := NewReconciler(&Config{Storage: store, DNSOperator: dns, Events: bus})
+
+	for range 3 {
+		r.processOrphanedCleanups(ctx)
+	}
+	got := published()
+	if len(got) != 2 || got[0] != events.TypeOrphanCleanup || got[1] != events.TypeOrphanCleanupFailed {
+		t.Fatalf("expected one cleanup and one failure event over three passes, got %v", got)
+	}
+
+	dns.fail = false
+	r.processOrphanedCleanups(ctx)
+	if got := published(); len(got) != 0 {
+		t.Errorf("expected no events for the retried delete, got %v", got)
+	}
+	if left, _ := store.ListOrphanedForCleanup(ctx, time.Now()); len(left) != 0 {
+		t.Errorf("expected the orphan to be deleted, got %d left", len(left))
+	}
+	if len(r.orphanCleanups) != 0 {
+		t.Errorf("expected deleted orphans to be forgotten, got %v", r.orphanCleanups)
+	}
+}
+
+func TestFilterHostnameConflicts_OrderIndependent(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	container := func(name, id string, priority int, created time.Time, stopping bool) *types.ParsedContainer {
+		return &types.ParsedContainer{
+			Info:     &types.ContainerInfo{ID: id, Name: name, Created: created},
+			Priority: priority,
+			Stopping: stopping,
+			DNSServices: []*types.DNSService{
+				{ServiceName: "web", Hostname: "app.example.com", Type: types.DNSTypeA, Target: "1.2.3.4"},
+			},
+			TunnelServices: []*types.TunnelService{
+				{ServiceName: "web", Hostname: "tunnel.example.com", Service: "http://web:80"},
+			},
+		}
+	}
+	// Precedence: stopping, then priority, then Created, then ID
+	containers := []*types.ParsedContainer{
+		container("stopping", "aaaaaaaaaaaa", 100, t0, true),
+		container("low", "bbbbbbbbbbbb", 0, t0, false),
+		container("newer", "dddddddddddd", 5, t0.Add(2*time.Hour), false),
+		container("higher-id", "eeeeeeeeeeee", 5, t0.Add(time.Hour), false),
+		container("winner", "cccccccccccc", 5, t0.Add(time.Hour), false),
+	}
+	wantReasons := map[string]string{
+		"stopping":  "container is stopping, handed over to winner",
+		"low":       "lower priority (0) than winner (5)",
+		"newer":     "created after winner",
+		"higher-id": "container ID sorts after winner",
+	}
+
+	r := &Reconciler{}
+	var first []string
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		shuffled := append([]*types.ParsedContainer(nil), containers...)
+		rng.Shuffle(len(shuffled), func(a, b int) { shuffled[a], shuffled[b] = shuffled[b], shuffled[a] })
+		sort.SliceStable(shuffled, func(i, j int) bool { return containerLess(shuffled[i], shuffled[j]) })
+
+		desired, conflicts := r.filterHostnameConflicts(shuffled)
+		for _, c := range desired {
+			want := 0
+			if c.Info.Name == "winner" {
+				want = 1
+			}
+			if len(c.DNSServices) != want || len(c.TunnelServices) != want {
+				t.Fatalf("run %d: %s kept %d DNS and %d tunnel services, want %d", i, c.Info.Name, len(c.DNSServices), len(c.TunnelServices), want)
+			}
+		}
+
+		var records []string
+		for _, c := range conflicts {
+			if c.WinnerContainerName != "winner" || c.Reason != wantReasons[c.ContainerName] {
+				t.Errorf("run %d: %s lost %s to %s: %q", i, c.ContainerName, c.Hostname, c.WinnerContainerName, c.Reason)
+			}
+			records = append(records, fmt.Sprintf("%s %s %s %s %s", c.ResourceType, c.Hostname, c.ContainerName, c.WinnerContainerName, c.Reason))
+		}
+		if len(records) != 2*len(wantReasons) {
+			t.Fatalf("run %d: expected %d conflicts, got %v", i, 2*len(wantReasons), records)
+		}
+		if first == nil {
+			first = records
+		} else if fmt.Sprint(records) != fmt.Sprint(first) {
+			t.Fatalf("run %d: conflicts depend on container order:\n%v\n%v", i, records, first)
+		}
+	}
+}
+
+func TestFilterHostnameConflicts_Rollout(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	paths := []string{"/", "/api", "/static", "/admin"}
+	container := func(name, id string, created time.Time, rollout int, stopping bool) *types.ParsedContainer {
+		c := &types.ParsedContainer{
+			Info:     &types.ContainerInfo{ID: id, Name: name, Created: created},
+			Rollout:  rollout,
+			Stopping: stopping,
+		}
+		for _, path := range paths {
+			c.TunnelServices = append(c.TunnelServices, &types.TunnelService{
+				ServiceName: "web" + strings.ReplaceAll(path, "/", "-"),
+				Hostname:    "app.example.com",
+				Path:        path,
+				Service:     "http://" + name + ":80",
+			})
+		}
+		return c
+	}
+	// rolledOut returns the paths the green container kept, checking that
+	// every path is served by exactly one container.
+	rolledOut := func(rollout int, blueStopping bool) []string {
+		t.Helper()
+		containers := []*types.ParsedContainer{
+			container("blue", "aaaaaaaaaaaa", t0, 0, blueStopping),
+			container("green", "bbbbbbbbbbbb", t0.Add(time.Hour), rollout, false),
+		}
+		sort.SliceStable(containers, func(i, j int) bool { return containerLess(containers[i], containers[j]) })
+
+		desired, conflicts := (&Reconciler{}).filterHostnameConflicts(containers)
+		served := make(map[string]string)
+		var green []string
+		for _, c := range desired {
+			for _, svc := range c.TunnelServices {
+				if owner, ok := served[svc.Path]; ok {
+					t.Fatalf("rollout %d: %s served by %s and %s", rollout, svc.Path, owner, c.Info.Name)
+				}
+				served[svc.Path] = c.Info.Name
+				if c.Info.Name == "green" {
+					green = append(green, svc.Path)
+				}
+			}
+		}
+		if len(served) != len(paths) || len(conflicts) != len(paths) {
+			t.Fatalf("rollout %d: expected every path served once, got %v and %d conflicts", rollout, served, len(conflicts))
+		}
+		sort.Strings(green)
+		return green
+	}
+
+	if got := rolledOut(0, false); len(got) != 0 {
+		t.Errorf("expected the older container to keep every path without a rollout, got %v", got)
+	}
+	previous := []string{}
+	for _, rollout := range []int{25, 50, 75, 100} {
+		got := rolledOut(rollout, false)
+		if want := len(paths) * rollout / 100; len(got) != want {
+			t.Errorf("rollout %d: expected %d paths rolled out, got %v", rollout, want, got)
+		}
+		for _, path := range previous {
+			if !slices.Contains(got, path) {
+				t.Errorf("rollout %d: %s moved back after rollout of %v", rollout, path, previous)
+			}
+		}
+		previous = got
+	}
+	if got := rolledOut(25, true); len(got) != len(paths) {
+		t.Errorf("expected a stopping container to hand over every path, got %v", got)
+	}
+}
@@ -0,0 +1,176 @@
+// Package reload applies configuration changes to a running instance, on
+// SIGHUP or through the API. Settings that can't change at runtime are
+// reported as requiring a restart and keep their current values.
+package reload
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/channinghe/labelgate/internal/agent"
+	"github.com/channinghe/labelgate/internal/cloudflare"
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/reconciler"
+)
+
+// timingKeys are the reconciler settings applied through SetTimings.
+var timingKeys = []string{"docker.poll_interval", "sync.orphan_ttl", "sync.remove_delay", "sync.debounce"}
+
+// Result lists the changed settings of a reload by their config keys
+// (e.g. "sync.debounce"). Maps such as cloudflare.credentials are
+// reported as a whole.
+type Result struct {
+	// Applied are the changed settings now in effect
+	Applied []string `json:"applied"`
+	// RestartRequired are the changed settings that only take effect after a restart
+	RestartRequired []string `json:"restart_required"`
+}
+
+// Components are the running components that apply reloaded settings.
+// A nil component leaves its settings to a restart.
+type Components struct {
+	Credentials *cloudflare.CredentialManager // cloudflare.*
+	AgentServer *agent.Server                 // agent.accept_token, agent.agents
+	Reconciler  *reconciler.Reconciler        // docker.poll_interval, sync.orphan_ttl, sync.remove_delay, sync.debounce
+}
+
+// reloadable reports whether the setting key can be applied by c.
+func (c *Components) reloadable(key string) bool {
+	switch {
+	case key == "log_level":
+		return true
+	case strings.HasPrefix(key, "cloudflare."):
+		return c.Credentials != nil
+	case key == "agent.accept_token" || key == "agent.agents":
+		return c.AgentServer != nil
+	case slices.Contains(timingKeys, key):
+		return c.Reconciler != nil
+	}
+	return false
+}
+
+// Reloader re-reads the configuration and applies the changes.
+type Reloader struct {
+	configPath string
+
+	mu         sync.Mutex
+	cfg        *config.Config // settings in effect
+	components Components
+}
+
+// New creates a reloader for the configuration loaded from configPath (the
+// CLI flag, may be empty) as cfg.
+func New(configPath string, cfg *config.Config) *Reloader {
+	return &Reloader{configPath: configPath, cfg: cfg}
+}
+
+// Register sets the components that apply reloaded settings, once they are
+// created.
+func (r *Reloader) Register(c Components) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.components = c
+}
+
+// Reload re-reads the configuration and applies the changed settings. An
+// invalid configuration returns an error and changes nothing.
+func (r *Reloader) Reload(ctx context.Context) (*Result, error) {
+	next, err := config.Reload(r.configPath)
+	if err != nil {
+		return nil, err
+	}
+	return r.apply(next)
+}
+
+// apply applies the reloadable settings of next that differ from the
+// settings in effect.
+func (r *Reloader) apply(next *config.Config) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &Result{Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range changedSettings("", reflect.ValueOf(r.cfg).Elem(), reflect.ValueOf(next).Elem()) {
+		if r.components.reloadable(key) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+	applied := func(prefixes ...string) bool {
+		return slices.ContainsFunc(result.Applied, func(key string) bool {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(key, prefix) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	// Steps that can fail go first, so a failure changes nothing
+	level, err := zerolog.ParseLevel(next.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log_level: %w", err)
+	}
+	if applied("cloudflare.") {
+		if err := r.components.Credentials.Update(next); err != nil {
+			return nil, fmt.Errorf("failed to apply cloudflare settings: %w", err)
+		}
+	}
+
+	cfg := *r.cfg
+	if applied("cloudflare.") {
+		cfg.Cloudflare = next.Cloudflare
+	}
+	if applied("log_level") {
+		zerolog.SetGlobalLevel(level)
+		cfg.LogLevel = next.LogLevel
+	}
+	if applied("agent.") {
+		r.components.AgentServer.UpdateAgents(next.Agent.AcceptToken, agent.ConfigEntries(&next.Agent))
+		cfg.Agent.AcceptToken, cfg.Agent.Agents = next.Agent.AcceptToken, next.Agent.Agents
+	}
+	if applied(timingKeys...) {
+		cfg.Docker.PollInterval = next.Docker.PollInterval
+		cfg.Sync.OrphanTTL, cfg.Sync.RemoveDelay, cfg.Sync.Debounce = next.Sync.OrphanTTL, next.Sync.RemoveDelay, next.Sync.Debounce
+		r.components.Reconciler.SetTimings(reconciler.Timings{
+			PollInterval: cfg.Docker.PollInterval,
+			OrphanTTL:    cfg.Sync.OrphanTTL,
+			RemoveDelay:  cfg.Sync.RemoveDelay,
+			Debounce:     cfg.Sync.Debounce,
+		})
+	}
+	r.cfg = &cfg
+
+	log.Info().
+		Strs("applied", result.Applied).
+		Strs("restart_required", result.RestartRequired).
+		Msg("Configuration reloaded")
+	return result, nil
+}
+
+// changedSettings returns the keys of the settings that differ between the
+// config structs a and b, joined from their mapstructure tags. Maps and
+// slices are compared as a whole.
+func changedSettings(prefix string, a, b reflect.Value) []string {
+	var keys []string
+	for i := range a.NumField() {
+		field := a.Type().Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, changedSettings(key+".", a.Field(i), b.Field(i))...)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,172 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/channinghe/labelgate/internal/agent"
+	"github.com/channinghe/labelgate/internal/cloudflare"
+	"github.com/channinghe/labelgate/internal/config"
+	"github.com/channinghe/labelgate/internal/reconciler"
+)
+
+const baseConfig = `
+log_level: info
+cloudflare:
+  api_token: token-a
+docker:
+  poll_interval: 30s
+sync:
+  workers: 4
+  debounce: 1s
+agent:
+  enabled: true
+  agents:
+    agent-1:
+      token: secret-1
+`
+
+// writeConfig writes a config file and returns its path.
+func writeConfig(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestReloader loads baseConfig and registers components created from it.
+func newTestReloader(t *testing.T) (*Reloader, string, Components) {
+	t.Helper()
+	path := writeConfig(t, filepath.Join(t.TempDir(), "labelgate.yaml"), baseConfig)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credManager, err := cloudflare.NewCredentialManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := reconciler.NewReconciler(&reconciler.Config{
+		PollInterval: cfg.Docker.PollInterval,
+		Debounce:     cfg.Sync.Debounce,
+	})
+	components := Components{
+		Credentials: credManager,
+		AgentServer: agent.NewServer(&cfg.Agent, agent.ConfigEntries(&cfg.Agent), rec, nil, "labelgate"),
+		Reconciler:  rec,
+	}
+	r := New(path, cfg)
+	r.Register(components)
+	return r, path, components
+}
+
+func TestReload_AppliesReloadableSettings(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	r, path, components := newTestReloader(t)
+
+	writeConfig(t, path, `
+log_level: debug
+cloudflare:
+  api_token: token-b
+docker:
+  poll_interval: 10s
+sync:
+  workers: 8
+  debounce: 2s
+agent:
+  enabled: true
+  agents:
+    agent-1:
+      token: secret-2
+`)
+	result, err := r.Reload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"log_level", "cloudflare.api_token", "docker.poll_interval", "sync.debounce", "agent.agents"} {
+		if !slices.Contains(result.Applied, key) {
+			t.Errorf("expected %s to be applied, got %v", key, result.Applied)
+		}
+	}
+	if !slices.Equal(result.RestartRequired, []string{"sync.workers"}) {
+		t.Errorf("expected sync.workers to require a restart, got %v", result.RestartRequired)
+	}
+
+	if timings := components.Reconciler.Timings(); timings.PollInterval != 10*time.Second || timings.Debounce != 2*time.Second {
+		t.Errorf("expected new reconciler timings, got %+v", timings)
+	}
+	if level := zerolog.GlobalLevel(); level != zerolog.DebugLevel {
+		t.Errorf("expected debug log level, got %s", level)
+	}
+	if cred, err := components.Credentials.GetCredentialForZone("app.example.com", ""); err != nil || cred.APIToken != "token-b" {
+		t.Errorf("expected the new default credential, got %+v (%v)", cred, err)
+	}
+
+	// A second reload of the same file only reports what still needs a restart
+	result, err = r.Reload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Applied) != 0 || !slices.Equal(result.RestartRequired, []string{"sync.workers"}) {
+		t.Errorf("expected only sync.workers pending, got %+v", result)
+	}
+}
+
+func TestReload_InvalidConfigKeepsCurrent(t *testing.T) {
+	r, path, components := newTestReloader(t)
+
+	writeConfig(t, path, `
+cloudflare:
+  api_token: token-a
+docker:
+  poll_interval: 0s
+sync:
+  debounce: 5s
+`)
+	if _, err := r.Reload(context.Background()); err == nil {
+		t.Fatal("expected an error for an invalid configuration")
+	}
+	if timings := components.Reconciler.Timings(); timings.PollInterval != 30*time.Second || timings.Debounce != time.Second {
+		t.Errorf("expected the current timings to be kept, got %+v", timings)
+	}
+}
+
+func TestReload_WithoutComponents(t *testing.T) {
+	path := writeConfig(t, filepath.Join(t.TempDir(), "labelgate.yaml"), baseConfig)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(path, cfg)
+
+	writeConfig(t, path, `
+cloudflare:
+  api_token: token-a
+docker:
+  poll_interval: 30s
+sync:
+  workers: 4
+  debounce: 3s
+agent:
+  enabled: true
+  agents:
+    agent-1:
+      token: secret-1
+`)
+	result, err := r.Reload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Applied) != 0 || !slices.Equal(result.RestartRequired, []string{"sync.debounce"}) {
+		t.Errorf("expected sync.debounce to require a restart without a reconciler, got %+v", result)
+	}
+}
@@ -0,0 +1,38 @@
+package storage
+
+import "time"
+
+// Audit entry statuses.
+const (
+	AuditSuccess = "success"
+	AuditError   = "error"
+)
+
+// AuditEntry records an action taken through the API, such as a manual
+// reconcile or a force-delete. Entries are append-only.
+type AuditEntry struct {
+	ID int64 `json:"id"`
+
+	// Action is the action name, e.g. "reconcile" or "resource.force_delete"
+	Action string `json:"action"`
+	// Target is the resource or agent ID the action applied to (empty for global actions)
+	Target string `json:"target,omitempty"`
+	// Actor identifies who requested the action
+	Actor string `json:"actor,omitempty"`
+
+	// Outcome
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Detail string `json:"detail,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditFilter represents filter options for querying the audit trail.
+type AuditFilter struct {
+	Action string
+	Target string
+	Since  time.Time
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// Backuper is implemented by backends that can take an online snapshot of
+// the database into a file while it is in use.
+type Backuper interface {
+	Backup(ctx context.Context, dst string) error
+}
+
+// LatestSchemaVersion is the schema version this build migrates to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// CheckSchemaVersion verifies that a database at version can be used by this
+// build. Older schemas are migrated on startup; newer ones come from a newer
+// labelgate and would lose data.
+func CheckSchemaVersion(version int) error {
+	if version <= 0 {
+		return fmt.Errorf("not a labelgate database: no schema version")
+	}
+	if latest := LatestSchemaVersion(); version > latest {
+		return fmt.Errorf("database schema version %d is newer than supported version %d; upgrade labelgate first", version, latest)
+	}
+	return nil
+}
+
+// SchemaVersion returns the schema version recorded in the database.
+func (s *sqlStorage) SchemaVersion(ctx context.Context) (int, error) {
+	value, err := s.GetSyncState(ctx, "schema_version")
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// Snapshot is a portable JSON export of the database.
+type Snapshot struct {
+	SchemaVersion int                `json:"schema_version"`
+	ExportedAt    time.Time          `json:"exported_at"`
+	Resources     []*ManagedResource `json:"resources"`
+	Agents        []*Agent           `json:"agents"`
+	Conflicts     []*Conflict        `json:"conflicts"`
+	Events        []*ResourceEvent   `json:"events"`
+	Audit         []*AuditEntry      `json:"audit"`
+}
+
+// Export reads the full contents of store, including deleted resources and
+// resource history.
+func Export(ctx context.Context, store Storage) (*Snapshot, error) {
+	version, err := store.SchemaVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	snapshot := &Snapshot{SchemaVersion: version, ExportedAt: time.Now().UTC()}
+	if snapshot.Resources, err = store.ListResources(ctx, ResourceFilter{}); err != nil {
+		return nil, fmt.Errorf("failed to export resources: %w", err)
+	}
+	if snapshot.Agents, err = store.ListAgents(ctx); err != nil {
+		return nil, fmt.Errorf("failed to export agents: %w", err)
+	}
+	if snapshot.Conflicts, err = store.ListConflicts(ctx); err != nil {
+		return nil, fmt.Errorf("failed to export conflicts: %w", err)
+	}
+	if snapshot.Events, err = store.ListResourceEvents(ctx, EventFilter{}); err != nil {
+		return nil, fmt.Errorf("failed to export resource history: %w", err)
+	}
+	if snapshot.Audit, err = store.ListAuditEntries(ctx, AuditFilter{}); err != nil {
+		return nil, fmt.Errorf("failed to export audit trail: %w", err)
+	}
+
+	// Export empty tables as [] rather than null
+	if snapshot.Resources == nil {
+		snapshot.Resources = []*ManagedResource{}
+	}
+	if snapshot.Agents == nil {
+		snapshot.Agents = []*Agent{}
+	}
+	if snapshot.Conflicts == nil {
+		snapshot.Conflicts = []*Conflict{}
+	}
+	if snapshot.Events == nil {
+		snapshot.Events = []*ResourceEvent{}
+	}
+	if snapshot.Audit == nil {
+		snapshot.Audit = []*AuditEntry{}
+	}
+	return snapshot, nil
+}
+
+// sqliteBackuper is the online backup API of the modernc SQLite driver.
+type sqliteBackuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Backup writes a consistent copy of the database to dst using SQLite's
+// online backup API; writers are not blocked while it runs. dst is replaced
+// atomically once the copy is complete.
+func (s *SQLiteStorage) Backup(ctx context.Context, dst string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := s.copyDatabase(ctx, func(b sqliteBackuper) (*sqlite.Backup, error) {
+		return b.NewBackup(tmp.Name())
+	}); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
+
+// Restore replaces the contents of the database with the backup at src.
+// The backup must pass an integrity check and have a schema version this
+// build supports; older schemas are migrated afterwards. Labelgate must not
+// be running against the database while it is restored.
+func (s *SQLiteStorage) Restore(ctx context.Context, src string) error {
+	if err := verifyBackup(ctx, src); err != nil {
+		return err
+	}
+
+	if err := s.copyDatabase(ctx, func(b sqliteBackuper) (*sqlite.Backup, error) {
+		return b.NewRestore(src)
+	}); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	return s.Initialize(ctx)
+}
+
+// CheckNotInUse returns an error if another connection, such as a running
+// labelgate, has the SQLite database at path open. Every open connection to
+// a WAL database holds a shared lock, so an exclusive lock is only granted
+// when there are none. A missing database is not in use.
+func CheckNotInUse(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=locking_mode(EXCLUSIVE)&_pragma=busy_timeout(0)")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN EXCLUSIVE"); err != nil {
+		return fmt.Errorf("database is in use, stop labelgate first: %w", err)
+	}
+	_, err = conn.ExecContext(ctx, "ROLLBACK")
+	return err
+}
+
+// verifyBackup opens the backup at src read-only and checks its schema
+// version and integrity.
+func verifyBackup(ctx context.Context, src string) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+src+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	backup := &SQLiteStorage{sqlStorage: &sqlStorage{db: db, dialect: dialectSQLite, migrations: migrations}, path: src}
+	defer backup.Close()
+
+	version, err := backup.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("not a labelgate database: %w", err)
+	}
+	if err := CheckSchemaVersion(version); err != nil {
+		return err
+	}
+	if err := backup.IntegrityCheck(ctx); err != nil {
+		return fmt.Errorf("backup is corrupt: %w", err)
+	}
+	return nil
+}
+
+// copyDatabase runs a backup or restore on a dedicated connection.
+func (s *SQLiteStorage) copyDatabase(ctx context.Context, start func(b sqliteBackuper) (*sqlite.Backup, error)) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		b, ok := driverConn.(sqliteBackuper)
+		if !ok {
+			return fmt.Errorf("sqlite driver does not support the backup API")
+		}
+		bck, err := start(b)
+		if err != nil {
+			return err
+		}
+		for more := true; more; {
+			if more, err = bck.Step(-1); err != nil {
+				bck.Finish()
+				return err
+			}
+		}
+		return bck.Finish()
+	})
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStorage_BackupRestore(t *testing.T) {
+	source, cleanup := setupTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := source.SaveResource(ctx, &ManagedResource{
+		ResourceType: ResourceTypeDNS,
+		Hostname:     "app.example.com",
+		RecordType:   "A",
+		CFID:         "cf-123",
+		ServiceName:  "web",
+		Status:       StatusActive,
+	}); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := source.Backup(ctx, backup); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	target, cleanupTarget := setupTestStorage(t)
+	defer cleanupTarget()
+	if err := target.Restore(ctx, backup); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	got, err := target.GetResourceByHostname(ctx, "app.example.com", ResourceTypeDNS)
+	if err != nil {
+		t.Fatalf("restored resource not found: %v", err)
+	}
+	if got.CFID != "cf-123" {
+		t.Errorf("expected cf_id cf-123, got %s", got.CFID)
+	}
+	events, err := target.ListResourceEvents(ctx, EventFilter{ResourceID: got.ID})
+	if err != nil || len(events) != 1 {
+		t.Errorf("expected resource history to be restored, got %d events (%v)", len(events), err)
+	}
+}
+
+func TestSQLiteStorage_RestoreRejectsNewerSchema(t *testing.T) {
+	source, cleanup := setupTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Pretend the backup was written by a newer labelgate
+	if err := source.SetSyncState(ctx, "schema_version", "999"); err != nil {
+		t.Fatalf("failed to set schema version: %v", err)
+	}
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := source.Backup(ctx, backup); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+
+	target, cleanupTarget := setupTestStorage(t)
+	defer cleanupTarget()
+	err := target.Restore(ctx, backup)
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Fatalf("expected schema version error, got %v", err)
+	}
+
+	// The target is untouched
+	version, err := target.SchemaVersion(ctx)
+	if err != nil || version != LatestSchemaVersion() {
+		t.Errorf("expected schema version %d, got %d (%v)", LatestSchemaVersion(), version, err)
+	}
+}
+
+func TestCheckNotInUse(t *testing.T) {
+	ctx := context.Background()
+	if err := CheckNotInUse(ctx, filepath.Join(t.TempDir(), "missing.db")); err != nil {
+		t.Errorf("expected a missing database not to be in use, got %v", err)
+	}
+
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+	if err := CheckNotInUse(ctx, store.path); err == nil {
+		t.Error("expected an open database to be in use")
+	}
+	store.Close()
+	if err := CheckNotInUse(ctx, store.path); err != nil {
+		t.Errorf("expected a closed database not to be in use, got %v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	store, cleanup := setupTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := store.SaveResource(ctx, &ManagedResource{
+		ResourceType: ResourceTypeTunnelIngress,
+		Hostname:     "app.example.com",
+		ServiceName:  "web",
+		Status:       StatusDeleted,
+	}); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+	if err := store.SaveAgent(ctx, &Agent{ID: "agent-1", Name: "edge"}); err != nil {
+		t.Fatalf("failed to save agent: %v", err)
+	}
+
+	snapshot, err := Export(ctx, store)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if snapshot.SchemaVersion != LatestSchemaVersion() {
+		t.Errorf("expected schema version %d, got %d", LatestSchemaVersion(), snapshot.SchemaVersion)
+	}
+	if len(snapshot.Resources) != 1 || len(snapshot.Agents) != 1 || len(snapshot.Events) != 1 {
+		t.Errorf("expected 1 resource, agent and event, got %d, %d, %d",
+			len(snapshot.Resources), len(snapshot.Agents), len(snapshot.Events))
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// ResourceAction describes a resource state transition recorded in history.
+type ResourceAction string
+
+const (
+	// ActionCreate means the resource was created.
+	ActionCreate ResourceAction = "create"
+	// ActionUpdate means the resource changed (including reactivation).
+	ActionUpdate ResourceAction = "update"
+	// ActionHandover means the resource was rebound to another container.
+	ActionHandover ResourceAction = "handover"
+	// ActionOrphan means the resource lost its container.
+	ActionOrphan ResourceAction = "orphan"
+	// ActionError means the resource entered the error state.
+	ActionError ResourceAction = "error"
+	// ActionDelete means the resource was deleted.
+	ActionDelete ResourceAction = "delete"
+)
+
+// ResourceEvent is an append-only history entry for a managed resource.
+type ResourceEvent struct {
+	ID int64 `json:"id"`
+
+	// Resource the event belongs to
+	ResourceID   string       `json:"resource_id"`
+	ResourceType ResourceType `json:"resource_type"`
+	Hostname     string       `json:"hostname"`
+
+	// Transition
+	Action ResourceAction   `json:"action"`
+	Before *ManagedResource `json:"before,omitempty"`
+	After  *ManagedResource `json:"after,omitempty"`
+	Error  string           `json:"error,omitempty"`
+
+	// Origin: owning container/agent and the reconcile pass that made the change
+	ContainerID   string `json:"container_id,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	AgentID       string `json:"agent_id,omitempty"`
+	ReconcileID   string `json:"reconcile_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventFilter represents filter options for querying resource history.
+type EventFilter struct {
+	ResourceID   string
+	ResourceType ResourceType
+	Hostname     string
+	Action       ResourceAction
+	ReconcileID  string
+	Since        time.Time
+	Limit        int
+	Offset       int
+}
+
+// EventNotifier is implemented by backends that report resource history
+// entries as they are committed, e.g. to feed a live event stream.
+type EventNotifier interface {
+	OnResourceEvent(fn func(*ResourceEvent))
+}
+
+type reconcileIDKey struct{}
+
+// WithReconcileID returns a context whose storage writes are attributed to
+// the given reconcile pass in resource history.
+func WithReconcileID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, reconcileIDKey{}, id)
+}
+
+// ReconcileIDFromContext returns the reconcile ID set by WithReconcileID.
+func ReconcileIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileIDKey{}).(string)
+	return id
+}
+
+// newResourceEvent builds the history entry for a transition from before to
+// after (either may be nil). It returns nil if nothing worth recording changed.
+func newResourceEvent(ctx context.Context, before, after *ManagedResource) *ResourceEvent {
+	var action ResourceAction
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case after == nil:
+		action = ActionDelete
+	case before == nil:
+		action = ActionCreate
+		if after.Status == StatusError {
+			action = ActionError
+		}
+	case !resourceChanged(before, after):
+		return nil
+	case before.Status != after.Status && after.Status == StatusOrphaned:
+		action = ActionOrphan
+	case before.Status != after.Status && after.Status == StatusDeleted:
+		action = ActionDelete
+	case after.Status == StatusError && (before.Status != StatusError || before.LastError != after.LastError):
+		action = ActionError
+	case before.ContainerID != after.ContainerID:
+		action = ActionHandover
+	default:
+		action = ActionUpdate
+	}
+
+	// Attribute the event to the resource's current owner
+	owner := after
+	if owner == nil {
+		owner = before
+	}
+	event := &ResourceEvent{
+		ResourceID:    owner.ID,
+		ResourceType:  owner.ResourceType,
+		Hostname:      owner.Hostname,
+		Action:        action,
+		Before:        before,
+		After:         after,
+		ContainerID:   owner.ContainerID,
+		ContainerName: owner.ContainerName,
+		AgentID:       owner.AgentID,
+		ReconcileID:   ReconcileIDFromContext(ctx),
+		CreatedAt:     time.Now(),
+	}
+	if action == ActionError {
+		event.Error = after.LastError
+	}
+	return event
+}
+
+// resourceChanged reports whether two resource states differ in anything but
+// bookkeeping timestamps.
+func resourceChanged(before, after *ManagedResource) bool {
+	a, b := *before, *after
+	a.CreatedAt, b.CreatedAt = time.Time{}, time.Time{}
+	a.UpdatedAt, b.UpdatedAt = time.Time{}, time.Time{}
+	a.ID, b.ID = "", ""
+	return !reflect.DeepEqual(a, b)
+}
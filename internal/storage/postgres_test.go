@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postgresTestDSN names the environment variable pointing the conformance
+// tests at a PostgreSQL server; they are skipped when it is unset.
+const postgresTestDSN = "LABELGATE_TEST_POSTGRES_DSN"
+
+func TestPostgresStorage_Conformance(t *testing.T) {
+	dsn := os.Getenv(postgresTestDSN)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresTestDSN)
+	}
+
+	runConformance(t, func(t *testing.T) Storage {
+		return setupPostgresStorage(t, dsn)
+	})
+}
+
+func TestPostgresStorage_Migrations(t *testing.T) {
+	if len(postgresMigrations) != len(migrations) {
+		t.Fatalf("expected %d postgres migrations, got %d", len(migrations), len(postgresMigrations))
+	}
+	for i := range migrations {
+		if postgresMigrations[i].Version != migrations[i].Version {
+			t.Errorf("migration %d: expected version %d, got %d", i, migrations[i].Version, postgresMigrations[i].Version)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT * FROM t WHERE a = ? AND b IN (?, ?)`
+
+	sqlite := &sqlStorage{dialect: dialectSQLite}
+	if got := sqlite.rebind(query); got != query {
+		t.Errorf("sqlite: expected query unchanged, got %q", got)
+	}
+
+	postgres := &sqlStorage{dialect: dialectPostgres}
+	want := `SELECT * FROM t WHERE a = $1 AND b IN ($2, $3)`
+	if got := postgres.rebind(query); got != want {
+		t.Errorf("postgres: expected %q, got %q", want, got)
+	}
+}
+
+// setupPostgresStorage creates an initialized storage in a throwaway schema,
+// so tests don't see each other's rows.
+func setupPostgresStorage(t *testing.T, dsn string) Storage {
+	t.Helper()
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("labelgate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	storage, err := NewPostgresStorage(withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+
+	if err := storage.Initialize(context.Background()); err != nil {
+		t.Fatalf("failed to initialize storage: %v", err)
+	}
+	return storage
+}
+
+// withSearchPath adds a search_path runtime parameter to a URL or keyword/value DSN.
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
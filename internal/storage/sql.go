@@ -0,0 +1,1256 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// dialect identifies the SQL flavour spoken by a backend.
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// sqlStorage implements the backend-independent part of Storage on top of
+// database/sql. SQLiteStorage and PostgresStorage embed it and add their own
+// migrations and maintenance. Queries are written with ? placeholders and
+// rebound to the backend's syntax before execution.
+type sqlStorage struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+
+	// onEvent is called with each history entry once its transaction commits
+	onEvent func(*ResourceEvent)
+}
+
+// Migration represents a database migration.
+type Migration struct {
+	Version int
+	SQL     string
+}
+
+// resourceColumns is the standard column list for resource queries.
+const resourceColumns = `id, resource_type, cf_id, zone_id, hostname, record_type, content, proxied, ttl,
+	tunnel_id, service, path, access_app_id, account_id, access_app_name, access_policy_name, access_decision,
+	container_id, container_name, service_name, agent_id,
+	status, cleanup_enabled, last_error, created_at, updated_at, deleted_at`
+
+// rebind converts ? placeholders to the backend's bind syntax ($1, $2, ... for PostgreSQL).
+func (s *sqlStorage) rebind(query string) string {
+	if s.dialect != dialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
+
+// timestampType returns the column type used for timestamps.
+func (s *sqlStorage) timestampType() string {
+	if s.dialect == dialectPostgres {
+		return "TIMESTAMPTZ"
+	}
+	return "TIMESTAMP"
+}
+
+// migrationLockID is the PostgreSQL advisory lock held while migrating, so
+// instances starting together apply each migration once.
+const migrationLockID = 0x6c6267617465 // "lbgate"
+
+// Initialize creates tables and runs migrations. Each migration commits
+// together with its schema version, so a failed one can simply be retried.
+func (s *sqlStorage) Initialize(ctx context.Context) error {
+	// The advisory lock belongs to a session, so keep one connection
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	if s.dialect == dialectPostgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockID)
+	}
+
+	// Get current schema version, as left by whoever held the lock before
+	currentVersion := s.getSchemaVersion(ctx)
+
+	// Run migrations
+	for _, m := range s.migrations {
+		if m.Version > currentVersion {
+			if err := s.migrate(ctx, conn, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrate applies m and records its version in one transaction.
+func (s *sqlStorage) migrate(ctx context.Context, conn *sql.Conn, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("migration %d failed: %w", m.Version, err)
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(setSyncStateQuery), "schema_version", strconv.Itoa(m.Version), time.Now()); err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %d failed: %w", m.Version, err)
+	}
+	return nil
+}
+
+// Close closes the database connection.
+func (s *sqlStorage) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// GetResource retrieves a resource by ID.
+func (s *sqlStorage) GetResource(ctx context.Context, id string) (*ManagedResource, error) {
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM managed_resources
+		WHERE id = ?
+	`
+
+	row := s.db.QueryRowContext(ctx, s.rebind(query), id)
+	return s.scanResource(row)
+}
+
+// GetResourceByHostname retrieves a resource by hostname and type.
+func (s *sqlStorage) GetResourceByHostname(ctx context.Context, hostname string, resourceType ResourceType) (*ManagedResource, error) {
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM managed_resources
+		WHERE hostname = ? AND resource_type = ? AND status != 'deleted'
+	`
+
+	row := s.db.QueryRowContext(ctx, s.rebind(query), hostname, resourceType)
+	return s.scanResource(row)
+}
+
+// GetResourceByContainerService retrieves a resource by container and service name.
+func (s *sqlStorage) GetResourceByContainerService(ctx context.Context, containerID, serviceName string) (*ManagedResource, error) {
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM managed_resources
+		WHERE container_id = ? AND service_name = ? AND status != 'deleted'
+	`
+
+	row := s.db.QueryRowContext(ctx, s.rebind(query), containerID, serviceName)
+	return s.scanResource(row)
+}
+
+// ListResources lists resources with optional filtering.
+func (s *sqlStorage) ListResources(ctx context.Context, filter ResourceFilter) ([]*ManagedResource, error) {
+	where, args := resourceWhere(filter)
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM managed_resources
+		WHERE ` + where + `
+		ORDER BY created_at DESC`
+
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resources []*ManagedResource
+	for rows.Next() {
+		r, err := s.scanResourceRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+
+	return resources, rows.Err()
+}
+
+// CountResources returns the number of resources matching filter, ignoring Limit and Offset.
+func (s *sqlStorage) CountResources(ctx context.Context, filter ResourceFilter) (int, error) {
+	where, args := resourceWhere(filter)
+	var count int
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM managed_resources WHERE `+where), args...).Scan(&count)
+	return count, err
+}
+
+// CountResourcesByGroup returns resource counts grouped by type, status and
+// agent for resources matching filter, ignoring Limit and Offset.
+func (s *sqlStorage) CountResourcesByGroup(ctx context.Context, filter ResourceFilter) ([]ResourceCount, error) {
+	where, args := resourceWhere(filter)
+	query := `
+		SELECT resource_type, COALESCE(status, ''), COALESCE(agent_id, ''), COUNT(*)
+		FROM managed_resources
+		WHERE ` + where + `
+		GROUP BY resource_type, COALESCE(status, ''), COALESCE(agent_id, '')
+		ORDER BY resource_type, COALESCE(status, ''), COALESCE(agent_id, '')`
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []ResourceCount
+	for rows.Next() {
+		var c ResourceCount
+		if err := rows.Scan(&c.ResourceType, &c.Status, &c.AgentID, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// resourceWhere builds the WHERE clause for a resource filter; Limit and Offset are left to the caller.
+func resourceWhere(filter ResourceFilter) (string, []any) {
+	where := "1=1"
+	var args []any
+
+	if filter.ResourceType != "" {
+		where += " AND resource_type = ?"
+		args = append(args, filter.ResourceType)
+	}
+	if filter.Hostname != "" {
+		where += " AND hostname = ?"
+		args = append(args, filter.Hostname)
+	}
+	if filter.ContainerID != "" {
+		where += " AND container_id = ?"
+		args = append(args, filter.ContainerID)
+	}
+	if filter.ServiceName != "" {
+		where += " AND service_name = ?"
+		args = append(args, filter.ServiceName)
+	}
+	if filter.AgentID != "" {
+		where += " AND agent_id = ?"
+		args = append(args, filter.AgentID)
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, s := range filter.Statuses {
+			placeholders[i] = "?"
+			args = append(args, s)
+		}
+		where += " AND status IN (" + strings.Join(placeholders, ",") + ")"
+	} else if filter.Status != "" {
+		where += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+
+	return where, args
+}
+
+// SaveResource creates or updates a resource.
+// Uses upsert based on (resource_type, hostname, record_type, path) unique constraint.
+func (s *sqlStorage) SaveResource(ctx context.Context, resource *ManagedResource) error {
+	if resource.ID == "" {
+		resource.ID = uuid.New().String()
+	}
+	if resource.CreatedAt.IsZero() {
+		resource.CreatedAt = time.Now()
+	}
+	resource.UpdatedAt = time.Now()
+
+	// Use ON CONFLICT for the unique constraint (resource_type, hostname, record_type, path)
+	// This handles both:
+	// - id conflict (updating existing resource by ID)
+	// - unique constraint conflict (resource already exists with same type/hostname/record_type)
+	query := `
+		INSERT INTO managed_resources (
+			id, resource_type, cf_id, zone_id, hostname, record_type, content, proxied, ttl,
+			tunnel_id, service, path, access_app_id, account_id, access_app_name, access_policy_name, access_decision,
+			container_id, container_name, service_name, agent_id,
+			status, cleanup_enabled, last_error, created_at, updated_at, deleted_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_type, hostname, record_type, path) DO UPDATE SET
+			cf_id = excluded.cf_id,
+			zone_id = excluded.zone_id,
+			content = excluded.content,
+			proxied = excluded.proxied,
+			ttl = excluded.ttl,
+			tunnel_id = excluded.tunnel_id,
+			service = excluded.service,
+			path = excluded.path,
+			access_app_id = excluded.access_app_id,
+			account_id = excluded.account_id,
+			access_app_name = excluded.access_app_name,
+			access_policy_name = excluded.access_policy_name,
+			access_decision = excluded.access_decision,
+			container_id = excluded.container_id,
+			container_name = excluded.container_name,
+			service_name = excluded.service_name,
+			agent_id = excluded.agent_id,
+			status = excluded.status,
+			cleanup_enabled = excluded.cleanup_enabled,
+			last_error = excluded.last_error,
+			updated_at = excluded.updated_at,
+			deleted_at = excluded.deleted_at
+	`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Load the row this upsert will replace, for history
+	before, err := s.getResourceTx(ctx, tx,
+		`id = ? OR (resource_type = ? AND hostname = ? AND record_type = ? AND path = ?) ORDER BY id = ? DESC`,
+		resource.ID, resource.ResourceType, resource.Hostname, resource.RecordType, resource.Path, resource.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, s.rebind(query),
+		resource.ID, resource.ResourceType, resource.CFID, resource.ZoneID,
+		resource.Hostname, resource.RecordType, resource.Content, resource.Proxied, resource.TTL,
+		resource.TunnelID, resource.Service, resource.Path,
+		resource.AccessAppID, resource.AccountID, resource.AccessAppName, resource.AccessPolicyName, resource.AccessDecision,
+		resource.ContainerID, resource.ContainerName, resource.ServiceName, resource.AgentID,
+		resource.Status, resource.CleanupEnabled, resource.LastError, resource.CreatedAt, resource.UpdatedAt, resource.DeletedAt,
+	); err != nil {
+		return err
+	}
+
+	// An upsert on the unique key keeps the existing row's ID and creation time
+	if before != nil {
+		resource.ID = before.ID
+		resource.CreatedAt = before.CreatedAt
+	}
+	after := *resource
+	event, err := s.recordEvent(ctx, tx, before, &after)
+	if err != nil {
+		return err
+	}
+
+	return s.commit(tx, event)
+}
+
+// UpdateResourceStatus updates the status of a resource.
+func (s *sqlStorage) UpdateResourceStatus(ctx context.Context, id string, status ResourceStatus) error {
+	now := time.Now()
+	var deletedAt *time.Time
+	if status == StatusDeleted {
+		deletedAt = &now
+	}
+
+	return s.updateResource(ctx, id, func(tx *sql.Tx, r *ManagedResource) error {
+		r.Status = status
+		r.UpdatedAt = now
+		if deletedAt != nil {
+			r.DeletedAt = deletedAt
+			_, err := tx.ExecContext(ctx, s.rebind(`UPDATE managed_resources SET status = ?, updated_at = ?, deleted_at = ? WHERE id = ?`), status, now, deletedAt, id)
+			return err
+		}
+		_, err := tx.ExecContext(ctx, s.rebind(`UPDATE managed_resources SET status = ?, updated_at = ? WHERE id = ?`), status, now, id)
+		return err
+	})
+}
+
+// UpdateResourceError updates the status and last_error of a resource.
+// Pass an empty lastError string to clear the error.
+func (s *sqlStorage) UpdateResourceError(ctx context.Context, id string, status ResourceStatus, lastError string) error {
+	now := time.Now()
+	return s.updateResource(ctx, id, func(tx *sql.Tx, r *ManagedResource) error {
+		r.Status = status
+		r.LastError = lastError
+		r.UpdatedAt = now
+		_, err := tx.ExecContext(ctx, s.rebind(`UPDATE managed_resources SET status = ?, last_error = ?, updated_at = ? WHERE id = ?`), status, lastError, now, id)
+		return err
+	})
+}
+
+// RebindResource moves a resource to a new owner in a single statement.
+// The update only applies while the resource is still owned by fromContainerID;
+// otherwise ErrNotFound is returned.
+func (s *sqlStorage) RebindResource(ctx context.Context, id, fromContainerID string, owner ResourceOwner) error {
+	now := time.Now()
+	return s.updateResource(ctx, id, func(tx *sql.Tx, r *ManagedResource) error {
+		query := `
+			UPDATE managed_resources
+			SET container_id = ?, container_name = ?, service_name = ?, agent_id = ?, updated_at = ?
+			WHERE id = ? AND COALESCE(container_id, '') = ?
+		`
+		result, err := tx.ExecContext(ctx, s.rebind(query),
+			owner.ContainerID, owner.ContainerName, owner.ServiceName, owner.AgentID, now,
+			id, fromContainerID,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+
+		r.ContainerID = owner.ContainerID
+		r.ContainerName = owner.ContainerName
+		r.ServiceName = owner.ServiceName
+		r.AgentID = owner.AgentID
+		r.UpdatedAt = now
+		return nil
+	})
+}
+
+// DeleteResource deletes a resource by ID.
+func (s *sqlStorage) DeleteResource(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := s.getResourceTx(ctx, tx, `id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM managed_resources WHERE id = ?`), id); err != nil {
+		return err
+	}
+	event, err := s.recordEvent(ctx, tx, before, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.commit(tx, event)
+}
+
+// updateResource runs update against resource id in a transaction and records
+// the resulting transition in history. update applies its change both to the
+// database and to the in-memory copy it receives. A missing resource is a no-op.
+func (s *sqlStorage) updateResource(ctx context.Context, id string, update func(tx *sql.Tx, r *ManagedResource) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := s.getResourceTx(ctx, tx, `id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	var after *ManagedResource
+	if before != nil {
+		r := *before
+		after = &r
+	} else {
+		after = &ManagedResource{}
+	}
+	if err := update(tx, after); err != nil {
+		return err
+	}
+	var event *ResourceEvent
+	if before != nil {
+		if event, err = s.recordEvent(ctx, tx, before, after); err != nil {
+			return err
+		}
+	}
+
+	return s.commit(tx, event)
+}
+
+// getResourceTx loads the first resource matching where within tx, or nil if none.
+func (s *sqlStorage) getResourceTx(ctx context.Context, tx *sql.Tx, where string, args ...any) (*ManagedResource, error) {
+	row := tx.QueryRowContext(ctx, s.rebind(`SELECT `+resourceColumns+` FROM managed_resources WHERE `+where+` LIMIT 1`), args...)
+	r, err := s.scanResource(row)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// recordEvent appends the history entry for a resource transition, if any,
+// and returns it.
+func (s *sqlStorage) recordEvent(ctx context.Context, tx *sql.Tx, before, after *ManagedResource) (*ResourceEvent, error) {
+	event := newResourceEvent(ctx, before, after)
+	if event == nil {
+		return nil, nil
+	}
+
+	beforeJSON, err := marshalResource(event.Before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := marshalResource(event.After)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO resource_events (
+			resource_id, resource_type, hostname, action, before_state, after_state, error,
+			container_id, container_name, agent_id, reconcile_id, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, s.rebind(query),
+		event.ResourceID, event.ResourceType, event.Hostname, event.Action, beforeJSON, afterJSON, event.Error,
+		event.ContainerID, event.ContainerName, event.AgentID, event.ReconcileID, event.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// commit commits tx and then reports event (if any) to the OnResourceEvent callback.
+func (s *sqlStorage) commit(tx *sql.Tx, event *ResourceEvent) error {
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if event != nil && s.onEvent != nil {
+		s.onEvent(event)
+	}
+	return nil
+}
+
+// OnResourceEvent registers fn to be called with each resource history entry
+// after it is committed. It must be set before the storage is used.
+func (s *sqlStorage) OnResourceEvent(fn func(*ResourceEvent)) {
+	s.onEvent = fn
+}
+
+// ListResourceEvents lists resource history, newest first.
+func (s *sqlStorage) ListResourceEvents(ctx context.Context, filter EventFilter) ([]*ResourceEvent, error) {
+	where, args := eventWhere(filter)
+	query := `
+		SELECT id, resource_id, resource_type, hostname, action, before_state, after_state, error,
+			container_id, container_name, agent_id, reconcile_id, created_at
+		FROM resource_events
+		WHERE ` + where + `
+		ORDER BY id DESC`
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+		if filter.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*ResourceEvent
+	for rows.Next() {
+		e := &ResourceEvent{}
+		var beforeJSON, afterJSON, eventErr, containerID, containerName, agentID, reconcileID sql.NullString
+		if err := rows.Scan(
+			&e.ID, &e.ResourceID, &e.ResourceType, &e.Hostname, &e.Action, &beforeJSON, &afterJSON, &eventErr,
+			&containerID, &containerName, &agentID, &reconcileID, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if e.Before, err = unmarshalResource(beforeJSON); err != nil {
+			return nil, err
+		}
+		if e.After, err = unmarshalResource(afterJSON); err != nil {
+			return nil, err
+		}
+		e.Error = eventErr.String
+		e.ContainerID = containerID.String
+		e.ContainerName = containerName.String
+		e.AgentID = agentID.String
+		e.ReconcileID = reconcileID.String
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// CountResourceEvents returns the number of history entries matching filter, ignoring Limit and Offset.
+func (s *sqlStorage) CountResourceEvents(ctx context.Context, filter EventFilter) (int, error) {
+	where, args := eventWhere(filter)
+	var count int
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM resource_events WHERE `+where), args...).Scan(&count)
+	return count, err
+}
+
+// eventWhere builds the WHERE clause for an event filter; Limit and Offset are left to the caller.
+func eventWhere(filter EventFilter) (string, []any) {
+	where := "1=1"
+	var args []any
+
+	if filter.ResourceID != "" {
+		where += " AND resource_id = ?"
+		args = append(args, filter.ResourceID)
+	}
+	if filter.ResourceType != "" {
+		where += " AND resource_type = ?"
+		args = append(args, filter.ResourceType)
+	}
+	if filter.Hostname != "" {
+		where += " AND hostname = ?"
+		args = append(args, filter.Hostname)
+	}
+	if filter.Action != "" {
+		where += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.ReconcileID != "" {
+		where += " AND reconcile_id = ?"
+		args = append(args, filter.ReconcileID)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, filter.Since)
+	}
+
+	return where, args
+}
+
+// CleanupResourceEvents removes history entries older than the given time.
+func (s *sqlStorage) CleanupResourceEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM resource_events WHERE created_at < ?`), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RecordAudit appends an entry to the audit trail.
+func (s *sqlStorage) RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO audit_log (action, target, actor, status, error, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.ExecContext(ctx, s.rebind(query),
+		entry.Action, entry.Target, entry.Actor, entry.Status, entry.Error, entry.Detail, entry.CreatedAt,
+	)
+	return err
+}
+
+// ListAuditEntries lists the audit trail, newest first.
+func (s *sqlStorage) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	where, args := auditWhere(filter)
+	query := `
+		SELECT id, action, target, actor, status, error, detail, created_at
+		FROM audit_log
+		WHERE ` + where + `
+		ORDER BY id DESC`
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+		if filter.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		e := &AuditEntry{}
+		var target, actor, entryErr, detail sql.NullString
+		if err := rows.Scan(&e.ID, &e.Action, &target, &actor, &e.Status, &entryErr, &detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Target = target.String
+		e.Actor = actor.String
+		e.Error = entryErr.String
+		e.Detail = detail.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// CountAuditEntries returns the number of audit entries matching filter, ignoring Limit and Offset.
+func (s *sqlStorage) CountAuditEntries(ctx context.Context, filter AuditFilter) (int, error) {
+	where, args := auditWhere(filter)
+	var count int
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM audit_log WHERE `+where), args...).Scan(&count)
+	return count, err
+}
+
+// auditWhere builds the WHERE clause for an audit filter; Limit and Offset are left to the caller.
+func auditWhere(filter AuditFilter) (string, []any) {
+	where := "1=1"
+	var args []any
+
+	if filter.Action != "" {
+		where += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		where += " AND target = ?"
+		args = append(args, filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, filter.Since)
+	}
+
+	return where, args
+}
+
+// CreateAPIToken stores a new API token. Returns ErrConflict if a token with
+// the same name exists.
+func (s *sqlStorage) CreateAPIToken(ctx context.Context, token *APIToken) error {
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM api_tokens WHERE name = ?`), token.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	query := `
+		INSERT INTO api_tokens (id, name, token_hash, scopes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err = s.db.ExecContext(ctx, s.rebind(query),
+		token.ID, token.Name, token.Hash, strings.Join(token.Scopes, ","), token.CreatedAt, token.ExpiresAt,
+	)
+	return err
+}
+
+// ListAPITokens lists all API tokens, including expired ones, by name.
+func (s *sqlStorage) ListAPITokens(ctx context.Context) ([]*APIToken, error) {
+	query := `
+		SELECT id, name, token_hash, scopes, created_at, expires_at, last_used_at
+		FROM api_tokens
+		ORDER BY name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		t := &APIToken{}
+		var scopes string
+		var expiresAt, lastUsedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Name, &t.Hash, &scopes, &t.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		if scopes != "" {
+			t.Scopes = strings.Split(scopes, ",")
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = &lastUsedAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes the token with the given name. Returns ErrNotFound
+// if there is none.
+func (s *sqlStorage) DeleteAPIToken(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM api_tokens WHERE name = ?`), name)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIToken records when the token with the given name was last used.
+func (s *sqlStorage) TouchAPIToken(ctx context.Context, name string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(`UPDATE api_tokens SET last_used_at = ? WHERE name = ?`), at, name)
+	return err
+}
+
+// GetAgent retrieves an agent by ID.
+func (s *sqlStorage) GetAgent(ctx context.Context, id string) (*Agent, error) {
+	query := `
+		SELECT id, name, connected, last_seen, public_ip, default_tunnel, status, created_at, updated_at
+		FROM agents
+		WHERE id = ?
+	`
+
+	row := s.db.QueryRowContext(ctx, s.rebind(query), id)
+	return s.scanAgent(row)
+}
+
+// ListAgents lists all agents.
+func (s *sqlStorage) ListAgents(ctx context.Context) ([]*Agent, error) {
+	query := `
+		SELECT id, name, connected, last_seen, public_ip, default_tunnel, status, created_at, updated_at
+		FROM agents
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []*Agent
+	for rows.Next() {
+		a, err := s.scanAgentRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+
+	return agents, rows.Err()
+}
+
+// SaveAgent creates or updates an agent.
+func (s *sqlStorage) SaveAgent(ctx context.Context, agent *Agent) error {
+	if agent.CreatedAt.IsZero() {
+		agent.CreatedAt = time.Now()
+	}
+	agent.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO agents (id, name, connected, last_seen, public_ip, default_tunnel, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			connected = excluded.connected,
+			last_seen = excluded.last_seen,
+			public_ip = excluded.public_ip,
+			default_tunnel = excluded.default_tunnel,
+			status = excluded.status,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := s.db.ExecContext(ctx, s.rebind(query),
+		agent.ID, agent.Name, agent.Connected, agent.LastSeen, agent.PublicIP,
+		agent.DefaultTunnel, agent.Status, agent.CreatedAt, agent.UpdatedAt,
+	)
+	return err
+}
+
+// UpdateAgentStatus updates agent connection status.
+func (s *sqlStorage) UpdateAgentStatus(ctx context.Context, id string, connected bool, status AgentStatus) error {
+	query := `UPDATE agents SET connected = ?, status = ?, last_seen = ?, updated_at = ? WHERE id = ?`
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, s.rebind(query), connected, status, now, now, id)
+	return err
+}
+
+// DeleteAgent deletes an agent by ID.
+func (s *sqlStorage) DeleteAgent(ctx context.Context, id string) error {
+	query := `DELETE FROM agents WHERE id = ?`
+	_, err := s.db.ExecContext(ctx, s.rebind(query), id)
+	return err
+}
+
+// GetSyncState retrieves a sync state value.
+func (s *sqlStorage) GetSyncState(ctx context.Context, key string) (string, error) {
+	query := `SELECT value FROM sync_state WHERE key = ?`
+	var value string
+	err := s.db.QueryRowContext(ctx, s.rebind(query), key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// setSyncStateQuery upserts a sync state value.
+const setSyncStateQuery = `
+	INSERT INTO sync_state (key, value, updated_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT(key) DO UPDATE SET
+		value = excluded.value,
+		updated_at = excluded.updated_at
+`
+
+// SetSyncState sets a sync state value.
+func (s *sqlStorage) SetSyncState(ctx context.Context, key, value string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(setSyncStateQuery), key, value, time.Now())
+	return err
+}
+
+// ReplaceConflicts replaces the stored conflict set in a single transaction.
+// Conflicts are identified by (resource_type, hostname, container_id, service_name);
+// existing ones keep their first_seen timestamp, resolved ones are removed.
+func (s *sqlStorage) ReplaceConflicts(ctx context.Context, conflicts []*Conflict) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		INSERT INTO hostname_conflicts (
+			id, hostname, resource_type, container_id, container_name, service_name, agent_id,
+			winner_container_id, winner_container_name, winner_service_name, reason, first_seen, last_seen
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_type, hostname, container_id, service_name) DO UPDATE SET
+			container_name = excluded.container_name,
+			agent_id = excluded.agent_id,
+			winner_container_id = excluded.winner_container_id,
+			winner_container_name = excluded.winner_container_name,
+			winner_service_name = excluded.winner_service_name,
+			reason = excluded.reason,
+			last_seen = excluded.last_seen
+	`
+	for _, c := range conflicts {
+		if c.ID == "" {
+			c.ID = uuid.New().String()
+		}
+		if _, err := tx.ExecContext(ctx, s.rebind(query),
+			c.ID, c.Hostname, c.ResourceType, c.ContainerID, c.ContainerName, c.ServiceName, c.AgentID,
+			c.WinnerContainerID, c.WinnerContainerName, c.WinnerServiceName, c.Reason, now, now,
+		); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM hostname_conflicts WHERE last_seen < ?`), now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListConflicts lists all current hostname conflicts.
+func (s *sqlStorage) ListConflicts(ctx context.Context) ([]*Conflict, error) {
+	query := `
+		SELECT id, hostname, resource_type, container_id, container_name, service_name, agent_id,
+			winner_container_id, winner_container_name, winner_service_name, reason, first_seen, last_seen
+		FROM hostname_conflicts
+		ORDER BY hostname, resource_type, container_name
+	`
+	rows, err := s.db.QueryContext(ctx, s.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []*Conflict
+	for rows.Next() {
+		c := &Conflict{}
+		var containerName, agentID, winnerContainerID, winnerContainerName, winnerServiceName sql.NullString
+		if err := rows.Scan(
+			&c.ID, &c.Hostname, &c.ResourceType, &c.ContainerID, &containerName, &c.ServiceName, &agentID,
+			&winnerContainerID, &winnerContainerName, &winnerServiceName, &c.Reason, &c.FirstSeen, &c.LastSeen,
+		); err != nil {
+			return nil, err
+		}
+		c.ContainerName = containerName.String
+		c.AgentID = agentID.String
+		c.WinnerContainerID = winnerContainerID.String
+		c.WinnerContainerName = winnerContainerName.String
+		c.WinnerServiceName = winnerServiceName.String
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, rows.Err()
+}
+
+// CleanupDeletedResources removes deleted resources older than the given time.
+func (s *sqlStorage) CleanupDeletedResources(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM managed_resources WHERE status = 'deleted' AND deleted_at < ?`
+	result, err := s.db.ExecContext(ctx, s.rebind(query), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListExpiredOrphans returns orphaned resources with cleanup_enabled=false
+// whose updated_at is older than the given time (for orphan_ttl DB-only cleanup).
+func (s *sqlStorage) ListExpiredOrphans(ctx context.Context, olderThan time.Time) ([]*ManagedResource, error) {
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM managed_resources
+		WHERE status = 'orphaned' AND cleanup_enabled = FALSE AND updated_at < ?
+		ORDER BY updated_at ASC
+	`
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resources []*ManagedResource
+	for rows.Next() {
+		r, err := s.scanResourceRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+	return resources, rows.Err()
+}
+
+// ListOrphanedForCleanup returns orphaned resources with cleanup_enabled=true
+// whose updated_at is older than the given time (for remove_delay CF cleanup).
+func (s *sqlStorage) ListOrphanedForCleanup(ctx context.Context, olderThan time.Time) ([]*ManagedResource, error) {
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM managed_resources
+		WHERE status = 'orphaned' AND cleanup_enabled = TRUE AND updated_at < ?
+		ORDER BY updated_at ASC
+	`
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resources []*ManagedResource
+	for rows.Next() {
+		r, err := s.scanResourceRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+	return resources, rows.Err()
+}
+
+// Helper methods
+
+func (s *sqlStorage) getSchemaVersion(ctx context.Context) int {
+	// Create sync_state table if it doesn't exist (for initial setup)
+	s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS sync_state (
+		key TEXT PRIMARY KEY,
+		value TEXT,
+		updated_at `+s.timestampType()+` DEFAULT CURRENT_TIMESTAMP
+	)`)
+
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+func (s *sqlStorage) scanResource(row *sql.Row) (*ManagedResource, error) {
+	r := &ManagedResource{}
+	var cfID, zoneID, recordType, content, tunnelID, service, path sql.NullString
+	var accessAppID, accountID, accessAppName, accessPolicyName, accessDecision sql.NullString
+	var containerID, containerName, agentID, lastError sql.NullString
+	var proxied sql.NullBool
+	var ttl sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&r.ID, &r.ResourceType, &cfID, &zoneID, &r.Hostname, &recordType, &content, &proxied, &ttl,
+		&tunnelID, &service, &path, &accessAppID, &accountID, &accessAppName, &accessPolicyName, &accessDecision,
+		&containerID, &containerName, &r.ServiceName, &agentID,
+		&r.Status, &r.CleanupEnabled, &lastError, &r.CreatedAt, &r.UpdatedAt, &deletedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.CFID = cfID.String
+	r.ZoneID = zoneID.String
+	r.RecordType = recordType.String
+	r.Content = content.String
+	r.Proxied = proxied.Bool
+	r.TTL = int(ttl.Int64)
+	r.TunnelID = tunnelID.String
+	r.Service = service.String
+	r.Path = path.String
+	r.AccessAppID = accessAppID.String
+	r.AccountID = accountID.String
+	r.AccessAppName = accessAppName.String
+	r.AccessPolicyName = accessPolicyName.String
+	r.AccessDecision = accessDecision.String
+	r.ContainerID = containerID.String
+	r.ContainerName = containerName.String
+	r.AgentID = agentID.String
+	r.LastError = lastError.String
+	if deletedAt.Valid {
+		r.DeletedAt = &deletedAt.Time
+	}
+
+	return r, nil
+}
+
+func (s *sqlStorage) scanResourceRows(rows *sql.Rows) (*ManagedResource, error) {
+	r := &ManagedResource{}
+	var cfID, zoneID, recordType, content, tunnelID, service, path sql.NullString
+	var accessAppID, accountID, accessAppName, accessPolicyName, accessDecision sql.NullString
+	var containerID, containerName, agentID, lastError sql.NullString
+	var proxied sql.NullBool
+	var ttl sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := rows.Scan(
+		&r.ID, &r.ResourceType, &cfID, &zoneID, &r.Hostname, &recordType, &content, &proxied, &ttl,
+		&tunnelID, &service, &path, &accessAppID, &accountID, &accessAppName, &accessPolicyName, &accessDecision,
+		&containerID, &containerName, &r.ServiceName, &agentID,
+		&r.Status, &r.CleanupEnabled, &lastError, &r.CreatedAt, &r.UpdatedAt, &deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.CFID = cfID.String
+	r.ZoneID = zoneID.String
+	r.RecordType = recordType.String
+	r.Content = content.String
+	r.Proxied = proxied.Bool
+	r.TTL = int(ttl.Int64)
+	r.TunnelID = tunnelID.String
+	r.Service = service.String
+	r.Path = path.String
+	r.AccessAppID = accessAppID.String
+	r.AccountID = accountID.String
+	r.AccessAppName = accessAppName.String
+	r.AccessPolicyName = accessPolicyName.String
+	r.AccessDecision = accessDecision.String
+	r.ContainerID = containerID.String
+	r.ContainerName = containerName.String
+	r.AgentID = agentID.String
+	r.LastError = lastError.String
+	if deletedAt.Valid {
+		r.DeletedAt = &deletedAt.Time
+	}
+
+	return r, nil
+}
+
+func (s *sqlStorage) scanAgent(row *sql.Row) (*Agent, error) {
+	a := &Agent{}
+	var name, publicIP, defaultTunnel sql.NullString
+	var lastSeen sql.NullTime
+
+	err := row.Scan(
+		&a.ID, &name, &a.Connected, &lastSeen, &publicIP, &defaultTunnel, &a.Status, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.Name = name.String
+	a.PublicIP = publicIP.String
+	a.DefaultTunnel = defaultTunnel.String
+	if lastSeen.Valid {
+		a.LastSeen = &lastSeen.Time
+	}
+
+	return a, nil
+}
+
+func (s *sqlStorage) scanAgentRows(rows *sql.Rows) (*Agent, error) {
+	a := &Agent{}
+	var name, publicIP, defaultTunnel sql.NullString
+	var lastSeen sql.NullTime
+
+	err := rows.Scan(
+		&a.ID, &name, &a.Connected, &lastSeen, &publicIP, &defaultTunnel, &a.Status, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Name = name.String
+	a.PublicIP = publicIP.String
+	a.DefaultTunnel = defaultTunnel.String
+	if lastSeen.Valid {
+		a.LastSeen = &lastSeen.Time
+	}
+
+	return a, nil
+}
+
+// marshalResource encodes a resource snapshot for the history table.
+func marshalResource(r *ManagedResource) (sql.NullString, error) {
+	if r == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalResource decodes a resource snapshot from the history table.
+func unmarshalResource(data sql.NullString) (*ManagedResource, error) {
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	r := &ManagedResource{}
+	if err := json.Unmarshal([]byte(data.String), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
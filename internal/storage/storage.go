@@ -3,6 +3,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -112,6 +113,41 @@ type Agent struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ResourceOwner identifies the container service that owns a resource.
+type ResourceOwner struct {
+	ContainerID   string
+	ContainerName string
+	ServiceName   string
+	AgentID       string
+}
+
+// Conflict records a service that lost a hostname conflict and is not applied.
+type Conflict struct {
+	ID string `json:"id"`
+
+	// Contested resource
+	Hostname     string       `json:"hostname"`
+	ResourceType ResourceType `json:"resource_type"`
+
+	// Losing service
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	ServiceName   string `json:"service_name"`
+	AgentID       string `json:"agent_id,omitempty"`
+
+	// Winning service
+	WinnerContainerID   string `json:"winner_container_id"`
+	WinnerContainerName string `json:"winner_container_name"`
+	WinnerServiceName   string `json:"winner_service_name"`
+
+	// Reason explains why the service lost
+	Reason string `json:"reason"`
+
+	// Timestamps
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
 // ResourceFilter represents filter options for querying resources.
 type ResourceFilter struct {
 	ResourceType ResourceType
@@ -125,6 +161,15 @@ type ResourceFilter struct {
 	Offset       int
 }
 
+// ResourceCount is the number of resources sharing a type, status and agent.
+// AgentID is empty for resources of local containers.
+type ResourceCount struct {
+	ResourceType ResourceType   `json:"resource_type"`
+	Status       ResourceStatus `json:"status"`
+	AgentID      string         `json:"agent_id"`
+	Count        int            `json:"count"`
+}
+
 // Storage defines the interface for persistent storage.
 type Storage interface {
 	// Initialize initializes the storage (create tables, run migrations).
@@ -133,14 +178,23 @@ type Storage interface {
 	// Close closes the storage connection.
 	Close() error
 
+	// SchemaVersion returns the schema version recorded in the database.
+	SchemaVersion(ctx context.Context) (int, error)
+
 	// Resource operations
 	GetResource(ctx context.Context, id string) (*ManagedResource, error)
 	GetResourceByHostname(ctx context.Context, hostname string, resourceType ResourceType) (*ManagedResource, error)
 	GetResourceByContainerService(ctx context.Context, containerID, serviceName string) (*ManagedResource, error)
 	ListResources(ctx context.Context, filter ResourceFilter) ([]*ManagedResource, error)
+	// CountResources and CountResourcesByGroup ignore filter.Limit and filter.Offset.
+	CountResources(ctx context.Context, filter ResourceFilter) (int, error)
+	CountResourcesByGroup(ctx context.Context, filter ResourceFilter) ([]ResourceCount, error)
 	SaveResource(ctx context.Context, resource *ManagedResource) error
 	UpdateResourceStatus(ctx context.Context, id string, status ResourceStatus) error
 	UpdateResourceError(ctx context.Context, id string, status ResourceStatus, lastError string) error
+	// RebindResource atomically hands a resource over from fromContainerID to
+	// a new owner. Returns ErrNotFound if it is no longer owned by fromContainerID.
+	RebindResource(ctx context.Context, id, fromContainerID string, owner ResourceOwner) error
 	DeleteResource(ctx context.Context, id string) error
 
 	// Agent operations
@@ -150,6 +204,30 @@ type Storage interface {
 	UpdateAgentStatus(ctx context.Context, id string, connected bool, status AgentStatus) error
 	DeleteAgent(ctx context.Context, id string) error
 
+	// Conflict operations
+	// ReplaceConflicts replaces the current conflict set; first_seen is kept
+	// for conflicts that are still present.
+	ReplaceConflicts(ctx context.Context, conflicts []*Conflict) error
+	ListConflicts(ctx context.Context) ([]*Conflict, error)
+
+	// History operations
+	// Resource writes above append history entries themselves; the reconcile
+	// pass is taken from the context (see WithReconcileID).
+	ListResourceEvents(ctx context.Context, filter EventFilter) ([]*ResourceEvent, error)
+	CountResourceEvents(ctx context.Context, filter EventFilter) (int, error)
+	CleanupResourceEvents(ctx context.Context, before time.Time) (int64, error)
+
+	// Audit operations
+	RecordAudit(ctx context.Context, entry *AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error)
+	CountAuditEntries(ctx context.Context, filter AuditFilter) (int, error)
+
+	// API token operations
+	CreateAPIToken(ctx context.Context, token *APIToken) error
+	ListAPITokens(ctx context.Context) ([]*APIToken, error)
+	DeleteAPIToken(ctx context.Context, name string) error
+	TouchAPIToken(ctx context.Context, name string, at time.Time) error
+
 	// Sync state operations
 	GetSyncState(ctx context.Context, key string) (string, error)
 	SetSyncState(ctx context.Context, key, value string) error
@@ -159,6 +237,8 @@ type Storage interface {
 	ListExpiredOrphans(ctx context.Context, olderThan time.Time) ([]*ManagedResource, error)
 	ListOrphanedForCleanup(ctx context.Context, olderThan time.Time) ([]*ManagedResource, error)
 	Vacuum(ctx context.Context) error
+	Checkpoint(ctx context.Context) error
+	IntegrityCheck(ctx context.Context) error
 }
 
 // Common errors
@@ -198,3 +278,22 @@ func IsConflict(err error) bool {
 	}
 	return false
 }
+
+// Storage drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Open opens the storage backend for driver: a SQLite file at path, or the
+// PostgreSQL database at dsn.
+func Open(driver, path, dsn string) (Storage, error) {
+	switch driver {
+	case DriverSQLite, "":
+		return NewSQLiteStorage(path)
+	case DriverPostgres:
+		return NewPostgresStorage(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", driver)
+	}
+}
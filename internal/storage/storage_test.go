@@ -0,0 +1,761 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// conformanceTests run against every Storage backend, so they only use the
+// Storage interface.
+var conformanceTests = []struct {
+	name string
+	run  func(t *testing.T, storage Storage)
+}{
+	{"Resource_CRUD", testResourceCRUD},
+	{"Agent_CRUD", testAgentCRUD},
+	{"SyncState", testSyncState},
+	{"CleanupDeletedResources", testCleanupDeletedResources},
+	{"HostnameUniqueness", testHostnameUniqueness},
+	{"TunnelPaths", testTunnelPaths},
+	{"RebindResource", testRebindResource},
+	{"ResourceHistory", testResourceHistory},
+	{"Conflicts", testConflicts},
+	{"Counts", testCounts},
+	{"Audit", testAudit},
+	{"APITokens", testAPITokens},
+	{"EventNotifier", testEventNotifier},
+	{"ConcurrentWrites", testConcurrentWrites},
+}
+
+// runConformance runs the conformance tests with a fresh, initialized
+// storage from newStorage for each test.
+func runConformance(t *testing.T, newStorage func(t *testing.T) Storage) {
+	for _, tc := range conformanceTests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newStorage(t))
+		})
+	}
+}
+
+func testResourceCRUD(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	// Create resource
+	resource := &ManagedResource{
+		ResourceType:   ResourceTypeDNS,
+		Hostname:       "test.example.com",
+		RecordType:     "A",
+		Content:        "1.2.3.4",
+		Proxied:        true,
+		TTL:            300,
+		ContainerID:    "container123",
+		ContainerName:  "test-container",
+		ServiceName:    "web",
+		Status:         StatusActive,
+		CleanupEnabled: false,
+	}
+
+	// Save
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+
+	if resource.ID == "" {
+		t.Error("resource ID should be set after save")
+	}
+
+	// Get by ID
+	got, err := storage.GetResource(ctx, resource.ID)
+	if err != nil {
+		t.Fatalf("failed to get resource: %v", err)
+	}
+
+	if got.Hostname != resource.Hostname {
+		t.Errorf("hostname mismatch: got %s, want %s", got.Hostname, resource.Hostname)
+	}
+
+	// Get by hostname
+	got, err = storage.GetResourceByHostname(ctx, "test.example.com", ResourceTypeDNS)
+	if err != nil {
+		t.Fatalf("failed to get resource by hostname: %v", err)
+	}
+	if got.ID != resource.ID {
+		t.Error("should return same resource")
+	}
+
+	// Update status
+	if err := storage.UpdateResourceStatus(ctx, resource.ID, StatusOrphaned); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+
+	got, _ = storage.GetResource(ctx, resource.ID)
+	if got.Status != StatusOrphaned {
+		t.Errorf("status should be orphaned, got %s", got.Status)
+	}
+
+	// List with filter
+	resources, err := storage.ListResources(ctx, ResourceFilter{Status: StatusOrphaned})
+	if err != nil {
+		t.Fatalf("failed to list resources: %v", err)
+	}
+	if len(resources) != 1 {
+		t.Errorf("should return 1 resource, got %d", len(resources))
+	}
+
+	// Delete
+	if err := storage.DeleteResource(ctx, resource.ID); err != nil {
+		t.Fatalf("failed to delete resource: %v", err)
+	}
+
+	_, err = storage.GetResource(ctx, resource.ID)
+	if !IsNotFound(err) {
+		t.Error("should return not found after delete")
+	}
+}
+
+func testAgentCRUD(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	// Create agent
+	agent := &Agent{
+		ID:            "agent-1",
+		Name:          "Test Agent",
+		Connected:     true,
+		DefaultTunnel: "default",
+		Status:        AgentStatusActive,
+	}
+
+	// Save
+	if err := storage.SaveAgent(ctx, agent); err != nil {
+		t.Fatalf("failed to save agent: %v", err)
+	}
+
+	// Get
+	got, err := storage.GetAgent(ctx, "agent-1")
+	if err != nil {
+		t.Fatalf("failed to get agent: %v", err)
+	}
+	if got.Name != agent.Name {
+		t.Errorf("name mismatch: got %s, want %s", got.Name, agent.Name)
+	}
+
+	// Update status
+	if err := storage.UpdateAgentStatus(ctx, "agent-1", false, AgentStatusDisconnected); err != nil {
+		t.Fatalf("failed to update agent status: %v", err)
+	}
+
+	got, _ = storage.GetAgent(ctx, "agent-1")
+	if got.Connected != false || got.Status != AgentStatusDisconnected {
+		t.Error("agent status should be updated")
+	}
+
+	// List
+	agents, err := storage.ListAgents(ctx)
+	if err != nil {
+		t.Fatalf("failed to list agents: %v", err)
+	}
+	if len(agents) != 1 {
+		t.Errorf("should return 1 agent, got %d", len(agents))
+	}
+
+	// Delete
+	if err := storage.DeleteAgent(ctx, "agent-1"); err != nil {
+		t.Fatalf("failed to delete agent: %v", err)
+	}
+
+	_, err = storage.GetAgent(ctx, "agent-1")
+	if !IsNotFound(err) {
+		t.Error("should return not found after delete")
+	}
+}
+
+func testSyncState(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	// Set value
+	if err := storage.SetSyncState(ctx, "test_key", "test_value"); err != nil {
+		t.Fatalf("failed to set sync state: %v", err)
+	}
+
+	// Get value
+	value, err := storage.GetSyncState(ctx, "test_key")
+	if err != nil {
+		t.Fatalf("failed to get sync state: %v", err)
+	}
+	if value != "test_value" {
+		t.Errorf("value mismatch: got %s, want test_value", value)
+	}
+
+	// Update value
+	if err := storage.SetSyncState(ctx, "test_key", "new_value"); err != nil {
+		t.Fatalf("failed to update sync state: %v", err)
+	}
+
+	value, _ = storage.GetSyncState(ctx, "test_key")
+	if value != "new_value" {
+		t.Errorf("value should be updated, got %s", value)
+	}
+
+	// Get non-existent key
+	value, err = storage.GetSyncState(ctx, "non_existent")
+	if err != nil {
+		t.Fatalf("should not error for non-existent key: %v", err)
+	}
+	if value != "" {
+		t.Error("should return empty string for non-existent key")
+	}
+}
+
+func testCleanupDeletedResources(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	// Create resources with different status
+	now := time.Now()
+	oldTime := now.Add(-24 * time.Hour)
+
+	resources := []*ManagedResource{
+		{
+			ResourceType: ResourceTypeDNS,
+			Hostname:     "active.example.com",
+			ServiceName:  "active",
+			Status:       StatusActive,
+		},
+		{
+			ResourceType: ResourceTypeDNS,
+			Hostname:     "deleted1.example.com",
+			ServiceName:  "deleted1",
+			Status:       StatusDeleted,
+			DeletedAt:    &oldTime,
+		},
+		{
+			ResourceType: ResourceTypeDNS,
+			Hostname:     "deleted2.example.com",
+			ServiceName:  "deleted2",
+			Status:       StatusDeleted,
+			DeletedAt:    &now,
+		},
+	}
+
+	for _, r := range resources {
+		if err := storage.SaveResource(ctx, r); err != nil {
+			t.Fatalf("failed to save resource: %v", err)
+		}
+	}
+
+	// Cleanup old deleted resources
+	cutoff := now.Add(-12 * time.Hour)
+	count, err := storage.CleanupDeletedResources(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("failed to cleanup: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("should cleanup 1 resource, got %d", count)
+	}
+
+	// Verify remaining resources
+	all, _ := storage.ListResources(ctx, ResourceFilter{})
+	if len(all) != 2 {
+		t.Errorf("should have 2 resources remaining, got %d", len(all))
+	}
+}
+
+func testHostnameUniqueness(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	// Create first resource
+	resource1 := &ManagedResource{
+		ResourceType: ResourceTypeDNS,
+		Hostname:     "unique.example.com",
+		RecordType:   "A",
+		ServiceName:  "web1",
+		Status:       StatusActive,
+	}
+	if err := storage.SaveResource(ctx, resource1); err != nil {
+		t.Fatalf("failed to save first resource: %v", err)
+	}
+
+	// Save second resource with same (resource_type, hostname, record_type).
+	// SaveResource uses ON CONFLICT DO UPDATE (upsert), so this should succeed
+	// and overwrite the first resource's mutable fields.
+	resource2 := &ManagedResource{
+		ResourceType: ResourceTypeDNS,
+		Hostname:     "unique.example.com",
+		RecordType:   "A",
+		ServiceName:  "web2",
+		Status:       StatusActive,
+	}
+	if err := storage.SaveResource(ctx, resource2); err != nil {
+		t.Fatalf("upsert with same key should succeed: %v", err)
+	}
+
+	// Verify the upsert replaced service_name
+	got, err := storage.GetResourceByHostname(ctx, "unique.example.com", ResourceTypeDNS)
+	if err != nil {
+		t.Fatalf("failed to get resource after upsert: %v", err)
+	}
+	if got.ServiceName != "web2" {
+		t.Errorf("expected service_name to be updated to web2, got %s", got.ServiceName)
+	}
+
+	// Different resource_type with same hostname should NOT conflict
+	tunnelResource := &ManagedResource{
+		ResourceType: ResourceTypeTunnelIngress,
+		Hostname:     "unique.example.com",
+		RecordType:   "",
+		ServiceName:  "tunnel1",
+		Status:       StatusActive,
+	}
+	if err := storage.SaveResource(ctx, tunnelResource); err != nil {
+		t.Fatalf("different resource_type with same hostname should not conflict: %v", err)
+	}
+
+	// Different record_type with same hostname should NOT conflict
+	aaaaResource := &ManagedResource{
+		ResourceType: ResourceTypeDNS,
+		Hostname:     "unique.example.com",
+		RecordType:   "AAAA",
+		ServiceName:  "web3",
+		Status:       StatusActive,
+	}
+	if err := storage.SaveResource(ctx, aaaaResource); err != nil {
+		t.Fatalf("different record_type with same hostname should not conflict: %v", err)
+	}
+}
+
+func testTunnelPaths(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	// Paths of one hostname are separate ingress resources
+	for _, path := range []string{"", "/api"} {
+		if err := storage.SaveResource(ctx, &ManagedResource{
+			ResourceType: ResourceTypeTunnelIngress,
+			Hostname:     "app.example.com",
+			Path:         path,
+			Service:      "http://app:8080",
+			ServiceName:  "web",
+			Status:       StatusActive,
+		}); err != nil {
+			t.Fatalf("failed to save tunnel resource (path %q): %v", path, err)
+		}
+	}
+
+	resources, err := storage.ListResources(ctx, ResourceFilter{ResourceType: ResourceTypeTunnelIngress})
+	if err != nil {
+		t.Fatalf("failed to list resources: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Errorf("expected 2 tunnel resources, got %d", len(resources))
+	}
+}
+
+func testRebindResource(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	resource := &ManagedResource{
+		ResourceType:  ResourceTypeDNS,
+		CFID:          "cf-1",
+		Hostname:      "app.example.com",
+		RecordType:    "A",
+		Content:       "1.2.3.4",
+		ContainerID:   "blue",
+		ContainerName: "app-blue",
+		ServiceName:   "web",
+		Status:        StatusActive,
+	}
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+
+	owner := ResourceOwner{ContainerID: "green", ContainerName: "app-green", ServiceName: "web"}
+	if err := storage.RebindResource(ctx, resource.ID, "blue", owner); err != nil {
+		t.Fatalf("failed to rebind resource: %v", err)
+	}
+
+	got, err := storage.GetResource(ctx, resource.ID)
+	if err != nil {
+		t.Fatalf("failed to get resource: %v", err)
+	}
+	if got.ContainerID != "green" || got.ContainerName != "app-green" {
+		t.Errorf("expected resource owned by app-green, got %s (%s)", got.ContainerName, got.ContainerID)
+	}
+	if got.CFID != "cf-1" || got.Content != "1.2.3.4" {
+		t.Errorf("rebind must not touch the record itself, got cf_id=%s content=%s", got.CFID, got.Content)
+	}
+
+	// A stale rebind from the previous owner does not apply
+	if err := storage.RebindResource(ctx, resource.ID, "blue", owner); !IsNotFound(err) {
+		t.Errorf("expected not found for stale owner, got %v", err)
+	}
+}
+
+func testResourceHistory(t *testing.T, storage Storage) {
+	ctx := WithReconcileID(context.Background(), "rec-1")
+
+	resource := &ManagedResource{
+		ResourceType:  ResourceTypeDNS,
+		Hostname:      "app.example.com",
+		RecordType:    "A",
+		Content:       "1.2.3.4",
+		ContainerID:   "blue",
+		ContainerName: "app-blue",
+		ServiceName:   "web",
+		Status:        StatusActive,
+	}
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+
+	// Saving unchanged state records nothing
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+
+	resource.Content = "5.6.7.8"
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+	if err := storage.RebindResource(ctx, resource.ID, "blue", ResourceOwner{ContainerID: "green", ContainerName: "app-green", ServiceName: "web"}); err != nil {
+		t.Fatalf("failed to rebind resource: %v", err)
+	}
+	if err := storage.UpdateResourceStatus(ctx, resource.ID, StatusOrphaned); err != nil {
+		t.Fatalf("failed to orphan resource: %v", err)
+	}
+	if err := storage.UpdateResourceError(ctx, resource.ID, StatusError, "boom"); err != nil {
+		t.Fatalf("failed to set error: %v", err)
+	}
+	if err := storage.DeleteResource(ctx, resource.ID); err != nil {
+		t.Fatalf("failed to delete resource: %v", err)
+	}
+
+	events, err := storage.ListResourceEvents(ctx, EventFilter{ResourceID: resource.ID})
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+
+	want := []ResourceAction{ActionDelete, ActionError, ActionOrphan, ActionHandover, ActionUpdate, ActionCreate}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(events))
+	}
+	for i, e := range events {
+		if e.Action != want[i] {
+			t.Errorf("event %d: expected %s, got %s", i, want[i], e.Action)
+		}
+		if e.ReconcileID != "rec-1" {
+			t.Errorf("event %d: expected reconcile ID rec-1, got %q", i, e.ReconcileID)
+		}
+	}
+
+	update := events[4]
+	if update.Before == nil || update.Before.Content != "1.2.3.4" || update.After == nil || update.After.Content != "5.6.7.8" {
+		t.Errorf("expected update event with before/after content, got %+v", update)
+	}
+	if handover := events[3]; handover.ContainerName != "app-green" || handover.Before.ContainerName != "app-blue" {
+		t.Errorf("expected handover from app-blue to app-green, got %+v", handover)
+	}
+	if events[1].Error != "boom" {
+		t.Errorf("expected error event to carry the error, got %q", events[1].Error)
+	}
+	if events[0].After != nil || events[0].Before == nil {
+		t.Errorf("expected delete event with only a before state")
+	}
+
+	// Retention removes old entries
+	deleted, err := storage.CleanupResourceEvents(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to clean up events: %v", err)
+	}
+	if deleted != int64(len(want)) {
+		t.Errorf("expected %d events cleaned up, got %d", len(want), deleted)
+	}
+}
+
+func testConflicts(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	loser := &Conflict{
+		Hostname:            "app.example.com",
+		ResourceType:        ResourceTypeDNS,
+		ContainerID:         "c2",
+		ContainerName:       "app-new",
+		ServiceName:         "web",
+		WinnerContainerID:   "c1",
+		WinnerContainerName: "app-old",
+		WinnerServiceName:   "web",
+		Reason:              "created after app-old",
+	}
+	if err := storage.ReplaceConflicts(ctx, []*Conflict{loser}); err != nil {
+		t.Fatalf("failed to save conflicts: %v", err)
+	}
+
+	conflicts, err := storage.ListConflicts(ctx)
+	if err != nil {
+		t.Fatalf("failed to list conflicts: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d", len(conflicts))
+	}
+	firstSeen := conflicts[0].FirstSeen
+
+	// Same conflict seen again keeps its first_seen
+	time.Sleep(10 * time.Millisecond)
+	again := *loser
+	again.ID = ""
+	if err := storage.ReplaceConflicts(ctx, []*Conflict{&again}); err != nil {
+		t.Fatalf("failed to save conflicts: %v", err)
+	}
+	conflicts, err = storage.ListConflicts(ctx)
+	if err != nil {
+		t.Fatalf("failed to list conflicts: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d", len(conflicts))
+	}
+	if !conflicts[0].FirstSeen.Equal(firstSeen) {
+		t.Errorf("expected first_seen to be kept, got %v want %v", conflicts[0].FirstSeen, firstSeen)
+	}
+	if !conflicts[0].LastSeen.After(firstSeen) {
+		t.Errorf("expected last_seen to be updated")
+	}
+
+	// Resolved conflicts are removed
+	if err := storage.ReplaceConflicts(ctx, nil); err != nil {
+		t.Fatalf("failed to clear conflicts: %v", err)
+	}
+	conflicts, err = storage.ListConflicts(ctx)
+	if err != nil {
+		t.Fatalf("failed to list conflicts: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("expected resolved conflicts to be removed, got %d", len(conflicts))
+	}
+}
+
+func testCounts(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	resources := []*ManagedResource{
+		{ResourceType: ResourceTypeDNS, Hostname: "a.example.com", RecordType: "A", ServiceName: "web", Status: StatusActive},
+		{ResourceType: ResourceTypeDNS, Hostname: "b.example.com", RecordType: "A", ServiceName: "web", Status: StatusActive, AgentID: "agent-1"},
+		{ResourceType: ResourceTypeDNS, Hostname: "c.example.com", RecordType: "A", ServiceName: "web", Status: StatusError, AgentID: "agent-1"},
+		{ResourceType: ResourceTypeTunnelIngress, Hostname: "a.example.com", ServiceName: "web", Status: StatusActive, AgentID: "agent-1"},
+	}
+	for _, r := range resources {
+		if err := storage.SaveResource(ctx, r); err != nil {
+			t.Fatalf("failed to save resource: %v", err)
+		}
+	}
+
+	// Totals ignore pagination
+	total, err := storage.CountResources(ctx, ResourceFilter{ResourceType: ResourceTypeDNS, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("failed to count resources: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 DNS resources, got %d", total)
+	}
+
+	groups, err := storage.CountResourcesByGroup(ctx, ResourceFilter{})
+	if err != nil {
+		t.Fatalf("failed to count resources by group: %v", err)
+	}
+	got := make(map[ResourceCount]int)
+	for _, g := range groups {
+		got[ResourceCount{ResourceType: g.ResourceType, Status: g.Status, AgentID: g.AgentID}] = g.Count
+	}
+	want := map[ResourceCount]int{
+		{ResourceType: ResourceTypeDNS, Status: StatusActive}:                               1,
+		{ResourceType: ResourceTypeDNS, Status: StatusActive, AgentID: "agent-1"}:           1,
+		{ResourceType: ResourceTypeDNS, Status: StatusError, AgentID: "agent-1"}:            1,
+		{ResourceType: ResourceTypeTunnelIngress, Status: StatusActive, AgentID: "agent-1"}: 1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d groups, got %d: %+v", len(want), len(got), groups)
+	}
+	for k, n := range want {
+		if got[k] != n {
+			t.Errorf("group %+v: expected %d, got %d", k, n, got[k])
+		}
+	}
+
+	// Every save was recorded in history
+	events, err := storage.CountResourceEvents(ctx, EventFilter{Hostname: "a.example.com", Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if events != 2 {
+		t.Errorf("expected 2 events for a.example.com, got %d", events)
+	}
+}
+
+func testAudit(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	entries := []*AuditEntry{
+		{Action: "reconcile", Actor: "10.0.0.1", Status: AuditSuccess},
+		{Action: "resource.forget", Target: "r1", Actor: "10.0.0.1", Status: AuditSuccess},
+		{Action: "resource.retry", Target: "r1", Actor: "10.0.0.2", Status: AuditError, Error: "still failing"},
+	}
+	for _, e := range entries {
+		if err := storage.RecordAudit(ctx, e); err != nil {
+			t.Fatalf("failed to record audit entry: %v", err)
+		}
+	}
+
+	got, err := storage.ListAuditEntries(ctx, AuditFilter{Target: "r1", Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if len(got) != 1 || got[0].Action != "resource.retry" || got[0].Error != "still failing" {
+		t.Fatalf("expected newest entry for r1 first, got %+v", got)
+	}
+
+	total, err := storage.CountAuditEntries(ctx, AuditFilter{Target: "r1", Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to count audit entries: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("expected 2 entries for r1, got %d", total)
+	}
+}
+
+func testAPITokens(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	token := &APIToken{Name: "ci", Hash: "sha256:abc", Scopes: []string{"read", "write"}, ExpiresAt: &expires}
+	if err := storage.CreateAPIToken(ctx, token); err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if token.ID == "" {
+		t.Error("expected an ID to be assigned")
+	}
+
+	err := storage.CreateAPIToken(ctx, &APIToken{Name: "ci", Hash: "sha256:def", Scopes: []string{"read"}})
+	if !IsConflict(err) {
+		t.Errorf("expected conflict for duplicate name, got %v", err)
+	}
+
+	if err := storage.TouchAPIToken(ctx, "ci", time.Now()); err != nil {
+		t.Fatalf("failed to touch token: %v", err)
+	}
+
+	tokens, err := storage.ListAPITokens(ctx)
+	if err != nil {
+		t.Fatalf("failed to list tokens: %v", err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("expected 1 token, got %d", len(tokens))
+	}
+	got := tokens[0]
+	if got.Hash != "sha256:abc" || len(got.Scopes) != 2 || got.Scopes[1] != "write" {
+		t.Errorf("unexpected token: %+v", got)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+		t.Errorf("expected expiry %v, got %v", expires, got.ExpiresAt)
+	}
+	if got.LastUsedAt == nil {
+		t.Error("expected last_used_at to be set")
+	}
+
+	if err := storage.DeleteAPIToken(ctx, "ci"); err != nil {
+		t.Fatalf("failed to delete token: %v", err)
+	}
+	if err := storage.DeleteAPIToken(ctx, "ci"); !IsNotFound(err) {
+		t.Errorf("expected not found deleting a revoked token, got %v", err)
+	}
+}
+
+func testEventNotifier(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	notifier, ok := storage.(EventNotifier)
+	if !ok {
+		t.Skip("backend does not report resource events")
+	}
+	var got []ResourceAction
+	notifier.OnResourceEvent(func(e *ResourceEvent) {
+		got = append(got, e.Action)
+	})
+
+	resource := &ManagedResource{
+		ResourceType: ResourceTypeDNS,
+		Hostname:     "notify.example.com",
+		RecordType:   "A",
+		ServiceName:  "web",
+		Status:       StatusActive,
+	}
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+	// Unchanged save records no history and must not notify
+	if err := storage.SaveResource(ctx, resource); err != nil {
+		t.Fatalf("failed to save resource: %v", err)
+	}
+	if err := storage.UpdateResourceStatus(ctx, resource.ID, StatusOrphaned); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	if err := storage.DeleteResource(ctx, resource.ID); err != nil {
+		t.Fatalf("failed to delete resource: %v", err)
+	}
+
+	want := []ResourceAction{ActionCreate, ActionOrphan, ActionDelete}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+// testConcurrentWrites runs writes from several goroutines, as the
+// reconciler's workers do; none may fail on a locked database.
+func testConcurrentWrites(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	const writers, writes = 8, 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes*2)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range writes {
+				resource := &ManagedResource{
+					ResourceType: ResourceTypeDNS,
+					CFID:         fmt.Sprintf("cf-%d-%d", w, i),
+					Hostname:     fmt.Sprintf("app-%d-%d.example.com", w, i),
+					RecordType:   "A",
+					Content:      "1.2.3.4",
+					ContainerID:  fmt.Sprintf("container-%d", w),
+					Status:       StatusActive,
+				}
+				if err := storage.SaveResource(ctx, resource); err != nil {
+					errs <- err
+					continue
+				}
+				if err := storage.UpdateResourceError(ctx, resource.ID, StatusError, "boom"); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+	for err := range errs {
+		if failed == 0 {
+			t.Errorf("concurrent write failed: %v", err)
+		}
+		failed++
+	}
+	if failed > 0 {
+		t.Errorf("%d of %d concurrent writes failed", failed, writers*writes*2)
+	}
+}
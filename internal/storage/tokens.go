@@ -0,0 +1,24 @@
+package storage
+
+import "time"
+
+// APIToken is a named API token. Only a hash of the token is stored; the
+// plaintext is shown once when the token is created.
+type APIToken struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Hash is the token hash (see auth.HashToken); never exposed through the API
+	Hash string `json:"-"`
+	// Scopes granted to the token, e.g. "read" or "write"
+	Scopes []string `json:"scopes"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Expired reports whether the token has expired at now.
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
@@ -0,0 +1,83 @@
+// Package tracing sets up OpenTelemetry tracing and provides helpers for
+// instrumenting labelgate. Until Setup installs a provider, spans are no-ops.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/channinghe/labelgate/internal/config"
+)
+
+// instrumentationName identifies labelgate's tracer.
+const instrumentationName = "github.com/channinghe/labelgate"
+
+// Setup installs a global tracer provider exporting to the OTLP/HTTP endpoint
+// in cfg. It returns a shutdown function that flushes pending spans. When
+// tracing is disabled, nothing is installed and shutdown is a no-op.
+func Setup(ctx context.Context, cfg *config.TracingConfig, serviceVersion string) (shutdown func(context.Context) error, err error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.Endpoint)}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := NewProvider(cfg, sdktrace.NewBatchSpanProcessor(exporter), serviceVersion)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider that samples per cfg and hands spans
+// to processor. Tests pass a synchronous processor around an in-memory exporter.
+func NewProvider(cfg *config.TracingConfig, processor sdktrace.SpanProcessor, serviceVersion string) *sdktrace.TracerProvider {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(serviceVersion),
+	))
+	if err != nil {
+		// Only fails on conflicting schema URLs; keep our attributes
+		res = resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName), semconv.ServiceVersion(serviceVersion))
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+}
+
+// Start starts a span from the global tracer provider.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError marks span as failed with err; a nil err is ignored.
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// End records err (if any) on span and ends it.
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
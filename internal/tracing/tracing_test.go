@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/channinghe/labelgate/internal/config"
+)
+
+// useInMemoryExporter installs a provider recording spans in memory for the
+// duration of the test.
+func useInMemoryExporter(t *testing.T, cfg *config.TracingConfig) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(cfg, sdktrace.NewSimpleSpanProcessor(exporter), "test")
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestSpans(t *testing.T) {
+	exporter := useInMemoryExporter(t, &config.TracingConfig{ServiceName: "labelgate", SampleRatio: 1})
+
+	ctx, parent := Start(context.Background(), "reconcile")
+	_, child := Start(ctx, "reconcile.dns")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	dns, rec := spans[0], spans[1]
+	if dns.Name != "reconcile.dns" || rec.Name != "reconcile" {
+		t.Fatalf("unexpected spans %q, %q", dns.Name, rec.Name)
+	}
+	if dns.Parent.SpanID() != rec.SpanContext.SpanID() {
+		t.Errorf("expected reconcile.dns to be a child of reconcile")
+	}
+	if dns.Status.Code != codes.Error || len(dns.Events) != 1 {
+		t.Errorf("expected error status and recorded error on reconcile.dns, got %v", dns.Status)
+	}
+	if rec.Status.Code != codes.Unset {
+		t.Errorf("expected unset status on reconcile, got %v", rec.Status)
+	}
+}
+
+func TestSampleRatioZero(t *testing.T) {
+	exporter := useInMemoryExporter(t, &config.TracingConfig{ServiceName: "labelgate", SampleRatio: 0})
+
+	_, span := Start(context.Background(), "reconcile")
+	span.End()
+
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("expected no sampled spans, got %d", n)
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), &config.TracingConfig{Enabled: false}, "test")
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+}
@@ -8,6 +8,8 @@ type EventType string
 const (
 	// EventStart is emitted when a container starts.
 	EventStart EventType = "start"
+	// EventKill is emitted when a container is signalled to stop, before it exits.
+	EventKill EventType = "kill"
 	// EventStop is emitted when a container stops.
 	EventStop EventType = "stop"
 	// EventDie is emitted when a container dies.
@@ -83,4 +85,22 @@ type ParsedContainer struct {
 
 	// AgentID is the agent that reported this container
 	AgentID string `json:"agent_id,omitempty"`
+
+	// Priority resolves hostname conflicts between containers (higher wins).
+	// Set with the container-level <prefix>.priority label.
+	Priority int `json:"priority,omitempty"`
+
+	// Rollout is the percentage of the tunnel rules shared with other running
+	// containers that this container takes over, regardless of precedence
+	// (0 = off). Set with the container-level <prefix>.rollout label.
+	Rollout int `json:"rollout,omitempty"`
+
+	// Errors are the label parse errors and hostname conflicts between the
+	// container's own services. Invalid services are skipped.
+	Errors []string `json:"errors,omitempty"`
+
+	// Stopping is set once the container was signalled to stop. A stopping
+	// container loses every hostname conflict, so a replacement container can
+	// take its resources over before it exits.
+	Stopping bool `json:"stopping,omitempty"`
 }
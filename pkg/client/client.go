@@ -0,0 +1,442 @@
+// Package client is a typed Go client for the labelgate HTTP API, as
+// described by the OpenAPI document served at /api/openapi.json.
+//
+//	c := client.New("http://labelgate:8080/api", os.Getenv("LABELGATE_TOKEN"))
+//	dns, err := c.ListDNS(ctx, client.ResourceFilter{Status: client.StatusError})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the labelgate API.
+type Client struct {
+	// BaseURL is the API base URL including the base path, e.g. http://localhost:8080/api
+	BaseURL string
+
+	// Token is the Bearer API token; empty when authentication is disabled
+	Token string
+
+	// HTTPClient is used for requests (default: http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// New creates a client for the API at baseURL.
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+	}
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	Message    string
+
+	// LoginURL is where to log in, for 401 responses when OIDC login is enabled
+	LoginURL string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("labelgate API: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnavailable reports whether err is a 503 response, returned for actions
+// on an instance that doesn't run the reconciler or a disabled feature.
+func IsUnavailable(err error) bool {
+	return hasStatus(err, http.StatusServiceUnavailable)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// ResourceFilter filters resource lists. Zero values don't filter.
+type ResourceFilter struct {
+	Status   string
+	AgentID  string
+	Hostname string
+	Limit    int
+	Offset   int
+}
+
+func (f ResourceFilter) query() url.Values {
+	q := url.Values{}
+	set(q, "status", f.Status)
+	set(q, "agent_id", f.AgentID)
+	set(q, "hostname", f.Hostname)
+	setInt(q, "limit", f.Limit)
+	setInt(q, "offset", f.Offset)
+	return q
+}
+
+// EventFilter filters resource history. Zero values don't filter.
+type EventFilter struct {
+	ResourceType string
+	Hostname     string
+	Action       string
+	ReconcileID  string
+	Since        time.Time
+	Limit        int // default 100
+	Offset       int
+}
+
+func (f EventFilter) query() url.Values {
+	q := url.Values{}
+	set(q, "resource_type", f.ResourceType)
+	set(q, "hostname", f.Hostname)
+	set(q, "action", f.Action)
+	set(q, "reconcile_id", f.ReconcileID)
+	setTime(q, "since", f.Since)
+	setInt(q, "limit", f.Limit)
+	setInt(q, "offset", f.Offset)
+	return q
+}
+
+// ContainerFilter filters the container list.
+type ContainerFilter struct {
+	// AgentID selects an agent's containers; "local" for the local Docker host
+	AgentID string
+	// OnlyErrors selects containers with label errors
+	OnlyErrors bool
+}
+
+// AuditFilter filters the audit trail. Zero values don't filter.
+type AuditFilter struct {
+	Action string
+	Target string
+	Since  time.Time
+	Limit  int // default 100
+	Offset int
+}
+
+// Health checks that the server is up. It needs no token.
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/health", nil, nil, nil)
+}
+
+// Live runs the liveness check.
+func (c *Client) Live(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil, nil)
+}
+
+// Ready runs the readiness checks. A failing check is reported in the
+// result, not as an error.
+func (c *Client) Ready(ctx context.Context) (*Readiness, error) {
+	var result Readiness
+	err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, &result)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable {
+		// The 503 body has no error field, so the message is the raw result
+		if json.Unmarshal([]byte(apiErr.Message), &result) == nil && result.Status != "" {
+			return &result, nil
+		}
+	}
+	return &result, err
+}
+
+// OpenAPI returns the OpenAPI document of the server.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var doc json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/openapi.json", nil, nil, &doc)
+	return doc, err
+}
+
+// Me returns the authenticated caller and its scopes.
+func (c *Client) Me(ctx context.Context) (*Me, error) {
+	var me Me
+	return &me, c.do(ctx, http.MethodGet, "/auth/me", nil, nil, &me)
+}
+
+// Overview returns the dashboard overview.
+func (c *Client) Overview(ctx context.Context) (*Overview, error) {
+	var o Overview
+	return &o, c.do(ctx, http.MethodGet, "/overview", nil, nil, &o)
+}
+
+// Version returns the server version.
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var v Version
+	return &v, c.do(ctx, http.MethodGet, "/version", nil, nil, &v)
+}
+
+// ListDNS lists managed DNS records.
+func (c *Client) ListDNS(ctx context.Context, filter ResourceFilter) (*ResourceList, error) {
+	var list ResourceList
+	return &list, c.do(ctx, http.MethodGet, "/resources/dns", filter.query(), nil, &list)
+}
+
+// ListTunnels lists managed tunnel ingress rules.
+func (c *Client) ListTunnels(ctx context.Context, filter ResourceFilter) (*ResourceList, error) {
+	var list ResourceList
+	return &list, c.do(ctx, http.MethodGet, "/resources/tunnels", filter.query(), nil, &list)
+}
+
+// ListAccess lists managed Access applications.
+func (c *Client) ListAccess(ctx context.Context, filter ResourceFilter) (*ResourceList, error) {
+	var list ResourceList
+	return &list, c.do(ctx, http.MethodGet, "/resources/access", filter.query(), nil, &list)
+}
+
+// ResourceHistory returns the history of one resource, newest first.
+// filter.ResourceType is ignored.
+func (c *Client) ResourceHistory(ctx context.Context, id string, filter EventFilter) (*EventList, error) {
+	q := filter.query()
+	q.Del("resource_type")
+	var list EventList
+	return &list, c.do(ctx, http.MethodGet, "/resources/"+url.PathEscape(id)+"/history", q, nil, &list)
+}
+
+// ListEvents returns resource history across all resources, newest first.
+func (c *Client) ListEvents(ctx context.Context, filter EventFilter) (*EventList, error) {
+	var list EventList
+	return &list, c.do(ctx, http.MethodGet, "/events", filter.query(), nil, &list)
+}
+
+// ListConflicts lists hostname conflicts.
+func (c *Client) ListConflicts(ctx context.Context) ([]*Conflict, error) {
+	var resp struct {
+		Conflicts []*Conflict `json:"conflicts"`
+	}
+	return resp.Conflicts, c.do(ctx, http.MethodGet, "/conflicts", nil, nil, &resp)
+}
+
+// ListContainers lists the labeled containers known to the reconciler.
+func (c *Client) ListContainers(ctx context.Context, filter ContainerFilter) ([]*Container, error) {
+	q := url.Values{}
+	set(q, "agent_id", filter.AgentID)
+	if filter.OnlyErrors {
+		q.Set("errors", "true")
+	}
+	var resp struct {
+		Containers []*Container `json:"containers"`
+	}
+	return resp.Containers, c.do(ctx, http.MethodGet, "/containers", q, nil, &resp)
+}
+
+// GetContainer returns one container by ID, unique ID prefix or name.
+func (c *Client) GetContainer(ctx context.Context, id string) (*Container, error) {
+	var container Container
+	return &container, c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id), nil, nil, &container)
+}
+
+// ListAgents lists registered agents.
+func (c *Client) ListAgents(ctx context.Context) ([]*Agent, error) {
+	var resp struct {
+		Agents []*Agent `json:"agents"`
+	}
+	return resp.Agents, c.do(ctx, http.MethodGet, "/agents", nil, nil, &resp)
+}
+
+// Snapshot writes an online backup of the SQLite database to w. It requires
+// an admin token.
+func (c *Client) Snapshot(ctx context.Context, w io.Writer) error {
+	return c.do(ctx, http.MethodGet, "/db/snapshot", nil, nil, w)
+}
+
+// ListAudit returns the audit trail of write actions, newest first.
+func (c *Client) ListAudit(ctx context.Context, filter AuditFilter) (*AuditList, error) {
+	q := url.Values{}
+	set(q, "action", filter.Action)
+	set(q, "target", filter.Target)
+	setTime(q, "since", filter.Since)
+	setInt(q, "limit", filter.Limit)
+	setInt(q, "offset", filter.Offset)
+	var list AuditList
+	return &list, c.do(ctx, http.MethodGet, "/audit", q, nil, &list)
+}
+
+// ListNotificationSinks lists the configured notification sinks.
+func (c *Client) ListNotificationSinks(ctx context.Context) (*NotificationSinks, error) {
+	var sinks NotificationSinks
+	return &sinks, c.do(ctx, http.MethodGet, "/notifications", nil, nil, &sinks)
+}
+
+// TestNotifications sends a test notification to sink, or to every sink if
+// sink is empty.
+func (c *Client) TestNotifications(ctx context.Context, sink string) ([]*NotificationTestResult, error) {
+	var resp struct {
+		Results []*NotificationTestResult `json:"results"`
+	}
+	body := map[string]string{"sink": sink}
+	return resp.Results, c.do(ctx, http.MethodPost, "/notifications/test", nil, body, &resp)
+}
+
+// Validate lints the labels of one container without applying them.
+func (c *Client) Validate(ctx context.Context, labels map[string]string) (*ValidateResult, error) {
+	var result ValidateResult
+	body := map[string]any{"labels": labels}
+	return &result, c.do(ctx, http.MethodPost, "/validate", nil, body, &result)
+}
+
+// ValidateContainers lints the labels of several containers (name -> labels),
+// resolving access policy references across them.
+func (c *Client) ValidateContainers(ctx context.Context, containers map[string]map[string]string) (*ValidateResult, error) {
+	var result ValidateResult
+	body := map[string]any{"containers": containers}
+	return &result, c.do(ctx, http.MethodPost, "/validate", nil, body, &result)
+}
+
+// Reconcile runs a full reconcile and waits for it to finish. A failed
+// reconcile is reported in ReconcileResult.Error, not as an error.
+func (c *Client) Reconcile(ctx context.Context) (*ReconcileResult, error) {
+	var result ReconcileResult
+	return &result, c.do(ctx, http.MethodPost, "/reconcile", nil, nil, &result)
+}
+
+// RetryResource re-reconciles a resource in error status.
+func (c *Client) RetryResource(ctx context.Context, id string) (*Resource, error) {
+	var resp struct {
+		Resource *Resource `json:"resource"`
+	}
+	return resp.Resource, c.do(ctx, http.MethodPost, "/resources/"+url.PathEscape(id)+"/retry", nil, nil, &resp)
+}
+
+// ForceDeleteResource deletes an orphaned resource from Cloudflare now.
+func (c *Client) ForceDeleteResource(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/resources/"+url.PathEscape(id)+"/force-delete", nil, nil, nil)
+}
+
+// ForgetResource removes a non-active resource from the database without
+// touching Cloudflare.
+func (c *Client) ForgetResource(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/resources/"+url.PathEscape(id)+"/forget", nil, nil, nil)
+}
+
+// RefreshAgent asks a connected agent to re-report its containers.
+func (c *Client) RefreshAgent(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/agents/"+url.PathEscape(id)+"/refresh", nil, nil, nil)
+}
+
+// ReconnectAgent asks a connected agent to reconnect.
+func (c *Client) ReconnectAgent(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/agents/"+url.PathEscape(id)+"/reconnect", nil, nil, nil)
+}
+
+// ListTokens lists API tokens.
+func (c *Client) ListTokens(ctx context.Context) ([]*Token, error) {
+	var resp struct {
+		Tokens []*Token `json:"tokens"`
+	}
+	return resp.Tokens, c.do(ctx, http.MethodGet, "/tokens", nil, nil, &resp)
+}
+
+// CreateToken creates an API token. The plaintext token is only returned here.
+func (c *Client) CreateToken(ctx context.Context, req CreateTokenRequest) (*CreatedToken, error) {
+	var token CreatedToken
+	return &token, c.do(ctx, http.MethodPost, "/tokens", nil, req, &token)
+}
+
+// RevokeToken deletes an API token.
+func (c *Client) RevokeToken(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/tokens/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// ReloadConfig re-reads the server configuration and applies the settings
+// that can change at runtime. An invalid configuration returns an *Error
+// with status 400 and the current configuration stays in effect.
+func (c *Client) ReloadConfig(ctx context.Context) (*ReloadResult, error) {
+	var result ReloadResult
+	return &result, c.do(ctx, http.MethodPost, "/config/reload", nil, nil, &result)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out, or copies it if out is an io.Writer.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var errBody struct {
+			Error    string `json:"error"`
+			LoginURL string `json:"login_url"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &errBody) == nil && errBody.Error != "" {
+			apiErr.Message, apiErr.LoginURL = errBody.Error, errBody.LoginURL
+		} else {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case io.Writer:
+		_, err = io.Copy(out, resp.Body)
+		return err
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+		}
+		return nil
+	}
+}
+
+func set(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
+
+func setInt(q url.Values, key string, value int) {
+	if value != 0 {
+		q.Set(key, strconv.Itoa(value))
+	}
+}
+
+func setTime(q url.Values, key string, value time.Time) {
+	if !value.IsZero() {
+		q.Set(key, value.UTC().Format(time.RFC3339))
+	}
+}
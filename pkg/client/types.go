@@ -0,0 +1,339 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Resource types.
+const (
+	ResourceTypeDNS           = "dns"
+	ResourceTypeTunnelIngress = "tunnel_ingress"
+	ResourceTypeAccessApp     = "access_app"
+)
+
+// Resource statuses.
+const (
+	StatusActive         = "active"
+	StatusOrphaned       = "orphaned"
+	StatusError          = "error"
+	StatusDeleted        = "deleted"
+	StatusPendingCleanup = "pending_cleanup"
+)
+
+// API token scopes.
+const (
+	ScopeRead        = "read"
+	ScopeWrite       = "write"
+	ScopeAgentsAdmin = "agents-admin"
+	ScopeAdmin       = "admin"
+)
+
+// Resource is a Cloudflare resource managed by labelgate.
+type Resource struct {
+	ID           string `json:"id"`
+	ResourceType string `json:"resource_type"`
+	CFID         string `json:"cf_id,omitempty"`
+
+	// DNS
+	ZoneID     string `json:"zone_id,omitempty"`
+	Hostname   string `json:"hostname"`
+	RecordType string `json:"record_type,omitempty"`
+	Content    string `json:"content,omitempty"`
+	Proxied    bool   `json:"proxied,omitempty"`
+	TTL        int    `json:"ttl,omitempty"`
+
+	// Tunnel ingress
+	TunnelID string `json:"tunnel_id,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Path     string `json:"path,omitempty"`
+
+	// Access application
+	AccessAppID      string `json:"access_app_id,omitempty"`
+	AccountID        string `json:"account_id,omitempty"`
+	AccessAppName    string `json:"access_app_name,omitempty"`
+	AccessPolicyName string `json:"access_policy_name,omitempty"`
+	AccessDecision   string `json:"access_decision,omitempty"`
+
+	// Owner
+	ContainerID   string `json:"container_id,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	ServiceName   string `json:"service_name"`
+	AgentID       string `json:"agent_id,omitempty"`
+
+	Status         string     `json:"status"`
+	CleanupEnabled bool       `json:"cleanup_enabled"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ResourceList is one page of resources.
+type ResourceList struct {
+	Resources []*Resource `json:"resources"`
+	// Total is the number of matches across all pages
+	Total int `json:"total"`
+}
+
+// ResourceEvent is a change to a managed resource.
+type ResourceEvent struct {
+	ID            int64     `json:"id"`
+	ResourceID    string    `json:"resource_id"`
+	ResourceType  string    `json:"resource_type"`
+	Hostname      string    `json:"hostname"`
+	Action        string    `json:"action"`
+	Before        *Resource `json:"before,omitempty"`
+	After         *Resource `json:"after,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	ContainerID   string    `json:"container_id,omitempty"`
+	ContainerName string    `json:"container_name,omitempty"`
+	AgentID       string    `json:"agent_id,omitempty"`
+	ReconcileID   string    `json:"reconcile_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// EventList is one page of resource history.
+type EventList struct {
+	Events []*ResourceEvent `json:"events"`
+	Total  int              `json:"total"`
+}
+
+// Conflict is a hostname claimed by several containers.
+type Conflict struct {
+	ID                  string    `json:"id"`
+	Hostname            string    `json:"hostname"`
+	ResourceType        string    `json:"resource_type"`
+	ContainerID         string    `json:"container_id"`
+	ContainerName       string    `json:"container_name"`
+	ServiceName         string    `json:"service_name"`
+	AgentID             string    `json:"agent_id,omitempty"`
+	WinnerContainerID   string    `json:"winner_container_id"`
+	WinnerContainerName string    `json:"winner_container_name"`
+	WinnerServiceName   string    `json:"winner_service_name"`
+	Reason              string    `json:"reason"`
+	FirstSeen           time.Time `json:"first_seen"`
+	LastSeen            time.Time `json:"last_seen"`
+}
+
+// ContainerInfo is the Docker information of a container.
+type ContainerInfo struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	Labels   map[string]string `json:"labels"`
+	Networks map[string]string `json:"networks,omitempty"`
+	State    string            `json:"state"`
+	Created  time.Time         `json:"created"`
+	Started  time.Time         `json:"started"`
+}
+
+// Container is a labeled container as seen by the reconciler. Parsed
+// services are kept as raw JSON; see the label documentation for their fields.
+type Container struct {
+	Info           *ContainerInfo             `json:"info"`
+	DNSServices    []json.RawMessage          `json:"dns_services,omitempty"`
+	TunnelServices []json.RawMessage          `json:"tunnel_services,omitempty"`
+	AccessPolicies map[string]json.RawMessage `json:"access_policies,omitempty"`
+	AgentID        string                     `json:"agent_id,omitempty"`
+	Priority       int                        `json:"priority,omitempty"`
+	Rollout        int                        `json:"rollout,omitempty"`
+	Errors         []string                   `json:"errors,omitempty"`
+	Stopping       bool                       `json:"stopping,omitempty"`
+	Conflicts      []*Conflict                `json:"conflicts"`
+	Resources      []*Resource                `json:"resources"`
+}
+
+// Agent is a registered agent.
+type Agent struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name,omitempty"`
+	Connected     bool       `json:"connected"`
+	LastSeen      *time.Time `json:"last_seen,omitempty"`
+	PublicIP      string     `json:"public_ip,omitempty"`
+	DefaultTunnel string     `json:"default_tunnel,omitempty"`
+	Status        string     `json:"status"`
+	ResourceCount int        `json:"resource_count"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ResourceCounts counts resources of one type by status.
+type ResourceCounts struct {
+	Total    int `json:"total"`
+	Active   int `json:"active"`
+	Orphaned int `json:"orphaned"`
+	Error    int `json:"error"`
+}
+
+// Overview is the dashboard overview.
+type Overview struct {
+	Resources struct {
+		DNS           ResourceCounts `json:"dns"`
+		TunnelIngress ResourceCounts `json:"tunnel_ingress"`
+		AccessApp     ResourceCounts `json:"access_app"`
+	} `json:"resources"`
+	Agents struct {
+		Total        int `json:"total"`
+		Connected    int `json:"connected"`
+		Disconnected int `json:"disconnected"`
+	} `json:"agents"`
+	Sync struct {
+		LastSync time.Time `json:"last_sync"`
+		Status   string    `json:"status"`
+		Error    string    `json:"error"`
+	} `json:"sync"`
+	Cloudflare struct {
+		Reachable bool      `json:"reachable"`
+		LastCheck time.Time `json:"last_check"`
+	} `json:"cloudflare"`
+	Version   string    `json:"version"`
+	Uptime    string    `json:"uptime"`
+	StartedAt time.Time `json:"started_at"`
+
+	// Leader is set when leader election is enabled
+	Leader *struct {
+		IsLeader bool   `json:"is_leader"`
+		Identity string `json:"identity"`
+		Leader   string `json:"leader"`
+	} `json:"leader,omitempty"`
+
+	// Maintenance holds the last run of each database maintenance task
+	Maintenance map[string]MaintenanceResult `json:"maintenance,omitempty"`
+}
+
+// MaintenanceResult is the last run of a database maintenance task.
+type MaintenanceResult struct {
+	LastRun  time.Time `json:"last_run"`
+	Duration string    `json:"duration"`
+	Status   string    `json:"status"`
+	Detail   string    `json:"detail,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AuditEntry is a recorded write action.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditList is one page of the audit trail.
+type AuditList struct {
+	Entries []*AuditEntry `json:"entries"`
+	Total   int           `json:"total"`
+}
+
+// Me is the authenticated caller.
+type Me struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// Version is the server version.
+type Version struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+}
+
+// Readiness is the result of the readiness checks.
+type Readiness struct {
+	// Status is "ok" or "fail"
+	Status string `json:"status"`
+	// Checks are the results by component (storage, docker, reconcile,
+	// cloudflare, agent_server)
+	Checks map[string]ComponentCheck `json:"checks"`
+}
+
+// ComponentCheck is the readiness of one component.
+type ComponentCheck struct {
+	// Status is "ok", "fail" or "skipped"
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Token is an API token, without its secret.
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateTokenRequest is the request to create an API token.
+type CreateTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn is a Go duration such as "720h"; empty for no expiry
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// CreatedToken is a newly created API token. Token is only returned once.
+type CreatedToken struct {
+	Token     string     `json:"token"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ReconcileResult is the outcome of a manual reconcile.
+type ReconcileResult struct {
+	LastSync time.Time `json:"last_sync"`
+	// Error is set when the reconcile failed
+	Error string `json:"error,omitempty"`
+}
+
+// ReloadResult lists the settings changed by a configuration reload, by
+// their config keys (e.g. "sync.debounce").
+type ReloadResult struct {
+	// Applied are the changed settings now in effect
+	Applied []string `json:"applied"`
+	// RestartRequired are the changed settings that only take effect after a restart
+	RestartRequired []string `json:"restart_required"`
+}
+
+// NotificationSink is a configured notification sink.
+type NotificationSink struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Events []string `json:"events"`
+}
+
+// NotificationSinks lists the configured notification sinks.
+type NotificationSinks struct {
+	Enabled bool                `json:"enabled"`
+	Sinks   []*NotificationSink `json:"sinks"`
+}
+
+// NotificationTestResult is the delivery result of a test notification.
+type NotificationTestResult struct {
+	Sink  string `json:"sink"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// LintIssue is a problem found in container labels.
+type LintIssue struct {
+	Label      string `json:"label,omitempty"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// ValidateResult is the result of validating container labels.
+type ValidateResult struct {
+	Valid bool `json:"valid"`
+	// Issues are set when validating one container
+	Issues []*LintIssue `json:"issues,omitempty"`
+	// Containers are the issues per container when validating several;
+	// containers without issues are omitted
+	Containers map[string][]*LintIssue `json:"containers,omitempty"`
+}
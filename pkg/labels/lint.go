@@ -0,0 +1,404 @@
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity is the severity of a lint issue.
+type Severity string
+
+const (
+	// SeverityError means labelgate ignores or rejects the label.
+	SeverityError Severity = "error"
+	// SeverityWarning means the label is accepted but has no or unexpected effect.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found by Lint.
+type Issue struct {
+	// Label is the offending label key (empty for service-level problems)
+	Label    string   `json:"label,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+
+	// Suggestion is the likely intended label key or value
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+func (i Issue) String() string {
+	s := string(i.Severity) + ": "
+	if i.Label != "" {
+		s += i.Label + ": "
+	}
+	s += i.Message
+	if i.Suggestion != "" {
+		s += " (did you mean " + i.Suggestion + "?)"
+	}
+	return s
+}
+
+// HasErrors reports whether any issue is an error.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Known properties per label type. Values select how the value is checked.
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindBool
+	kindInt
+	kindTTL
+	kindDuration
+	kindTunnelService
+)
+
+var dnsProperties = map[string]valueKind{
+	"hostname":   kindString,
+	"type":       kindString,
+	"target":     kindString,
+	"proxied":    kindBool,
+	"ttl":        kindTTL,
+	"credential": kindString,
+	"cleanup":    kindBool,
+	"comment":    kindString,
+	"access":     kindString,
+	"priority":   kindInt,
+	"weight":     kindInt,
+	"port":       kindInt,
+	"flags":      kindInt,
+	"tag":        kindString,
+}
+
+var tunnelProperties = map[string]valueKind{
+	"hostname":   kindString,
+	"service":    kindTunnelService,
+	"tunnel":     kindString,
+	"path":       kindString,
+	"credential": kindString,
+	"cleanup":    kindBool,
+	"access":     kindString,
+}
+
+var originProperties = map[string]valueKind{
+	"connect_timeout":          kindDuration,
+	"tls_timeout":              kindDuration,
+	"tcp_keepalive":            kindDuration,
+	"keep_alive_connections":   kindInt,
+	"keep_alive_timeout":       kindDuration,
+	"no_tls_verify":            kindBool,
+	"origin_server_name":       kindString,
+	"ca_pool":                  kindString,
+	"http_host_header":         kindString,
+	"no_happy_eyeballs":        kindBool,
+	"disable_chunked_encoding": kindBool,
+	"proxy_type":               kindString,
+}
+
+var accessProperties = map[string]valueKind{
+	"app_name":         kindString,
+	"session_duration": kindDuration,
+	"policy.decision":  kindString,
+	"policy.name":      kindString,
+}
+
+// Properties applied from the "default" service (see applyDNSDefaults and
+// applyTunnelDefaults).
+var (
+	dnsDefaultProperties    = []string{"type", "target", "proxied", "ttl", "credential", "cleanup"}
+	tunnelDefaultProperties = []string{"tunnel", "credential", "cleanup"}
+)
+
+// accessRulePrefixes are the access property prefixes followed by a selector.
+// Selectors are validated by Parse.
+var accessRulePrefixes = []string{"policy.include.", "policy.require.", "policy.exclude."}
+
+// Lint strictly validates one container's labels. Besides the errors Parse
+// reports, it flags unknown label types and properties, invalid origin keys,
+// malformed booleans, integers, TTLs and durations, and access references to
+// policies that are neither defined in labels nor in policies (policy names
+// defined on other containers). Issues are sorted by label.
+func (p *Parser) Lint(labels map[string]string, policies map[string]bool) []Issue {
+	result := p.Parse(labels)
+
+	var issues []Issue
+	for _, err := range result.Errors {
+		issues = append(issues, Issue{Severity: SeverityError, Message: err.Error()})
+	}
+	if err := p.CheckHostnameConflict(result); err != nil {
+		issues = append(issues, Issue{Severity: SeverityError, Message: err.Error()})
+	}
+
+	for key, value := range labels {
+		if !strings.HasPrefix(key, p.prefix+".") {
+			continue
+		}
+		issues = append(issues, p.lintLabel(key, value, result, policies)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Label < issues[j].Label
+	})
+	return issues
+}
+
+// LintContainers lints the labels of several containers (name -> labels),
+// resolving access references across all of them and the given policies.
+// Containers without issues are omitted.
+func (p *Parser) LintContainers(containers map[string]map[string]string, policies map[string]bool) map[string][]Issue {
+	known := make(map[string]bool, len(policies))
+	for name := range policies {
+		known[name] = true
+	}
+	for _, labels := range containers {
+		for name := range p.Parse(labels).AccessPolicies {
+			known[name] = true
+		}
+	}
+
+	results := make(map[string][]Issue)
+	for name, labels := range containers {
+		if issues := p.Lint(labels, known); len(issues) > 0 {
+			results[name] = issues
+		}
+	}
+	return results
+}
+
+// lintLabel checks a single prefixed label.
+func (p *Parser) lintLabel(key, value string, result *ParseResult, policies map[string]bool) []Issue {
+	rest := strings.TrimPrefix(key, p.prefix+".")
+	if rest == "priority" || rest == "rollout" {
+		return nil // validated by Parse
+	}
+
+	parts := strings.SplitN(rest, ".", 3)
+	if len(parts) < 3 {
+		issue := Issue{Label: key, Severity: SeverityError, Message: "unknown label (expected " + p.prefix + ".<type>.<service>.<property>, " + p.prefix + ".priority or " + p.prefix + ".rollout)"}
+		if s := suggest(rest, []string{"priority", "rollout"}); s != "" {
+			issue.Suggestion = p.prefix + "." + s
+		}
+		return []Issue{issue}
+	}
+	labelType, serviceName, property := parts[0], parts[1], parts[2]
+
+	var known map[string]valueKind
+	switch labelType {
+	case TypeDNS:
+		known = dnsProperties
+	case TypeTunnel:
+		known = tunnelProperties
+	case TypeAccess:
+		known = accessProperties
+	default:
+		issue := Issue{Label: key, Severity: SeverityError, Message: "unknown label type: " + labelType + " (expected dns, tunnel or access)"}
+		if s := suggest(labelType, []string{TypeDNS, TypeTunnel, TypeAccess}); s != "" {
+			issue.Suggestion = p.prefix + "." + s + "." + serviceName + "." + property
+		}
+		return []Issue{issue}
+	}
+
+	// Service names are validated by Parse
+	if reservedNames[serviceName] && serviceName != "default" || serviceName != "default" && !serviceNamePattern.MatchString(serviceName) {
+		return nil
+	}
+	prefix := p.prefix + "." + labelType + "." + serviceName + "."
+
+	kind, ok := known[property]
+	switch {
+	case ok:
+	case labelType == TypeTunnel && strings.HasPrefix(property, "origin."):
+		originKey := strings.TrimPrefix(property, "origin.")
+		kind, ok = originProperties[originKey]
+		if !ok {
+			issue := Issue{Label: key, Severity: SeverityError, Message: "unknown origin property: " + originKey}
+			if s := suggest(originKey, sortedKeys(originProperties)); s != "" {
+				issue.Suggestion = prefix + "origin." + s
+			}
+			return []Issue{issue}
+		}
+	case labelType == TypeAccess && hasAnyPrefix(property, accessRulePrefixes):
+		return nil // selectors are validated by Parse
+	default:
+		issue := Issue{Label: key, Severity: SeverityError, Message: "unknown " + labelType + " property: " + property}
+		candidates := sortedKeys(known)
+		if labelType == TypeTunnel {
+			candidates = append(candidates, "origin")
+		}
+		if labelType == TypeAccess {
+			for _, rp := range accessRulePrefixes {
+				candidates = append(candidates, strings.TrimSuffix(rp, "."))
+			}
+		}
+		if s := suggest(property, candidates); s != "" {
+			issue.Suggestion = prefix + s
+		}
+		return []Issue{issue}
+	}
+
+	var issues []Issue
+	if serviceName == "default" {
+		if issue, bad := lintDefault(key, labelType, property); bad {
+			return []Issue{issue}
+		}
+	}
+
+	if msg := checkValue(kind, value); msg != "" {
+		issues = append(issues, Issue{Label: key, Severity: SeverityError, Message: msg})
+	}
+
+	switch {
+	case property == "access" && value != "":
+		if _, ok := result.AccessPolicies[value]; !ok && !policies[value] {
+			issue := Issue{Label: key, Severity: SeverityError, Message: "access policy not defined on any container: " + value}
+			candidates := sortedKeys(policies)
+			for name := range result.AccessPolicies {
+				candidates = append(candidates, name)
+			}
+			issue.Suggestion = suggest(value, candidates)
+			issues = append(issues, issue)
+		}
+	case labelType == TypeDNS && property == "ttl" && value != "0" && value != "1" && proxied(result, serviceName):
+		issues = append(issues, Issue{Label: key, Severity: SeverityWarning, Message: "TTL is ignored for proxied records (set proxied=false or remove ttl)"})
+	}
+	return issues
+}
+
+// proxied reports whether the parsed DNS service (defaults applied) is proxied.
+func proxied(result *ParseResult, serviceName string) bool {
+	for _, svc := range result.DNSServices {
+		if svc.ServiceName == serviceName {
+			return svc.Proxied
+		}
+	}
+	return false
+}
+
+// lintDefault reports properties of the "default" service that aren't applied.
+func lintDefault(key, labelType, property string) (Issue, bool) {
+	var applied []string
+	switch labelType {
+	case TypeDNS:
+		applied = dnsDefaultProperties
+	case TypeTunnel:
+		applied = tunnelDefaultProperties
+	case TypeAccess:
+		return Issue{Label: key, Severity: SeverityWarning, Message: "access policies have no defaults; this label is ignored"}, true
+	}
+	for _, a := range applied {
+		if a == property {
+			return Issue{}, false
+		}
+	}
+	return Issue{Label: key, Severity: SeverityWarning, Message: fmt.Sprintf("%s is not applied as a %s default (supported: %s)", property, labelType, strings.Join(applied, ", "))}, true
+}
+
+// checkValue validates a value of the given kind and returns a message if invalid.
+func checkValue(kind valueKind, value string) string {
+	v := strings.TrimSpace(value)
+	switch kind {
+	case kindBool:
+		switch strings.ToLower(v) {
+		case "true", "1", "yes", "on", "false", "0", "no", "off":
+		default:
+			return "invalid boolean: " + value + " (use true or false)"
+		}
+	case kindInt:
+		if _, err := strconv.Atoi(v); err != nil {
+			return "invalid integer: " + value
+		}
+	case kindTTL:
+		ttl, err := strconv.Atoi(v)
+		if err != nil {
+			return "invalid TTL: " + value + " (seconds, 1 or 0 for automatic)"
+		}
+		if ttl != 0 && ttl != 1 && (ttl < 30 || ttl > 86400) {
+			return "invalid TTL: " + value + " (must be 1 for automatic or between 30 and 86400 seconds)"
+		}
+	case kindDuration:
+		if _, err := time.ParseDuration(v); err != nil {
+			return "invalid duration: " + value + " (e.g. 30s, 5m, 24h)"
+		}
+	case kindTunnelService:
+		if v == "hello_world" || strings.HasPrefix(v, "http_status:") || strings.HasPrefix(v, "unix:") || strings.HasPrefix(v, "unix+tls:") {
+			return ""
+		}
+		scheme, _, ok := strings.Cut(v, "://")
+		if !ok {
+			return "invalid service: " + value + " (expected a URL such as http://app:80)"
+		}
+		switch scheme {
+		case "http", "https", "tcp", "ssh", "rdp", "smb", "ws", "wss":
+		default:
+			return "unsupported service scheme: " + scheme
+		}
+	}
+	return ""
+}
+
+// suggest returns the candidate closest to word, or "" if none is close enough.
+func suggest(word string, candidates []string) string {
+	best, bestDist := "", 0
+	for _, c := range candidates {
+		if c == word {
+			continue
+		}
+		d := levenshtein(word, c)
+		if best == "" || d < bestDist || d == bestDist && c < best {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" || bestDist > 2 || bestDist*2 >= len(word) {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
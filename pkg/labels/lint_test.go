@@ -0,0 +1,159 @@
+package labels
+
+import (
+	"testing"
+)
+
+func TestParser_Lint(t *testing.T) {
+	parser := NewParser("labelgate")
+
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		policies   map[string]bool
+		wantLabel  string
+		severity   Severity
+		suggestion string
+	}{
+		{
+			name: "unknown type",
+			labels: map[string]string{
+				"labelgate.tunel.web.hostname": "web.example.com",
+			},
+			wantLabel:  "labelgate.tunel.web.hostname",
+			severity:   SeverityError,
+			suggestion: "labelgate.tunnel.web.hostname",
+		},
+		{
+			name: "unknown property",
+			labels: map[string]string{
+				"labelgate.dns.web.hostname": "web.example.com",
+				"labelgate.dns.web.proxyed":  "true",
+			},
+			wantLabel:  "labelgate.dns.web.proxyed",
+			severity:   SeverityError,
+			suggestion: "labelgate.dns.web.proxied",
+		},
+		{
+			name: "unknown origin property",
+			labels: map[string]string{
+				"labelgate.tunnel.web.hostname":             "web.example.com",
+				"labelgate.tunnel.web.service":              "http://web:80",
+				"labelgate.tunnel.web.origin.no_tls_verfiy": "true",
+			},
+			wantLabel:  "labelgate.tunnel.web.origin.no_tls_verfiy",
+			severity:   SeverityError,
+			suggestion: "labelgate.tunnel.web.origin.no_tls_verify",
+		},
+		{
+			name: "invalid TTL",
+			labels: map[string]string{
+				"labelgate.dns.web.hostname": "web.example.com",
+				"labelgate.dns.web.proxied":  "false",
+				"labelgate.dns.web.ttl":      "10",
+			},
+			wantLabel: "labelgate.dns.web.ttl",
+			severity:  SeverityError,
+		},
+		{
+			name: "TTL on proxied record",
+			labels: map[string]string{
+				"labelgate.dns.web.hostname": "web.example.com",
+				"labelgate.dns.web.ttl":      "300",
+			},
+			wantLabel: "labelgate.dns.web.ttl",
+			severity:  SeverityWarning,
+		},
+		{
+			name: "invalid boolean",
+			labels: map[string]string{
+				"labelgate.dns.web.hostname": "web.example.com",
+				"labelgate.dns.web.proxied":  "maybe",
+			},
+			wantLabel: "labelgate.dns.web.proxied",
+			severity:  SeverityError,
+		},
+		{
+			name: "invalid duration",
+			labels: map[string]string{
+				"labelgate.tunnel.web.hostname":               "web.example.com",
+				"labelgate.tunnel.web.service":                "http://web:80",
+				"labelgate.tunnel.web.origin.connect_timeout": "30",
+			},
+			wantLabel: "labelgate.tunnel.web.origin.connect_timeout",
+			severity:  SeverityError,
+		},
+		{
+			name: "default not applied",
+			labels: map[string]string{
+				"labelgate.tunnel.default.service": "http://web:80",
+				"labelgate.tunnel.web.hostname":    "web.example.com",
+				"labelgate.tunnel.web.service":     "http://web:80",
+			},
+			wantLabel: "labelgate.tunnel.default.service",
+			severity:  SeverityWarning,
+		},
+		{
+			name: "undefined access policy",
+			labels: map[string]string{
+				"labelgate.dns.web.hostname": "web.example.com",
+				"labelgate.dns.web.access":   "internl",
+			},
+			policies:   map[string]bool{"internal": true},
+			wantLabel:  "labelgate.dns.web.access",
+			severity:   SeverityError,
+			suggestion: "internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := parser.Lint(tt.labels, tt.policies)
+			if len(issues) != 1 {
+				t.Fatalf("expected 1 issue, got %v", issues)
+			}
+			got := issues[0]
+			if got.Label != tt.wantLabel || got.Severity != tt.severity || got.Suggestion != tt.suggestion {
+				t.Errorf("got %+v, want label %s severity %s suggestion %q", got, tt.wantLabel, tt.severity, tt.suggestion)
+			}
+		})
+	}
+}
+
+func TestParser_Lint_Valid(t *testing.T) {
+	parser := NewParser("labelgate")
+	issues := parser.Lint(map[string]string{
+		"labelgate.priority":                                        "10",
+		"labelgate.rollout":                                         "50",
+		"labelgate.dns.default.proxied":                             "false",
+		"labelgate.dns.web.hostname":                                "web.example.com",
+		"labelgate.dns.web.ttl":                                     "300",
+		"labelgate.dns.web.access":                                  "internal",
+		"labelgate.tunnel.api.hostname":                             "api.example.com",
+		"labelgate.tunnel.api.service":                              "http://api:8080",
+		"labelgate.tunnel.api.origin.no_tls_verify":                 "true",
+		"labelgate.access.internal.policy.decision":                 "allow",
+		"labelgate.access.internal.policy.include.emails_ending_in": "@example.com",
+		"traefik.enable":                                            "true",
+	}, nil)
+	if len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestParser_LintContainers(t *testing.T) {
+	parser := NewParser("labelgate")
+	results := parser.LintContainers(map[string]map[string]string{
+		"auth": {
+			"labelgate.access.internal.policy.decision":         "allow",
+			"labelgate.access.internal.policy.include.everyone": "true",
+		},
+		"web": {
+			"labelgate.dns.web.hostname": "web.example.com",
+			"labelgate.dns.web.access":   "internal",
+		},
+	}, nil)
+	if len(results) != 0 {
+		t.Errorf("expected access reference to resolve across containers, got %v", results)
+	}
+}
@@ -4,6 +4,7 @@ package labels
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -66,6 +67,8 @@ type ParseResult struct {
 	DNSServices    []*types.DNSService
 	TunnelServices []*types.TunnelService
 	AccessPolicies map[string]*types.AccessPolicyDef // policy_name -> definition
+	Priority       int                               // container-level conflict priority (<prefix>.priority)
+	Rollout        int                               // percent of shared tunnel rules to take over (<prefix>.rollout, 0 = off)
 	Errors         []error
 }
 
@@ -86,6 +89,29 @@ func (p *Parser) Parse(labels map[string]string) *ParseResult {
 
 		// Remove prefix: labelgate.dns.web.hostname -> dns.web.hostname
 		rest := strings.TrimPrefix(key, p.prefix+".")
+
+		// Container-level priority for hostname conflict resolution
+		if rest == "priority" {
+			priority, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("invalid priority: %s (must be an integer)", value))
+				continue
+			}
+			result.Priority = priority
+			continue
+		}
+
+		// Container-level weight for gradual tunnel rollouts
+		if rest == "rollout" {
+			rollout, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || rollout < 0 || rollout > 100 {
+				result.Errors = append(result.Errors, fmt.Errorf("invalid rollout: %s (must be a percentage from 0 to 100)", value))
+				continue
+			}
+			result.Rollout = rollout
+			continue
+		}
+
 		parts := strings.SplitN(rest, ".", 3)
 		if len(parts) < 3 {
 			// Not enough parts, might be global config
@@ -165,6 +191,14 @@ func (p *Parser) Parse(labels map[string]string) *ParseResult {
 		result.TunnelServices = append(result.TunnelServices, svc)
 	}
 
+	// Stable order so conflict resolution never depends on map iteration
+	sort.Slice(result.DNSServices, func(i, j int) bool {
+		return result.DNSServices[i].ServiceName < result.DNSServices[j].ServiceName
+	})
+	sort.Slice(result.TunnelServices, func(i, j int) bool {
+		return result.TunnelServices[i].ServiceName < result.TunnelServices[j].ServiceName
+	})
+
 	return result
 }
 
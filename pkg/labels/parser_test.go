@@ -190,6 +190,52 @@ func TestParser_CustomPrefix(t *testing.T) {
 	}
 }
 
+func TestParser_Priority(t *testing.T) {
+	parser := NewParser("labelgate")
+
+	result := parser.Parse(map[string]string{
+		"labelgate.priority":         "10",
+		"labelgate.dns.web.hostname": "web.example.com",
+		"labelgate.dns.mx.hostname":  "mail.example.com",
+		"labelgate.dns.mx.type":      "MX",
+		"labelgate.dns.mx.priority":  "5",
+	})
+	if result.Priority != 10 {
+		t.Errorf("got container priority %d, want 10", result.Priority)
+	}
+	if len(result.DNSServices) != 2 {
+		t.Fatalf("got %d DNS services, want 2", len(result.DNSServices))
+	}
+	// Services are sorted by name
+	if result.DNSServices[0].ServiceName != "mx" || result.DNSServices[0].Priority != 5 {
+		t.Errorf("expected MX service with priority 5 first, got %s (%d)", result.DNSServices[0].ServiceName, result.DNSServices[0].Priority)
+	}
+
+	result = parser.Parse(map[string]string{"labelgate.priority": "high"})
+	if len(result.Errors) != 1 {
+		t.Errorf("expected 1 error for invalid priority, got %d", len(result.Errors))
+	}
+}
+
+func TestParser_Rollout(t *testing.T) {
+	parser := NewParser("labelgate")
+
+	result := parser.Parse(map[string]string{
+		"labelgate.rollout":             "25",
+		"labelgate.tunnel.web.hostname": "app.example.com",
+		"labelgate.tunnel.web.service":  "http://web:80",
+	})
+	if result.Rollout != 25 || len(result.Errors) != 0 {
+		t.Errorf("got rollout %d (%v), want 25", result.Rollout, result.Errors)
+	}
+
+	for _, value := range []string{"half", "-1", "101"} {
+		if result := parser.Parse(map[string]string{"labelgate.rollout": value}); len(result.Errors) != 1 {
+			t.Errorf("expected 1 error for rollout %q, got %d", value, len(result.Errors))
+		}
+	}
+}
+
 func TestParser_DNSDefaults(t *testing.T) {
 	parser := NewParser("labelgate")
 